@@ -2,14 +2,23 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
 	"github.com/jsherman999/openclaw_keyspider/internal/exporter"
+	"github.com/jsherman999/openclaw_keyspider/internal/graph"
+	"github.com/jsherman999/openclaw_keyspider/internal/identity"
+	"github.com/jsherman999/openclaw_keyspider/internal/leader"
+	"github.com/jsherman999/openclaw_keyspider/internal/schedule"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 	"github.com/jsherman999/openclaw_keyspider/internal/watchhub"
 	"github.com/jsherman999/openclaw_keyspider/internal/webui"
@@ -34,13 +43,39 @@ func (a *API) Router() http.Handler {
 		_, _ = w.Write([]byte("ok"))
 	})
 
+	// Leader election status. "instance" is the keyspiderd answering; a leader
+	// whose heartbeat is older than three check intervals is reported stale.
+	r.Get("/leader", func(w http.ResponseWriter, r *http.Request) {
+		leaders, err := a.store.ListLeaders(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		iv := time.Duration(a.cfg.Leader.CheckIntervalSeconds) * time.Second
+		if iv <= 0 {
+			iv = 10 * time.Second
+		}
+		self := leader.InstanceID()
+		type status struct {
+			store.LeaderStatus
+			IsSelf bool `json:"is_self"`
+			Stale  bool `json:"stale"`
+		}
+		out := []status{}
+		for _, l := range leaders {
+			out = append(out, status{LeaderStatus: l, IsSelf: l.Holder == self, Stale: time.Since(l.HeartbeatAt) > 3*iv})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"instance": self, "leaders": out})
+	})
+
 	// Enqueue a scan job (used by the Web UI)
-	// POST /scan {"host":"server","since_seconds":604800,"spider_depth":1}
+	// POST /scan {"host":"server","since_seconds":604800,"spider_depth":1,"priority":0}
 	r.Post("/scan", func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Host         string `json:"host"`
 			SinceSeconds int    `json:"since_seconds"`
 			SpiderDepth  int    `json:"spider_depth"`
+			Priority     int    `json:"priority"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "bad json", 400)
@@ -54,7 +89,7 @@ func (a *API) Router() http.Handler {
 		if req.SinceSeconds > 0 {
 			since = req.SinceSeconds
 		}
-		jobID, err := a.store.EnqueueScanJob(r.Context(), req.Host, time.Duration(since)*time.Second, req.SpiderDepth)
+		jobID, err := a.store.EnqueueScanJob(r.Context(), req.Host, time.Duration(since)*time.Second, req.SpiderDepth, req.Priority)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -78,6 +113,261 @@ func (a *API) Router() http.Handler {
 		_ = json.NewEncoder(w).Encode(job)
 	})
 
+	// Per-host progress rows of a scan job (BFS order).
+	r.Get("/scan/{id}/hosts", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		hosts, err := a.store.ListScanJobHosts(r.Context(), id, time.Time{})
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(hosts)
+	})
+
+	// SSE stream of scan job progress. Each message carries the job, running
+	// totals and the host rows changed since the previous message; the stream
+	// ends with an "event: done" message once the job finishes.
+	r.Get("/scan/{id}/progress", a.scanProgress)
+
+	// List scan jobs
+	// GET /scans?status=running&host=server1&limit=200
+	r.Get("/scans", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := store.ScanJobFilter{Status: q.Get("status"), Host: q.Get("host"), Limit: 200}
+		if l := q.Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil {
+				f.Limit = v
+			}
+		}
+		jobs, err := a.store.ListScanJobs(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(jobs)
+	})
+
+	// Cancel a queued or running scan job
+	r.Post("/scan/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		status, err := a.store.CancelScanJob(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), 409)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"job_id": id, "status": status, "cancel_requested": true})
+	})
+
+	// Retry a finished scan job. POST /scan/{id}/retry?fresh=true discards the checkpoint.
+	r.Post("/scan/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		fresh := r.URL.Query().Get("fresh") == "true"
+		if err := a.store.RetryScanJob(r.Context(), id, fresh); err != nil {
+			code := 409
+			if errors.Is(err, store.ErrScanJobNotFound) {
+				code = 404
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"job_id": id, "status": "queued"})
+	})
+
+	// Enqueue a job of any kind. Progress and control use the /scan/{id} routes.
+	// POST /jobs {"kind":"config_audit","hosts":["a","b"],"priority":0}
+	// POST /jobs {"kind":"rescan_all","child_kind":"authorized_keys"}
+	r.Post("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Kind         string   `json:"kind"`
+			Host         string   `json:"host"`
+			Hosts        []string `json:"hosts"`
+			ChildKind    string   `json:"child_kind"`
+			SinceSeconds int      `json:"since_seconds"`
+			SpiderDepth  int      `json:"spider_depth"`
+			Priority     int      `json:"priority"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", 400)
+			return
+		}
+		since := 168 * 3600
+		if req.SinceSeconds > 0 {
+			since = req.SinceSeconds
+		}
+		spec := store.JobSpec{
+			Kind:        req.Kind,
+			TargetHost:  req.Host,
+			Since:       time.Duration(since) * time.Second,
+			SpiderDepth: req.SpiderDepth,
+			Priority:    req.Priority,
+			Params:      store.JobParams{Hosts: req.Hosts, ChildKind: req.ChildKind},
+		}
+		if spec.Kind != store.JobKindScan && req.Host != "" {
+			spec.Params.Hosts = append([]string{req.Host}, spec.Params.Hosts...)
+			spec.TargetHost = ""
+		}
+		if err := spec.Validate(); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		jobID, err := a.store.EnqueueJob(r.Context(), spec)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"job_id": jobID, "kind": spec.Kind})
+	})
+
+	// Schedules: recurring jobs and exports fired by keyspiderd.
+	r.Get("/schedules", func(w http.ResponseWriter, r *http.Request) {
+		scs, err := a.store.ListSchedules(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(scs)
+	})
+
+	// POST /schedules {"name":"nightly-web1","cron":"0 2 * * *","timezone":"UTC","action":"job",
+	//                  "spec":{"kind":"scan","host":"web1","spider_depth":1},"misfire_policy":"run_once"}
+	r.Post("/schedules", func(w http.ResponseWriter, r *http.Request) {
+		var sc store.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+			http.Error(w, "bad json", 400)
+			return
+		}
+		sc.Enabled = true
+		if err := schedule.Prepare(&sc, time.Now()); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		id, err := a.store.CreateSchedule(r.Context(), &sc)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "name": sc.Name, "next_run_at": sc.NextRunAt})
+	})
+
+	r.Delete("/schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		ok, err := a.store.DeleteSchedule(r.Context(), chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// POST /schedules/{name}/pause | /resume | /run (fire on the next scheduler tick)
+	r.Post("/schedules/{name}/{op}", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		var ok bool
+		var err error
+		switch chi.URLParam(r, "op") {
+		case "pause":
+			ok, err = a.store.SetScheduleEnabled(r.Context(), name, false, time.Time{})
+		case "resume":
+			var sc *store.Schedule
+			if sc, err = a.store.GetSchedule(r.Context(), name); err == nil && sc != nil {
+				var next time.Time
+				if next, err = schedule.NextRun(sc.Cron, sc.Timezone, time.Now()); err == nil {
+					ok, err = a.store.SetScheduleEnabled(r.Context(), name, true, next)
+				}
+			}
+		case "run":
+			ok, err = a.store.TriggerSchedule(r.Context(), name)
+		default:
+			http.Error(w, "unknown operation", 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found (or paused)", 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": name, "ok": true})
+	})
+
+	// Watchers: the running watcher picks up changes within watcher.reconcile_seconds.
+	// GET /watchers lists watchers with their live health. "stale" means an
+	// enabled watcher has not reported for a minute (no leader, or it is stuck).
+	r.Get("/watchers", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := a.store.ListWatchers(r.Context(), false)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		type watcherStatus struct {
+			store.Watcher
+			Stale bool `json:"stale"`
+		}
+		out := []watcherStatus{}
+		for _, x := range ws {
+			stale := x.Enabled && (x.LastHeartbeat == nil || time.Since(*x.LastHeartbeat) > time.Minute)
+			out = append(out, watcherStatus{Watcher: x, Stale: stale})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	// POST /watchers {"host":"web1","mode":"tail","enabled":true} creates or updates a watcher.
+	r.Post("/watchers", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Host    string `json:"host"`
+			Mode    string `json:"mode"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", 400)
+			return
+		}
+		if req.Host == "" {
+			http.Error(w, "host required", 400)
+			return
+		}
+		if req.Mode != "" && !slices.Contains(store.WatcherModes, req.Mode) {
+			http.Error(w, "mode must be auto, journal or tail", 400)
+			return
+		}
+		wt, err := a.store.PutWatcher(r.Context(), req.Host, req.Enabled, req.Mode)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(wt)
+	})
+
+	r.Delete("/watchers/{host}", func(w http.ResponseWriter, r *http.Request) {
+		ok, err := a.store.DeleteWatcher(r.Context(), chi.URLParam(r, "host"))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
 	// Phase 3: SSE stream of newly-ingested watcher events.
 	r.Get("/watch/events", func(w http.ResponseWriter, r *http.Request) {
 		flusher, ok := w.(http.Flusher)
@@ -109,8 +399,24 @@ func (a *API) Router() http.Handler {
 		}
 	})
 
+	// GET /hosts?tag=web&tag=prod&environment=production&owner_team=dba&in_inventory=true&limit=200
 	r.Get("/hosts", func(w http.ResponseWriter, r *http.Request) {
-		hosts, err := a.store.ListHosts(r.Context(), 200)
+		q := r.URL.Query()
+		f := store.HostFilter{Tags: q["tag"], Environment: q.Get("environment"), OwnerTeam: q.Get("owner_team"), Limit: 200}
+		if v := q.Get("in_inventory"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "bad in_inventory", 400)
+				return
+			}
+			f.InInventory = &b
+		}
+		if l := q.Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil {
+				f.Limit = v
+			}
+		}
+		hosts, err := a.store.FindHosts(r.Context(), f)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -118,6 +424,18 @@ func (a *API) Router() http.Handler {
 		_ = json.NewEncoder(w).Encode(hosts)
 	})
 
+	// Inventory hosts never scanned, and hosts seen but not in inventory.
+	// GET /inventory/gaps?limit=1000
+	r.Get("/inventory/gaps", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		gaps, err := a.store.ListInventoryGaps(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(gaps)
+	})
+
 	r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
 		hostIDStr := r.URL.Query().Get("host_id")
 		if hostIDStr == "" {
@@ -137,6 +455,462 @@ func (a *API) Router() http.Handler {
 		_ = json.NewEncoder(w).Encode(events)
 	})
 
+	// Reachability timeline of one host, newest first; reason says why a probe failed.
+	// GET /hosts/{id}/reachability?from=RFC3339&limit=500
+	r.Get("/hosts/{id}/reachability", func(w http.ResponseWriter, r *http.Request) {
+		hid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		q := r.URL.Query()
+		from := time.Now().AddDate(0, 0, -30)
+		if v := q.Get("from"); v != "" {
+			if from, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "bad from", 400)
+				return
+			}
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		probes, err := a.store.ListReachHistory(r.Context(), hid, from, limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(probes)
+	})
+
+	// Addresses a host was seen at, latest first.
+	// GET /hosts/{id}/addresses?limit=500
+	r.Get("/hosts/{id}/addresses", func(w http.ResponseWriter, r *http.Request) {
+		hid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		addrs, err := a.store.ListHostAddresses(r.Context(), hid, limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(addrs)
+	})
+
+	// Hosts an address belonged to over time, latest first.
+	// GET /addresses/{ip}?limit=500
+	r.Get("/addresses/{ip}", func(w http.ResponseWriter, r *http.Request) {
+		ip := net.ParseIP(chi.URLParam(r, "ip"))
+		if ip == nil {
+			http.Error(w, "bad ip", 400)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		addrs, err := a.store.AddressHistory(r.Context(), ip.String(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(addrs)
+	})
+
+	// Hosts whose reachability changed in [from, to): by default, sources that
+	// became unreachable in the last week.
+	// GET /reachability/changes?from=RFC3339&to=RFC3339&state=unreachable|reachable&sources_only=true&limit=500
+	r.Get("/reachability/changes", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := store.ReachChangeFilter{From: time.Now().AddDate(0, 0, -7), SourcesOnly: q.Get("sources_only") != "false", Limit: 500}
+		switch q.Get("state") {
+		case "", "unreachable":
+		case "reachable":
+			f.Reachable = true
+		default:
+			http.Error(w, "state must be unreachable or reachable", 400)
+			return
+		}
+		if v := q.Get("from"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad from", 400)
+				return
+			}
+			f.From = t
+		}
+		if v := q.Get("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad to", 400)
+				return
+			}
+			f.To = t
+		}
+		if l := q.Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil {
+				f.Limit = v
+			}
+		}
+		changes, err := a.store.ListReachChanges(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(changes)
+	})
+
+	// Identities: who is behind a key.
+	// GET /identities?kind=person|service|team&limit=1000 lists identities;
+	// GET /identities?fingerprint=SHA256:... lists a key's owners instead.
+	r.Get("/identities", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if fp := q.Get("fingerprint"); fp != "" {
+			owners, err := a.store.ListKeyOwners(r.Context(), fp, 0)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(owners)
+			return
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		ids, err := a.store.ListIdentities(r.Context(), q.Get("kind"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ids)
+	})
+
+	// POST /identities {"kind":"person","name":"alice@corp","display_name":"Alice","email":"alice@corp.example","team":"platform"}
+	r.Post("/identities", func(w http.ResponseWriter, r *http.Request) {
+		var id store.Identity
+		if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+			http.Error(w, "bad json", 400)
+			return
+		}
+		if id.Kind == "" {
+			id.Kind = store.IdentityPerson
+		}
+		if !store.ValidIdentityKind(id.Kind) || id.Name == "" {
+			http.Error(w, "name required; kind must be person, service or team", 400)
+			return
+		}
+		id.Source = "manual"
+		if _, err := a.store.UpsertIdentity(r.Context(), &id); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id.ID, "kind": id.Kind, "name": id.Name})
+	})
+
+	// GET /identities/{id} returns the identity with the keys linked to it.
+	r.Get("/identities/{id}", func(w http.ResponseWriter, r *http.Request) {
+		iid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		id, err := a.store.GetIdentity(r.Context(), iid)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if id == nil {
+			http.Error(w, "not found", 404)
+			return
+		}
+		ks, err := a.store.ListKeyOwners(r.Context(), "", iid)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"identity": id, "keys": ks})
+	})
+
+	r.Delete("/identities/{id}", func(w http.ResponseWriter, r *http.Request) {
+		iid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		ok, err := a.store.DeleteIdentity(r.Context(), iid)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// POST /identities/{id}/keys {"fingerprint":"SHA256:..."|"public_key":"ssh-ed25519 AAAA...","label":"laptop key"}
+	r.Post("/identities/{id}/keys", func(w http.ResponseWriter, r *http.Request) {
+		iid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		var req struct {
+			Fingerprint string `json:"fingerprint"`
+			PublicKey   string `json:"public_key"`
+			Label       string `json:"label"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", 400)
+			return
+		}
+		k := req.Fingerprint
+		if k == "" {
+			k = req.PublicKey
+		}
+		fp, err := identity.Fingerprint(k)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		id, err := a.store.GetIdentity(r.Context(), iid)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if id == nil {
+			http.Error(w, "not found", 404)
+			return
+		}
+		var label *string
+		if req.Label != "" {
+			label = &req.Label
+		}
+		if err := a.store.LinkKey(r.Context(), fp, iid, label, "manual", identity.ConfidenceManual); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"identity_id": iid, "fingerprint_sha256": fp})
+	})
+
+	// DELETE /identities/{id}/keys?fingerprint=SHA256:...
+	r.Delete("/identities/{id}/keys", func(w http.ResponseWriter, r *http.Request) {
+		iid, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad id", 400)
+			return
+		}
+		fp := r.URL.Query().Get("fingerprint")
+		if fp == "" {
+			http.Error(w, "fingerprint required", 400)
+			return
+		}
+		ok, err := a.store.UnlinkKey(r.Context(), fp, iid)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Concerns, open by default; type=keys is the key cleanup findings.
+	// GET /concerns?type=KEY_UNUSED,KEY_UNKNOWN|keys&host_id=1&status=open|resolved|all&limit=500
+	r.Get("/concerns", func(w http.ResponseWriter, r *http.Request) {
+		f, err := concernFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		cs, err := a.store.ListConcerns(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(cs)
+	})
+
+	// Recompute the key findings now (a key_findings job does the same).
+	// Parameters override config key_findings for this run.
+	// POST /findings/keys?unused_days=90&max_private_hosts=1&max_authorized_accounts=20
+	r.Post("/findings/keys", func(w http.ResponseWriter, r *http.Request) {
+		kf := a.cfg.KeyFindings
+		rules := store.KeyFindingRules{UnusedDays: kf.UnusedDays, MaxPrivateHosts: kf.MaxPrivateHosts, MaxAuthorizedAccounts: kf.MaxAuthorizedAccounts}
+		q := r.URL.Query()
+		for name, dst := range map[string]*int{"unused_days": &rules.UnusedDays, "max_private_hosts": &rules.MaxPrivateHosts, "max_authorized_accounts": &rules.MaxAuthorizedAccounts} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 || (name == "unused_days" && n == 0) {
+					http.Error(w, "bad "+name, 400)
+					return
+				}
+				*dst = n
+			}
+		}
+		res, err := a.store.RefreshKeyFindings(r.Context(), rules)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+
+	// Key spread: hosts, users and accounts each key is authorized for, and
+	// hosts holding a copy of its private half, widest first.
+	// GET /keys?min_accounts=10&min_private_hosts=2&limit=200
+	r.Get("/keys", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var f store.KeySpreadFilter
+		for name, dst := range map[string]*int{"min_accounts": &f.MinAccounts, "min_private_hosts": &f.MinPrivateHosts, "limit": &f.Limit} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					http.Error(w, "bad "+name, 400)
+					return
+				}
+				*dst = n
+			}
+		}
+		ks, err := a.store.ListKeySpread(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ks)
+	})
+
+	// One key: its spread, every location it was found at and its logins.
+	// The "SHA256:" prefix is optional; escape any "/" in the fingerprint as %2F.
+	// GET /keys/{fingerprint}?limit=500
+	r.Get("/keys/*", func(w http.ResponseWriter, r *http.Request) {
+		fp, err := url.PathUnescape(chi.URLParam(r, "*"))
+		if err != nil || fp == "" {
+			http.Error(w, "bad fingerprint", 400)
+			return
+		}
+		if !strings.HasPrefix(fp, "SHA256:") {
+			fp = "SHA256:" + fp
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		d, err := a.store.GetKeyDetail(r.Context(), fp, limit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if d == nil {
+			http.Error(w, "not found", 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(d)
+	})
+
+	// Audit trail of remote commands.
+	// GET /audit/ssh?host=h&from=RFC3339&to=RFC3339&limit=500
+	r.Get("/audit/ssh", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := store.SSHAuditFilter{Host: q.Get("host"), Limit: 500}
+		if v := q.Get("from"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad from", 400)
+				return
+			}
+			f.From = t
+		}
+		if v := q.Get("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad to", 400)
+				return
+			}
+			f.To = t
+		}
+		if l := q.Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil {
+				f.Limit = v
+			}
+		}
+		rows, err := a.store.ListSSHAudit(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rows)
+	})
+
+	// GET /audit/ssh/verify
+	r.Get("/audit/ssh/verify", func(w http.ResponseWriter, r *http.Request) {
+		badID, checked, err := a.store.VerifySSHAuditChain(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": badID == 0, "checked": checked, "first_bad_id": badID})
+	})
+
+	// Transitive reach over login and key-trust edges: what from (a host or a
+	// SHA256 key fingerprint) can reach, or what can reach to.
+	// GET /graph/reach?from=web01|SHA256:...|to=prod-db&max_depth=0&evidence=log,key&min_confidence=0&max_age_days=0
+	r.Get("/graph/reach", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, to := q.Get("from"), q.Get("to")
+		if graph.IsKey(from) {
+			from = strings.ReplaceAll(from, " ", "+") // an unescaped "+" in the fingerprint
+		}
+		if (from == "") == (to == "") {
+			http.Error(w, "exactly one of from or to required", 400)
+			return
+		}
+		f, maxDepth, err := graphFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		g, err := graph.Load(r.Context(), a.store, f, from)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		node := from
+		if to != "" {
+			node = to
+		}
+		if !g.Has(node) {
+			http.Error(w, "not found", 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"from": from, "to": to, "reached": g.Reach(node, to != "", maxDepth)})
+	})
+
+	// Shortest path from a host or key fingerprint to a host; 404 if none.
+	// GET /graph/paths?from=web01|SHA256:...&to=prod-db&max_depth=0&evidence=log,key&min_confidence=0&max_age_days=0
+	r.Get("/graph/paths", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, to := q.Get("from"), q.Get("to")
+		if graph.IsKey(from) {
+			from = strings.ReplaceAll(from, " ", "+") // an unescaped "+" in the fingerprint
+		}
+		if from == "" || to == "" {
+			http.Error(w, "from and to required", 400)
+			return
+		}
+		f, maxDepth, err := graphFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		g, err := graph.Load(r.Context(), a.store, f, from)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		p := g.ShortestPath(from, to, maxDepth)
+		if p == nil {
+			http.Error(w, "no path", 404)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"from": from, "to": to, "hops": len(p), "path": p})
+	})
+
 	// Phase 4 (exports only): download graph export.
 	// GET /export/graph?format=json|csv|graphml
 	r.Get("/export/graph", func(w http.ResponseWriter, r *http.Request) {
@@ -176,6 +950,36 @@ func (a *API) Router() http.Handler {
 		_, _ = w.Write(b)
 	})
 
+	// GET /export/concerns?format=csv|json plus the /concerns filters, e.g.
+	// type=keys for the key cleanup findings.
+	r.Get("/export/concerns", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		f, err := concernFilter(q)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		if q.Get("limit") == "" {
+			f.Limit = 10000
+		}
+		if format != "csv" && format != "json" {
+			http.Error(w, "unknown format", 400)
+			return
+		}
+		b, ct, err := exporter.ExportConcerns(r.Context(), a.store, format, f)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", ct)
+		w.WriteHeader(200)
+		_, _ = w.Write(b)
+	})
+
 	// Web UI
 	ui, uiErr := webui.Handler()
 	if uiErr == nil {
@@ -184,3 +988,61 @@ func (a *API) Router() http.Handler {
 
 	return r
 }
+
+// concernFilter reads the type, host_id, status and limit parameters shared
+// by /concerns and /export/concerns.
+func concernFilter(q url.Values) (store.ConcernFilter, error) {
+	f := store.ConcernFilter{Status: q.Get("status")}
+	switch f.Status {
+	case "", "open", "resolved", "all":
+	default:
+		return f, errors.New("status must be open, resolved or all")
+	}
+	for _, t := range strings.Split(q.Get("type"), ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "":
+		case "keys":
+			f.Types = append(f.Types, store.KeyFindingTypes...)
+		default:
+			f.Types = append(f.Types, t)
+		}
+	}
+	if v := q.Get("host_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, errors.New("bad host_id")
+		}
+		f.HostID = id
+	}
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	return f, nil
+}
+
+// graphFilter reads the edge filter and max_depth parameters shared by
+// /graph/reach and /graph/paths.
+func graphFilter(q url.Values) (store.GraphEdgeFilter, int, error) {
+	var f store.GraphEdgeFilter
+	for _, e := range strings.Split(q.Get("evidence"), ",") {
+		switch e = strings.TrimSpace(e); e {
+		case "":
+		case store.EvidenceLog, store.EvidenceKey:
+			f.Evidence = append(f.Evidence, e)
+		default:
+			return f, 0, errors.New("evidence must be log or key")
+		}
+	}
+	var maxDepth, maxAge int
+	for name, dst := range map[string]*int{"min_confidence": &f.MinConfidence, "max_age_days": &maxAge, "max_depth": &maxDepth} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return f, 0, errors.New("bad " + name)
+			}
+			*dst = n
+		}
+	}
+	if maxAge > 0 {
+		f.Since = time.Now().AddDate(0, 0, -maxAge)
+	}
+	return f, maxDepth, nil
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// scanProgressPoll is how often the progress stream checks scan_job_hosts.
+// Polling the DB (rather than an in-process hub) works when the job runs on
+// another keyspiderd replica.
+const scanProgressPoll = time.Second
+
+// scanProgressOverlap is how far before the newest update the stream looks
+// again, for rows whose saving transaction committed late.
+const scanProgressOverlap = 10 * time.Second
+
+func (a *API) scanProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", 400)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", 500)
+		return
+	}
+	if _, err := a.store.GetScanJob(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	_, _ = w.Write([]byte(": ok\n\n"))
+	flusher.Flush()
+
+	// The cursor is the newest updated_at sent; sent holds the version of
+	// each row sent within the overlap window before it.
+	var last time.Time
+	sent := map[int64]time.Time{}
+	lastStatus := ""
+	t := time.NewTicker(scanProgressPoll)
+	defer t.Stop()
+	for {
+		ctx := r.Context()
+		job, err := a.store.GetScanJob(ctx, id)
+		if err != nil {
+			return
+		}
+		since := last
+		if !since.IsZero() {
+			since = since.Add(-scanProgressOverlap)
+		}
+		rows, err := a.store.ListScanJobHosts(ctx, id, since)
+		if err != nil {
+			return
+		}
+		hosts := rows[:0]
+		for _, h := range rows {
+			if v, ok := sent[h.ID]; ok && v.Equal(h.UpdatedAt) {
+				continue
+			}
+			sent[h.ID] = h.UpdatedAt
+			if h.UpdatedAt.After(last) {
+				last = h.UpdatedAt
+			}
+			hosts = append(hosts, h)
+		}
+		for hid, v := range sent {
+			if v.Before(last.Add(-scanProgressOverlap)) {
+				delete(sent, hid)
+			}
+		}
+		totals, err := a.store.ScanJobTotals(ctx, id)
+		if err != nil {
+			return
+		}
+
+		finished := job.Status == "done" || job.Status == "error" || job.Status == "cancelled"
+		if len(hosts) > 0 || finished || job.Status != lastStatus {
+			lastStatus = job.Status
+			b, err := json.Marshal(map[string]any{"job": job, "totals": totals, "hosts": hosts})
+			if err != nil {
+				return
+			}
+			if finished {
+				_, _ = w.Write([]byte("event: done\n"))
+			}
+			_, _ = w.Write([]byte("data: "))
+			_, _ = w.Write(b)
+			_, _ = w.Write([]byte("\n\n"))
+			flusher.Flush()
+		}
+		if finished {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
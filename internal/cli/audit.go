@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func auditCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit trail of remote commands keyspider executed",
+	}
+	cmd.AddCommand(auditListCmd(cfgPath))
+	cmd.AddCommand(auditVerifyCmd(cfgPath))
+	return cmd
+}
+
+func auditListCmd(cfgPath *string) *cobra.Command {
+	var host, from, to string
+	var since time.Duration
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List ssh_audit rows by host and time range",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			f := store.SSHAuditFilter{Host: host, Limit: limit}
+			if since > 0 {
+				f.From = time.Now().Add(-since)
+			}
+			if from != "" {
+				if f.From, err = time.Parse(time.RFC3339, from); err != nil {
+					return fmt.Errorf("bad --from: %w", err)
+				}
+			}
+			if to != "" {
+				if f.To, err = time.Parse(time.RFC3339, to); err != nil {
+					return fmt.Errorf("bad --to: %w", err)
+				}
+			}
+
+			rows, err := st.ListSSHAudit(ctx, f)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(rows)
+			}
+			for _, a := range rows {
+				fmt.Printf("%s host=%s initiator=%s mode=%s exit=%d duration_ms=%d bytes=%d sha256=%s\n  %s\n",
+					a.TS.Format(time.RFC3339), a.Host, a.Initiator, a.Mode, a.ExitStatus, a.DurationMS, a.BytesReturned, a.CommandSHA256, a.Command)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&host, "host", "", "only commands run on this host")
+	cmd.Flags().DurationVar(&since, "since", 24*time.Hour, "how far back to look (ignored if --from is set)")
+	cmd.Flags().StringVar(&from, "from", "", "start of range (RFC3339)")
+	cmd.Flags().StringVar(&to, "to", "", "end of range (RFC3339)")
+	cmd.Flags().IntVar(&limit, "limit", 500, "max rows")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func auditVerifyCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the ssh_audit hash chain (requires audit.hash_chain)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			badID, checked, err := st.VerifySSHAuditChain(ctx)
+			if err != nil {
+				return err
+			}
+			if badID != 0 {
+				return fmt.Errorf("audit chain broken at id=%d (%d rows checked)", badID, checked)
+			}
+			fmt.Printf("audit chain ok (%d rows checked)\n", checked)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
+	"github.com/spf13/cobra"
+)
+
+func commandsCmd(cfgPath *string) *cobra.Command {
+	var show bool
+
+	cmd := &cobra.Command{
+		Use:   "commands [name]",
+		Short: "Review the remote command catalog (built-ins + config overrides)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(*cfgPath)
+			if err != nil {
+				return err
+			}
+			cat, err := remotecmd.New(cfg.Commands.Overrides)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("catalog version %s\n", remotecmd.CatalogVersion)
+			for _, t := range cat.List() {
+				if len(args) == 1 && t.Name != args[0] {
+					continue
+				}
+				src := "builtin"
+				if t.Override {
+					src = "override"
+				}
+				fmt.Printf("\n%s/%s v%d (%s) read_only=%t\n  review: %s\n", t.OS, t.Name, t.Version, src, t.ReadOnly, t.Review)
+				for _, p := range t.Params {
+					opt := ""
+					if p.Optional {
+						opt = " (optional)"
+					}
+					fmt.Printf("  param %s: %s%s\n", p.Name, p.Kind, opt)
+				}
+				if show || len(args) == 1 {
+					fmt.Printf("  script:\n%s\n", t.Script)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&show, "show", false, "print every script")
+	return cmd
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/exporter"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func findingsCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "findings",
+		Short: "Key findings: unused, unknown, orphaned and over-shared keys",
+		Long: `Key findings, kept as concerns:
+
+  KEY_UNUSED            authorized key no login used for key_findings.unused_days
+  KEY_UNKNOWN           key used in logs but in no scanned authorized_keys for the account
+                        (AuthorizedKeysCommand, or a file keyspider did not scan)
+  KEY_ORPHANED_PRIVATE  private key found by key hunt whose public half is authorized nowhere
+  KEY_SHARED_PRIVATE    the same private key on more than key_findings.max_private_hosts hosts
+  KEY_WIDE_ACCESS       key authorized for more than key_findings.max_authorized_accounts accounts
+
+refresh recomputes them: new findings are raised and findings that no longer
+hold are resolved.`,
+	}
+	cmd.AddCommand(findingsRefreshCmd(cfgPath))
+	cmd.AddCommand(findingsListCmd(cfgPath))
+	cmd.AddCommand(findingsExportCmd(cfgPath))
+	return cmd
+}
+
+func findingsRefreshCmd(cfgPath *string) *cobra.Command {
+	var days, maxPrivate, maxAccounts int
+
+	cmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Recompute the key findings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			cfg, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			rules := store.KeyFindingRules{
+				UnusedDays:            cfg.KeyFindings.UnusedDays,
+				MaxPrivateHosts:       cfg.KeyFindings.MaxPrivateHosts,
+				MaxAuthorizedAccounts: cfg.KeyFindings.MaxAuthorizedAccounts,
+			}
+			if days > 0 {
+				rules.UnusedDays = days
+			}
+			if cmd.Flags().Changed("max-private-hosts") {
+				rules.MaxPrivateHosts = maxPrivate
+			}
+			if cmd.Flags().Changed("max-authorized-accounts") {
+				rules.MaxAuthorizedAccounts = maxAccounts
+			}
+			res, err := st.RefreshKeyFindings(ctx, rules)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("unused=%d unknown=%d orphaned_private=%d shared_private=%d wide_access=%d raised=%d resolved=%d\n",
+				res.Unused, res.Unknown, res.OrphanedPrivate, res.SharedPrivate, res.WideAccess, res.Raised, res.Resolved)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "unused-days", 0, "days without a login before a key is unused (default: key_findings.unused_days)")
+	cmd.Flags().IntVar(&maxPrivate, "max-private-hosts", 0, "hosts a private key may live on, 0 = no check (default: key_findings.max_private_hosts)")
+	cmd.Flags().IntVar(&maxAccounts, "max-authorized-accounts", 0, "accounts a key may be authorized for, 0 = no check (default: key_findings.max_authorized_accounts)")
+	return cmd
+}
+
+func findingsListCmd(cfgPath *string) *cobra.Command {
+	var f store.ConcernFilter
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List key findings",
+		Example: `  keyspider findings list
+  keyspider findings list --type KEY_UNUSED --host-id 12
+  keyspider findings list --status resolved --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(f.Types) == 0 {
+				f.Types = store.KeyFindingTypes
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			cs, err := st.ListConcerns(ctx, f)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(cs)
+			}
+			deref := func(p *string) string {
+				if p == nil {
+					return "-"
+				}
+				return *p
+			}
+			for _, c := range cs {
+				fmt.Printf("%d %s %s host=%s key=%s owner=%s: %s\n", c.ID, c.Type, c.Severity,
+					deref(c.Hostname), deref(c.Fingerprint), deref(c.Owner), deref(c.Details))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&f.Types, "type", nil, "KEY_UNUSED|KEY_UNKNOWN|KEY_ORPHANED_PRIVATE|KEY_SHARED_PRIVATE|KEY_WIDE_ACCESS (repeatable; default: all)")
+	cmd.Flags().Int64Var(&f.HostID, "host-id", 0, "only findings for this host")
+	cmd.Flags().StringVar(&f.Status, "status", "open", "open|resolved|all")
+	cmd.Flags().IntVar(&f.Limit, "limit", 1000, "max findings")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func findingsExportCmd(cfgPath *string) *cobra.Command {
+	var f store.ConcernFilter
+	var format, outPath string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export key findings as CSV or JSON (e.g. for the quarterly key cleanup)",
+		Example: `  keyspider findings export --out key-findings.csv
+  keyspider findings export --type KEY_UNUSED --format json --out unused.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(f.Types) == 0 {
+				f.Types = store.KeyFindingTypes
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			b, _, err := exporter.ExportConcerns(ctx, st, format, f)
+			if err != nil {
+				return err
+			}
+			if outPath == "" || outPath == "-" {
+				_, _ = os.Stdout.Write(b)
+				return nil
+			}
+			return os.WriteFile(outPath, b, 0644)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&f.Types, "type", nil, "KEY_UNUSED|KEY_UNKNOWN|KEY_ORPHANED_PRIVATE|KEY_SHARED_PRIVATE|KEY_WIDE_ACCESS (repeatable; default: all)")
+	cmd.Flags().Int64Var(&f.HostID, "host-id", 0, "only findings for this host")
+	cmd.Flags().StringVar(&f.Status, "status", "open", "open|resolved|all")
+	cmd.Flags().IntVar(&f.Limit, "limit", 10000, "max findings")
+	cmd.Flags().StringVar(&format, "format", "csv", "export format: csv|json")
+	cmd.Flags().StringVar(&outPath, "out", "-", "output path (or - for stdout)")
+	return cmd
+}
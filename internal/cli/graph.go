@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/graph"
+	"github.com/jsherman999/openclaw_keyspider/internal/identity"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func graphCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Attack path queries: what a host or key can reach, and how",
+		Long: `Path queries over the access graph. Edges are logins seen in logs (evidence
+"log") and key trust (evidence "key"): a private key on one host that is
+authorized on another. A key fingerprint (or public key) as the start follows
+the hosts it is authorized on; those first hops are filtered by
+--max-age-days only.`,
+	}
+	cmd.AddCommand(graphReachCmd(cfgPath))
+	cmd.AddCommand(graphPathCmd(cfgPath))
+	return cmd
+}
+
+// graphFlags are the edge filters shared by the graph commands.
+type graphFlags struct {
+	evidence      []string
+	minConfidence int
+	maxAgeDays    int
+	maxDepth      int
+	asJSON        bool
+}
+
+func (gf *graphFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&gf.evidence, "evidence", nil, "edge evidence to follow: log|key (repeatable; default: all)")
+	cmd.Flags().IntVar(&gf.minConfidence, "min-confidence", 0, "only edges at least this confident")
+	cmd.Flags().IntVar(&gf.maxAgeDays, "max-age-days", 0, "only edges seen in the last N days (0 = any)")
+	cmd.Flags().IntVar(&gf.maxDepth, "max-depth", 0, "max hops (0 = no limit)")
+	cmd.Flags().BoolVar(&gf.asJSON, "json", false, "print JSON")
+}
+
+func (gf *graphFlags) filter() (store.GraphEdgeFilter, error) {
+	f := store.GraphEdgeFilter{Evidence: gf.evidence, MinConfidence: gf.minConfidence}
+	for _, e := range gf.evidence {
+		if e != store.EvidenceLog && e != store.EvidenceKey {
+			return f, fmt.Errorf("evidence must be log or key, not %q", e)
+		}
+	}
+	if gf.maxAgeDays > 0 {
+		f.Since = time.Now().AddDate(0, 0, -gf.maxAgeDays)
+	}
+	return f, nil
+}
+
+// graphNode returns a host name as is, and a key (fingerprint or public key)
+// as its fingerprint.
+func graphNode(s string) (string, error) {
+	if graph.IsKey(s) || strings.Contains(strings.TrimSpace(s), " ") {
+		return identity.Fingerprint(s)
+	}
+	return s, nil
+}
+
+// formatPath renders p as "a -[log 80]-> b -[key 60]-> c".
+func formatPath(p []store.GraphEdge) string {
+	var sb strings.Builder
+	for i, e := range p {
+		if i == 0 {
+			sb.WriteString(e.Src)
+		}
+		fmt.Fprintf(&sb, " -[%s %d]-> %s", e.Evidence, e.Confidence, e.Dest)
+	}
+	return sb.String()
+}
+
+func graphReachCmd(cfgPath *string) *cobra.Command {
+	var gf graphFlags
+	var from, to string
+
+	cmd := &cobra.Command{
+		Use:   "reach",
+		Short: "List what a host or key can reach (--from), or what can reach a host (--to)",
+		Example: `  keyspider graph reach --from web01
+  keyspider graph reach --from SHA256:... --evidence key
+  keyspider graph reach --to prod-db --max-age-days 30`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (from == "") == (to == "") {
+				return errors.New("exactly one of --from or --to required")
+			}
+			f, err := gf.filter()
+			if err != nil {
+				return err
+			}
+			node := to
+			if from != "" {
+				if from, err = graphNode(from); err != nil {
+					return err
+				}
+				node = from
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			g, err := graph.Load(ctx, st, f, from)
+			if err != nil {
+				return err
+			}
+			if !g.Has(node) {
+				return fmt.Errorf("%s has no edges", node)
+			}
+			reached := g.Reach(node, to != "", gf.maxDepth)
+			if gf.asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(reached)
+			}
+			for _, r := range reached {
+				fmt.Printf("%s depth=%d: %s\n", r.Node, r.Depth, formatPath(r.Path))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "host or key to start from")
+	cmd.Flags().StringVar(&to, "to", "", "host to find the sources of")
+	gf.register(cmd)
+	return cmd
+}
+
+func graphPathCmd(cfgPath *string) *cobra.Command {
+	var gf graphFlags
+
+	cmd := &cobra.Command{
+		Use:   "path <from host|key> <to host>",
+		Short: "Show the shortest access path between two hosts, or from a key to a host",
+		Example: `  keyspider graph path jump01 prod-db
+  keyspider graph path SHA256:... prod-db --evidence key`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := gf.filter()
+			if err != nil {
+				return err
+			}
+			from, err := graphNode(args[0])
+			if err != nil {
+				return err
+			}
+			to := args[1]
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			g, err := graph.Load(ctx, st, f, from)
+			if err != nil {
+				return err
+			}
+			p := g.ShortestPath(from, to, gf.maxDepth)
+			if p == nil {
+				return fmt.Errorf("no path from %s to %s", from, to)
+			}
+			if gf.asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(p)
+			}
+			fmt.Printf("%d hop(s): %s\n", len(p), formatPath(p))
+			return nil
+		},
+	}
+
+	gf.register(cmd)
+	return cmd
+}
@@ -0,0 +1,348 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/identity"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func identitiesCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "identities",
+		Short: "Map key fingerprints to people, service accounts and teams",
+	}
+	cmd.AddCommand(identitiesListCmd(cfgPath))
+	cmd.AddCommand(identitiesAddCmd(cfgPath))
+	cmd.AddCommand(identitiesLinkCmd(cfgPath))
+	cmd.AddCommand(identitiesUnlinkCmd(cfgPath))
+	cmd.AddCommand(identitiesOwnersCmd(cfgPath))
+	cmd.AddCommand(identitiesDeriveCmd(cfgPath))
+	cmd.AddCommand(identitiesImportCmd(cfgPath))
+	return cmd
+}
+
+func identitiesListCmd(cfgPath *string) *cobra.Command {
+	var kind string
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List identities",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			ids, err := st.ListIdentities(ctx, kind, limit)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(ids)
+			}
+			for _, id := range ids {
+				team := "-"
+				if id.Team != nil {
+					team = *id.Team
+				}
+				fmt.Printf("%d %s %s team=%s source=%s\n", id.ID, id.Kind, id.Name, team, id.Source)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", "", "person|service|team (default: all)")
+	cmd.Flags().IntVar(&limit, "limit", 1000, "max identities")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func identitiesAddCmd(cfgPath *string) *cobra.Command {
+	var id store.Identity
+	var displayName, email, team string
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Create or update an identity",
+		Example: `  keyspider identities add alice@corp --display-name "Alice Ng" --team platform
+  keyspider identities add svc-backup --kind service --team storage`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id.Name = args[0]
+			id.Source = "manual"
+			id.DisplayName, id.Email, id.Team = optString(displayName), optString(email), optString(team)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			iid, err := st.UpsertIdentity(ctx, &id)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d %s %s\n", iid, id.Kind, id.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&id.Kind, "kind", store.IdentityPerson, "person|service|team")
+	cmd.Flags().StringVar(&displayName, "display-name", "", "display name")
+	cmd.Flags().StringVar(&email, "email", "", "email address")
+	cmd.Flags().StringVar(&team, "team", "", "team the identity belongs to (created if needed)")
+	return cmd
+}
+
+func identitiesLinkCmd(cfgPath *string) *cobra.Command {
+	var kind, label string
+
+	cmd := &cobra.Command{
+		Use:   "link <name> <fingerprint|public key>",
+		Short: "Record that a key belongs to an identity",
+		Example: `  keyspider identities link alice@corp SHA256:abc... --label "laptop key"
+  keyspider identities link svc-backup "$(cat backup.pub)" --kind service`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fp, err := identity.Fingerprint(args[1])
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			id, err := st.FindIdentity(ctx, kind, args[0])
+			if err != nil {
+				return err
+			}
+			if id == nil {
+				return fmt.Errorf("no %s identity %q (create it with identities add)", kind, args[0])
+			}
+			if err := st.LinkKey(ctx, fp, id.ID, optString(label), "manual", identity.ConfidenceManual); err != nil {
+				return err
+			}
+			fmt.Printf("%s -> %s\n", fp, id.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", store.IdentityPerson, "person|service|team")
+	cmd.Flags().StringVar(&label, "label", "", `which of the identity's keys this is, e.g. "laptop key"`)
+	return cmd
+}
+
+func identitiesUnlinkCmd(cfgPath *string) *cobra.Command {
+	var kind string
+
+	cmd := &cobra.Command{
+		Use:   "unlink <name> <fingerprint|public key>",
+		Short: "Remove a key from an identity",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fp, err := identity.Fingerprint(args[1])
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			id, err := st.FindIdentity(ctx, kind, args[0])
+			if err != nil {
+				return err
+			}
+			if id == nil {
+				return fmt.Errorf("no %s identity %q", kind, args[0])
+			}
+			ok, err := st.UnlinkKey(ctx, fp, id.ID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s is not linked to %s", fp, id.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", store.IdentityPerson, "person|service|team")
+	return cmd
+}
+
+func identitiesOwnersCmd(cfgPath *string) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "owners <fingerprint|public key>",
+		Short: "Show who a key belongs to, most confident first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fp, err := identity.Fingerprint(args[0])
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			owners, err := st.ListKeyOwners(ctx, fp, 0)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(owners)
+			}
+			if len(owners) == 0 {
+				fmt.Printf("%s: no known owner\n", fp)
+				return nil
+			}
+			for _, o := range owners {
+				fmt.Printf("%s (%s) source=%s confidence=%d\n", o.Attribution(), o.IdentityKind, o.Source, o.Confidence)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func identitiesDeriveCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "derive",
+		Short: "Guess owners of every known key from its comment (identities.comment_patterns)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			cfg, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			matched, total, err := identity.NewDeriver(cfg).DeriveAll(ctx, st)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("keys with comments=%d attributed=%d\n", total, matched)
+			return nil
+		},
+	}
+}
+
+func identitiesImportCmd(cfgPath *string) *cobra.Command {
+	var format string
+	var opts identity.LDIFOptions
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import identities and their keys from a CSV or LDIF directory export",
+		Example: `  keyspider identities import people.csv
+  ldapsearch -LLL '(sshPublicKey=*)' uid mail cn ou sshPublicKey > people.ldif
+  keyspider identities import people.ldif --team-attr ou`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if format == "" {
+				format = identity.FormatFor(path)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			var entries []identity.Entry
+			switch format {
+			case identity.FormatCSV:
+				entries, err = identity.ParseCSV(f)
+			case identity.FormatLDIF:
+				entries, err = identity.ParseLDIF(f, opts)
+			default:
+				return fmt.Errorf("unknown format %q (use csv|ldif)", format)
+			}
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("%s: no identities", path)
+			}
+
+			if dryRun {
+				for _, e := range entries {
+					fps := make([]string, 0, len(e.Keys))
+					for _, k := range e.Keys {
+						fps = append(fps, k.Fingerprint)
+					}
+					fmt.Printf("%s %s keys=%s\n", e.Identity.Kind, e.Identity.Name, strings.Join(fps, ","))
+				}
+				fmt.Printf("%d identities (dry run)\n", len(entries))
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			n, links, err := identity.Import(ctx, st, entries)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("identities=%d key_links=%d\n", n, links)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "csv|ldif (default: from the file extension)")
+	cmd.Flags().StringVar(&opts.Kind, "kind", store.IdentityPerson, "ldif: kind of every entry")
+	cmd.Flags().StringVar(&opts.NameAttr, "name-attr", "", "ldif: attribute naming the identity (default: uid, else mail)")
+	cmd.Flags().StringVar(&opts.TeamAttr, "team-attr", "", "ldif: attribute naming the team, e.g. ou")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the parsed identities without importing")
+	return cmd
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
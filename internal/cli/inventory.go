@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/inventory"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func inventoryCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Import hosts from an inventory/CMDB and compare it with what was scanned",
+	}
+	cmd.AddCommand(inventoryImportCmd(cfgPath))
+	cmd.AddCommand(inventoryHostsCmd(cfgPath))
+	cmd.AddCommand(inventoryGapsCmd(cfgPath))
+	return cmd
+}
+
+func inventoryImportCmd(cfgPath *string) *cobra.Command {
+	var format, source string
+	var prune, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import hosts (environment, owner team, tags, addresses) from CSV, JSON or an Ansible inventory",
+		Example: `  keyspider inventory import hosts.csv
+  keyspider inventory import /etc/ansible/hosts --format ansible --prune
+  ansible-inventory -i prod.yml --list > prod.json && keyspider inventory import prod.json --source prod`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if format == "" {
+				format = inventory.FormatFor(path)
+			}
+			if source == "" {
+				source = filepath.Base(path)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			hosts, err := inventory.Parse(f, format)
+			if err != nil {
+				return err
+			}
+			if len(hosts) == 0 {
+				return fmt.Errorf("%s: no hosts", path)
+			}
+
+			if dryRun {
+				for _, h := range hosts {
+					fmt.Printf("%s env=%s team=%s tags=%s ips=%s\n", h.Hostname, h.Environment, h.OwnerTeam,
+						strings.Join(h.Tags, ","), strings.Join(h.IPs, ","))
+				}
+				fmt.Printf("%d hosts (dry run)\n", len(hosts))
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			res, err := st.ImportInventory(ctx, source, hosts, prune)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("source=%s hosts=%d created=%d updated=%d pruned=%d\n", source, len(hosts), res.Created, res.Updated, res.Pruned)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "csv|json|ansible (default: from the file extension)")
+	cmd.Flags().StringVar(&source, "source", "", "name of this inventory, for --prune (default: the file name)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "drop hosts last imported from this source that the file no longer lists")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the parsed hosts without importing")
+	return cmd
+}
+
+func inventoryHostsCmd(cfgPath *string) *cobra.Command {
+	var f store.HostFilter
+	var inInventory string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "List hosts by tag, environment or owner team",
+		Example: `  keyspider inventory hosts --env production --tag web
+  keyspider inventory hosts --team dba --in-inventory false`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch inInventory {
+			case "":
+			case "true", "false":
+				v := inInventory == "true"
+				f.InInventory = &v
+			default:
+				return fmt.Errorf("--in-inventory must be true or false")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			hosts, err := st.FindHosts(ctx, f)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(hosts)
+			}
+			for _, h := range hosts {
+				printInventoryHost(h)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&f.Tags, "tag", nil, "hosts carrying this tag (repeatable; all must match)")
+	cmd.Flags().StringVar(&f.Environment, "env", "", "environment")
+	cmd.Flags().StringVar(&f.OwnerTeam, "team", "", "owner team")
+	cmd.Flags().StringVar(&inInventory, "in-inventory", "", "true|false")
+	cmd.Flags().IntVar(&f.Limit, "limit", 1000, "max hosts")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func inventoryGapsCmd(cfgPath *string) *cobra.Command {
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "gaps",
+		Short: "Hosts in inventory never scanned, and hosts seen but missing from inventory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			gaps, err := st.ListInventoryGaps(ctx, limit)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(gaps)
+			}
+			fmt.Printf("in inventory, never scanned (%d):\n", len(gaps.NeverScanned))
+			for _, h := range gaps.NeverScanned {
+				printInventoryHost(h)
+			}
+			fmt.Printf("\nseen, not in inventory (%d):\n", len(gaps.NotInInventory))
+			for _, h := range gaps.NotInInventory {
+				printInventoryHost(h)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 1000, "max hosts of each kind")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func printInventoryHost(h store.Host) {
+	deref := func(p *string) string {
+		if p == nil {
+			return "-"
+		}
+		return *p
+	}
+	scanned := "never"
+	if h.LastScannedAt != nil {
+		scanned = h.LastScannedAt.Format(time.RFC3339)
+	}
+	fmt.Printf("%s env=%s team=%s tags=%s ips=%s in_inventory=%t last_scanned=%s\n", h.Hostname,
+		deref(h.Environment), deref(h.OwnerTeam), strings.Join(h.Tags, ","), strings.Join(h.IPAddresses, ","), h.InInventory, scanned)
+}
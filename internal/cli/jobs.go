@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func jobsCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Manage the scan job queue",
+		Long: `Manage the scan job queue.
+
+Job kinds: scan (logs + spider from one host), authorized_keys, keyhunt and
+config_audit (per-host, over --host list), rescan_all (one child job per
+host last seen reachable) and key_findings (refresh the key cleanup findings).`,
+	}
+	cmd.AddCommand(jobsListCmd(cfgPath))
+	cmd.AddCommand(jobsCancelCmd(cfgPath))
+	cmd.AddCommand(jobsRetryCmd(cfgPath))
+	cmd.AddCommand(jobsEnqueueCmd(cfgPath))
+	return cmd
+}
+
+func jobsListCmd(cfgPath *string) *cobra.Command {
+	var status, host string
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List scan jobs (newest first)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			jobs, err := st.ListScanJobs(ctx, store.ScanJobFilter{Status: status, Host: host, Limit: limit})
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(jobs)
+			}
+			for _, j := range jobs {
+				worker := ""
+				if j.WorkerID != nil {
+					worker = *j.WorkerID
+				}
+				errMsg := ""
+				if j.Error != nil {
+					errMsg = " error=" + strconv.Quote(*j.Error)
+				}
+				fmt.Printf("id=%d kind=%s host=%s status=%s priority=%d depth=%d attempts=%d/%d created=%s worker=%s%s\n",
+					j.ID, j.Kind, j.TargetHost, j.Status, j.Priority, j.SpiderDepth, j.Attempts, j.MaxAttempts,
+					j.CreatedAt.Format(time.RFC3339), worker, errMsg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&status, "status", "", "filter by status: queued|running|done|error|cancelled")
+	cmd.Flags().StringVar(&host, "host", "", "filter by target host")
+	cmd.Flags().IntVar(&limit, "limit", 50, "max jobs")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func jobsCancelCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel <job-id>",
+		Short: "Cancel a queued or running scan job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("bad job id %q", args[0])
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			status, err := st.CancelScanJob(ctx, id)
+			if err != nil {
+				return err
+			}
+			if status == "running" {
+				fmt.Printf("job %d: cancel requested; the worker will stop it shortly\n", id)
+				return nil
+			}
+			fmt.Printf("job %d: %s\n", id, status)
+			return nil
+		},
+	}
+}
+
+func jobsRetryCmd(cfgPath *string) *cobra.Command {
+	var fresh bool
+
+	cmd := &cobra.Command{
+		Use:   "retry <job-id>",
+		Short: "Requeue a finished scan job (a failed or cancelled one resumes from its checkpoint unless --fresh)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("bad job id %q", args[0])
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			if err := st.RetryScanJob(ctx, id, fresh); err != nil {
+				return err
+			}
+			fmt.Printf("job %d: queued\n", id)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fresh, "fresh", false, "discard the checkpoint and start the scan over")
+	return cmd
+}
+
+func jobsEnqueueCmd(cfgPath *string) *cobra.Command {
+	var kind, childKind string
+	var hosts []string
+	var since time.Duration
+	var depth, priority int
+
+	cmd := &cobra.Command{
+		Use:   "enqueue",
+		Short: "Queue a job for the daemon's workers",
+		Example: `  keyspider jobs enqueue --kind config_audit --host web1 --host web2
+  keyspider jobs enqueue --kind rescan_all --child-kind authorized_keys --priority -5`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := store.JobSpec{Kind: kind, Since: since, SpiderDepth: depth, Priority: priority}
+			switch kind {
+			case store.JobKindScan:
+				if len(hosts) != 1 {
+					return fmt.Errorf("scan takes exactly one --host")
+				}
+				spec.TargetHost = hosts[0]
+			case store.JobKindRescanAll:
+				spec.Params.ChildKind = childKind
+			case store.JobKindKeyFindings:
+			default:
+				spec.Params.Hosts = hosts
+			}
+			if err := spec.Validate(); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			id, err := st.EnqueueJob(ctx, spec)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("job %d: queued (%s)\n", id, kind)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", store.JobKindScan, "job kind: scan|authorized_keys|keyhunt|config_audit|rescan_all|key_findings")
+	cmd.Flags().StringArrayVar(&hosts, "host", nil, "target host (repeatable)")
+	cmd.Flags().StringVar(&childKind, "child-kind", "", "rescan_all: kind of the per-host jobs (default scan)")
+	cmd.Flags().DurationVar(&since, "since", 168*time.Hour, "scan: how far back to read logs")
+	cmd.Flags().IntVar(&depth, "spider-depth", 0, "scan: spider depth")
+	cmd.Flags().IntVar(&priority, "priority", 0, "higher runs first")
+	return cmd
+}
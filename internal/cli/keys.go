@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/identity"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func keysCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Key spread across hosts, and where a key lives and was used",
+	}
+	cmd.AddCommand(keysSpreadCmd(cfgPath))
+	cmd.AddCommand(keysShowCmd(cfgPath))
+	return cmd
+}
+
+func keysSpreadCmd(cfgPath *string) *cobra.Command {
+	var f store.KeySpreadFilter
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "spread",
+		Short: "List keys by how widely they are authorized and copied, widest first",
+		Example: `  keyspider keys spread --min-accounts 10
+  keyspider keys spread --min-private-hosts 2 --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			ks, err := st.ListKeySpread(ctx, f)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(ks)
+			}
+			for _, k := range ks {
+				owner := "-"
+				if k.Owner != nil {
+					owner = *k.Owner
+				}
+				fmt.Printf("%s %s hosts=%d users=%d accounts=%d private_copies=%d private_hosts=%d logins=%d owner=%s\n",
+					k.Fingerprint, k.KeyType, k.AuthorizedHosts, k.AuthorizedUsers, k.AuthorizedAccounts,
+					k.PrivateCopies, k.PrivateHosts, k.LoginCount, owner)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&f.MinAccounts, "min-accounts", 0, "only keys authorized for at least this many accounts")
+	cmd.Flags().IntVar(&f.MinPrivateHosts, "min-private-hosts", 0, "only keys whose private half is on at least this many hosts")
+	cmd.Flags().IntVar(&f.Limit, "limit", 200, "max keys")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func keysShowCmd(cfgPath *string) *cobra.Command {
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "show <fingerprint|public key>",
+		Short: "Show where a key was found and the logins made with it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fp, err := identity.Fingerprint(args[0])
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			d, err := st.GetKeyDetail(ctx, fp, limit)
+			if err != nil {
+				return err
+			}
+			if d == nil {
+				return fmt.Errorf("key %s not found", fp)
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(d)
+			}
+			deref := func(p *string) string {
+				if p == nil {
+					return "-"
+				}
+				return *p
+			}
+			fmt.Printf("%s %s comment=%s owner=%s\n", d.Fingerprint, d.KeyType, deref(d.Comment), deref(d.Owner))
+			fmt.Printf("authorized: hosts=%d users=%d accounts=%d  private: copies=%d hosts=%d  logins=%d\n",
+				d.AuthorizedHosts, d.AuthorizedUsers, d.AuthorizedAccounts, d.PrivateCopies, d.PrivateHosts, d.LoginCount)
+			fmt.Println("locations:")
+			for _, l := range d.Locations {
+				state := "current"
+				if !l.Current {
+					state = "gone"
+				}
+				fmt.Printf("  %s %s:%s user=%s last_seen=%s %s\n", l.InstanceType, l.Hostname, l.Path, deref(l.Username),
+					l.LastSeen.Format(time.RFC3339), state)
+			}
+			fmt.Println("logins:")
+			for _, e := range d.Logins {
+				fmt.Printf("  %s %s@%s from %s result=%s\n", e.TS.Format(time.RFC3339), deref(e.DestUser), e.DestHostname,
+					deref(e.SourceIP), deref(e.Result))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 500, "max logins")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
@@ -8,7 +8,10 @@ import (
 
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
 	"github.com/jsherman999/openclaw_keyspider/internal/spider"
+	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +26,15 @@ func Main() {
 
 	root.AddCommand(scanCmd(&cfgPath))
 	root.AddCommand(exportCmd(&cfgPath))
+	root.AddCommand(auditCmd(&cfgPath))
+	root.AddCommand(commandsCmd(&cfgPath))
+	root.AddCommand(jobsCmd(&cfgPath))
+	root.AddCommand(schedulesCmd(&cfgPath))
+	root.AddCommand(inventoryCmd(&cfgPath))
+	root.AddCommand(identitiesCmd(&cfgPath))
+	root.AddCommand(findingsCmd(&cfgPath))
+	root.AddCommand(keysCmd(&cfgPath))
+	root.AddCommand(graphCmd(&cfgPath))
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -55,7 +67,9 @@ func scanCmd(cfgPath *string) *cobra.Command {
 				return err
 			}
 
-			sp := spider.New(cfg, dbConn)
+			ctx = sshclient.WithInitiator(ctx, "cli:scan")
+			st := store.New(dbConn)
+			sp := spider.New(cfg, dbConn, reach.New(cfg, st, sshclient.NewAudited(cfg, st)))
 			res, err := sp.ScanHost(ctx, host, since, depth)
 			if err != nil {
 				return err
@@ -73,3 +87,20 @@ func scanCmd(cfgPath *string) *cobra.Command {
 	_ = cmd.MarkFlagRequired("host")
 	return cmd
 }
+
+// openStore loads config, connects and migrates the database. Callers must Close the returned DB.
+func openStore(ctx context.Context, cfgPath string) (*config.Config, *db.DB, *store.Store, error) {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dbConn, err := db.Open(ctx, cfg.DB.DSN)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := db.ApplyMigrations(ctx, dbConn); err != nil {
+		dbConn.Close()
+		return nil, nil, nil, err
+	}
+	return cfg, dbConn, store.New(dbConn), nil
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/schedule"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func schedulesCmd(cfgPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedules",
+		Short: "Manage recurring jobs and exports run by keyspiderd",
+	}
+	cmd.AddCommand(schedulesListCmd(cfgPath))
+	cmd.AddCommand(schedulesAddCmd(cfgPath))
+	cmd.AddCommand(schedulesDeleteCmd(cfgPath))
+	cmd.AddCommand(schedulesToggleCmd(cfgPath, "pause"))
+	cmd.AddCommand(schedulesToggleCmd(cfgPath, "resume"))
+	cmd.AddCommand(schedulesToggleCmd(cfgPath, "run"))
+	return cmd
+}
+
+func schedulesListCmd(cfgPath *string) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			scs, err := st.ListSchedules(ctx)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(scs)
+			}
+			for _, sc := range scs {
+				last := "never"
+				if sc.LastRunAt != nil {
+					last = sc.LastRunAt.Format(time.RFC3339)
+				}
+				status := ""
+				if sc.LastStatus != nil {
+					status = " last_status=" + *sc.LastStatus
+				}
+				if sc.LastError != nil {
+					status += " last_error=" + strconv.Quote(*sc.LastError)
+				}
+				spec, _ := json.Marshal(sc.Spec)
+				fmt.Printf("%s cron=%q tz=%s action=%s spec=%s enabled=%t misfire=%s next=%s last=%s%s\n",
+					sc.Name, sc.Cron, sc.Timezone, sc.Action, spec, sc.Enabled, sc.MisfirePolicy,
+					sc.NextRunAt.Format(time.RFC3339), last, status)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON")
+	return cmd
+}
+
+func schedulesAddCmd(cfgPath *string) *cobra.Command {
+	var sc store.Schedule
+	var since time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Create a schedule",
+		Example: `  keyspider schedules add nightly-web1 --cron "0 2 * * *" --kind scan --host web1 --spider-depth 1
+  keyspider schedules add weekly-audit --cron "@weekly" --kind rescan_all --child-kind config_audit
+  keyspider schedules add graph-csv --cron "30 6 * * mon-fri" --tz Europe/London --export csv`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc.Name = args[0]
+			sc.Enabled = true
+			sc.Action = store.ScheduleActionJob
+			if sc.Spec.Format != "" {
+				sc.Action = store.ScheduleActionExport
+			}
+			sc.Spec.SinceSeconds = int(since.Seconds())
+			if err := schedule.Prepare(&sc, time.Now()); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			if _, err := st.CreateSchedule(ctx, &sc); err != nil {
+				return err
+			}
+			fmt.Printf("schedule %s: next run %s\n", sc.Name, sc.NextRunAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&sc.Cron, "cron", "", `cron expression ("min hour dom month dow" or @daily etc.)`)
+	cmd.Flags().StringVar(&sc.Timezone, "tz", "UTC", "timezone the cron expression is evaluated in")
+	cmd.Flags().StringVar(&sc.MisfirePolicy, "misfire", store.MisfireRunOnce, "when a run was missed: run_once|skip")
+	cmd.Flags().StringVar(&sc.Spec.Kind, "kind", store.JobKindScan, "job kind: scan|authorized_keys|keyhunt|config_audit|rescan_all|key_findings")
+	cmd.Flags().StringVar(&sc.Spec.Host, "host", "", "target host")
+	cmd.Flags().StringArrayVar(&sc.Spec.Hosts, "hosts", nil, "more target hosts for host-list kinds (repeatable)")
+	cmd.Flags().StringVar(&sc.Spec.ChildKind, "child-kind", "", "rescan_all: kind of the per-host jobs (default scan)")
+	cmd.Flags().DurationVar(&since, "since", 168*time.Hour, "scan: how far back to read logs")
+	cmd.Flags().IntVar(&sc.Spec.SpiderDepth, "spider-depth", 0, "scan: spider depth")
+	cmd.Flags().IntVar(&sc.Spec.Priority, "priority", 0, "job priority")
+	cmd.Flags().StringVar(&sc.Spec.Format, "export", "", "make this an export schedule: json|csv|graphml")
+	cmd.Flags().IntVar(&sc.Spec.Limit, "limit", 10000, "export: max rows for hosts/edges")
+	_ = cmd.MarkFlagRequired("cron")
+	return cmd
+}
+
+func schedulesDeleteCmd(cfgPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			ok, err := st.DeleteSchedule(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("no schedule %q", args[0])
+			}
+			fmt.Printf("schedule %s: deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+// schedulesToggleCmd builds pause, resume and run.
+func schedulesToggleCmd(cfgPath *string, op string) *cobra.Command {
+	short := map[string]string{
+		"pause":  "Stop firing a schedule",
+		"resume": "Resume a paused schedule from its next regular run",
+		"run":    "Fire a schedule on the scheduler's next tick",
+	}[op]
+
+	return &cobra.Command{
+		Use:   op + " <name>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, dbConn, st, err := openStore(ctx, *cfgPath)
+			if err != nil {
+				return err
+			}
+			defer dbConn.Close()
+
+			name := args[0]
+			var ok bool
+			switch op {
+			case "pause":
+				ok, err = st.SetScheduleEnabled(ctx, name, false, time.Time{})
+			case "resume":
+				sc, err := st.GetSchedule(ctx, name)
+				if err != nil {
+					return err
+				}
+				if sc == nil {
+					return fmt.Errorf("no schedule %q", name)
+				}
+				next, err := schedule.NextRun(sc.Cron, sc.Timezone, time.Now())
+				if err != nil {
+					return err
+				}
+				ok, err = st.SetScheduleEnabled(ctx, name, true, next)
+				if err != nil {
+					return err
+				}
+			case "run":
+				ok, err = st.TriggerSchedule(ctx, name)
+			}
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("no schedule %q (or it is paused)", name)
+			}
+			fmt.Printf("schedule %s: %s ok\n", name, op)
+			return nil
+		},
+	}
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
 	"github.com/spf13/viper"
 )
 
@@ -17,17 +21,61 @@ type Config struct {
 	} `mapstructure:"api"`
 
 	SSH struct {
-		User                 string        `mapstructure:"user"`
+		User                  string        `mapstructure:"user"`
 		ConnectTimeoutSeconds int           `mapstructure:"connect_timeout_seconds"`
-		ConnectTimeout       time.Duration  `mapstructure:"-"`
+		ConnectTimeout        time.Duration `mapstructure:"-"`
+		// Multiplex log streams, probes and commands to a host over one
+		// connection (OpenSSH ControlMaster); 0 disables.
+		ControlPersistSeconds int    `mapstructure:"control_persist_seconds"`
+		ControlDir            string `mapstructure:"control_dir"` // control sockets; default <tmp>/keyspider-ssh
 	} `mapstructure:"ssh"`
 
+	// Reach caches reachability probes (shared by watcher and spider).
+	Reach struct {
+		TTLSeconds         int `mapstructure:"ttl_seconds"`          // reachable answers
+		NegativeTTLSeconds int `mapstructure:"negative_ttl_seconds"` // unreachable answers
+		Workers            int `mapstructure:"workers"`              // background probes at once
+		QueueSize          int `mapstructure:"queue_size"`
+	} `mapstructure:"reach"`
+
+	// Commands overrides built-in remote command templates, keyed "os/name"
+	// (e.g. "aix/sshd_logs"). Overrides must pass the read-only check.
+	Commands struct {
+		Overrides map[string]string `mapstructure:"overrides"`
+	} `mapstructure:"commands"`
+
+	// Audit records every remote command in the append-only ssh_audit table.
+	Audit struct {
+		Enabled   bool `mapstructure:"enabled"`
+		HashChain bool `mapstructure:"hash_chain"` // link rows with sha256 for tamper evidence
+	} `mapstructure:"audit"`
+
 	Discovery struct {
+		// DNS names log sources by their reverse DNS (see internal/resolver).
 		DNS struct {
-			Enabled bool `mapstructure:"enabled"`
+			Enabled            bool   `mapstructure:"enabled"`
+			Server             string `mapstructure:"server"` // host:port; empty uses the system resolver
+			TimeoutSeconds     int    `mapstructure:"timeout_seconds"`
+			TTLSeconds         int    `mapstructure:"ttl_seconds"`          // answers with names
+			NegativeTTLSeconds int    `mapstructure:"negative_ttl_seconds"` // answers without
+			RequireFCrDNS      bool   `mapstructure:"require_fcrdns"`       // name a source only if its PTR resolves back to it
+			MaxEntries         int    `mapstructure:"max_entries"`
 		} `mapstructure:"dns"`
 	} `mapstructure:"discovery"`
 
+	// Scope limits which hosts the spider and watcher may probe.
+	// Deny rules win; if any allow rule is set, a host must match one.
+	Scope struct {
+		AllowCIDRs         []string `mapstructure:"allow_cidrs"`
+		DenyCIDRs          []string `mapstructure:"deny_cidrs"`
+		AllowDomains       []string `mapstructure:"allow_domains"` // suffix match, e.g. "corp.example.com"
+		DenyDomains        []string `mapstructure:"deny_domains"`
+		AllowHostnameRegex []string `mapstructure:"allow_hostname_regex"`
+		DenyHostnameRegex  []string `mapstructure:"deny_hostname_regex"`
+		AllowTags          []string `mapstructure:"allow_tags"` // inventory tags
+		DenyTags           []string `mapstructure:"deny_tags"`
+	} `mapstructure:"scope"`
+
 	KeyHunt struct {
 		Enabled    bool     `mapstructure:"enabled"`
 		AllowRoots []string `mapstructure:"allow_roots"`
@@ -35,13 +83,109 @@ type Config struct {
 		MaxDepth   int      `mapstructure:"max_depth"`
 	} `mapstructure:"key_hunt"`
 
+	// KeyFindings drives the key findings (keyspider findings refresh,
+	// key_findings jobs): an authorized key no login used for UnusedDays is
+	// reported as unused; a private key on more than MaxPrivateHosts hosts, or
+	// a key authorized for more than MaxAuthorizedAccounts accounts, as
+	// spread too wide (0 turns either check off).
+	KeyFindings struct {
+		UnusedDays            int `mapstructure:"unused_days"`
+		MaxPrivateHosts       int `mapstructure:"max_private_hosts"`
+		MaxAuthorizedAccounts int `mapstructure:"max_authorized_accounts"`
+	} `mapstructure:"key_findings"`
+
+	// Identities attributes keys to people and service accounts. Comment
+	// patterns derive owners from ssh_keys.comment (first match wins).
+	Identities struct {
+		CommentPatterns []CommentPattern `mapstructure:"comment_patterns"`
+	} `mapstructure:"identities"`
+
+	// Worker controls scan job leases. A running job's lease is renewed every
+	// heartbeat; the reaper requeues jobs whose lease expired (crashed worker)
+	// or fails them once scan_jobs.max_attempts is used up.
+	Worker struct {
+		LeaseSeconds          int `mapstructure:"lease_seconds"`
+		HeartbeatSeconds      int `mapstructure:"heartbeat_seconds"`
+		ReaperIntervalSeconds int `mapstructure:"reaper_interval_seconds"`
+	} `mapstructure:"worker"`
+
+	// Leader controls election of the keyspiderd replica that runs singleton
+	// duties (watchers, scheduler, reaper). A standby notices a dead leader
+	// within about one check interval.
+	Leader struct {
+		CheckIntervalSeconds int `mapstructure:"check_interval_seconds"`
+	} `mapstructure:"leader"`
+
+	// Scheduler fires the schedules table (recurring jobs and exports). Only
+	// one keyspiderd claims due schedules at a time.
+	Scheduler struct {
+		Enabled             bool   `mapstructure:"enabled"`
+		IntervalSeconds     int    `mapstructure:"interval_seconds"`
+		MisfireGraceSeconds int    `mapstructure:"misfire_grace_seconds"` // later than this is a misfire
+		ExportDir           string `mapstructure:"export_dir"`            // scheduled exports are written here
+	} `mapstructure:"scheduler"`
+
 	Watcher struct {
-		Enabled      bool              `mapstructure:"enabled"`
-		Hosts        []string          `mapstructure:"hosts"`
-		DefaultMode  string            `mapstructure:"default_mode"`  // auto|journal|tail
-		HostModes    map[string]string `mapstructure:"host_modes"`    // hostname -> mode
-		DedupeWindow int               `mapstructure:"dedupe_window"` // in-memory recent hashes per host
+		Enabled          bool              `mapstructure:"enabled"`
+		Hosts            []string          `mapstructure:"hosts"`             // seeds the watchers table; manage at runtime via /watchers
+		DefaultMode      string            `mapstructure:"default_mode"`      // auto|journal|tail
+		HostModes        map[string]string `mapstructure:"host_modes"`        // hostname -> mode (seeding only)
+		DedupeWindow     int               `mapstructure:"dedupe_window"`     // in-memory recent hashes per host
+		ReconcileSeconds int               `mapstructure:"reconcile_seconds"` // how often the watchers table is re-read
+		// A connected watcher with no log line for this long raises WATCHER_SILENT (0 disables).
+		SilentAfterSeconds int `mapstructure:"silent_after_seconds"`
+		// Reconnects back off exponentially (with jitter) from base to max seconds.
+		BackoffBaseSeconds int `mapstructure:"backoff_base_seconds"`
+		BackoffMaxSeconds  int `mapstructure:"backoff_max_seconds"`
+		// After this many consecutive failures the breaker opens and the host is
+		// left alone for breaker_cooldown_seconds before one trial reconnect.
+		BreakerThreshold       int `mapstructure:"breaker_threshold"`
+		BreakerCooldownSeconds int `mapstructure:"breaker_cooldown_seconds"`
 	} `mapstructure:"watcher"`
+
+	// Syslog receives pushed sshd logs (RFC3164/RFC5424) in every replica.
+	// An empty listen address disables that transport.
+	Syslog struct {
+		Enabled         bool     `mapstructure:"enabled"`
+		UDPListen       string   `mapstructure:"udp_listen"`
+		TCPListen       string   `mapstructure:"tcp_listen"`
+		TLSListen       string   `mapstructure:"tls_listen"`
+		TLSCertFile     string   `mapstructure:"tls_cert_file"`
+		TLSKeyFile      string   `mapstructure:"tls_key_file"`
+		TLSClientCAFile string   `mapstructure:"tls_client_ca_file"` // require client certs signed by this CA
+		HostFrom        string   `mapstructure:"host_from"`          // hostname|address
+		AllowCIDRs      []string `mapstructure:"allow_cidrs"`        // senders accepted; empty only with mutual TLS alone
+		MaxMessageBytes int      `mapstructure:"max_message_bytes"`
+		MaxConnections  int      `mapstructure:"max_connections"` // concurrent TCP+TLS senders
+	} `mapstructure:"syslog"`
+
+	// JournalRemote accepts systemd-journal-upload pushes (POST /upload,
+	// application/vnd.fdo.journal) in every replica.
+	JournalRemote struct {
+		Enabled         bool     `mapstructure:"enabled"`
+		Listen          string   `mapstructure:"listen"`
+		TLSCertFile     string   `mapstructure:"tls_cert_file"` // serve HTTPS when set
+		TLSKeyFile      string   `mapstructure:"tls_key_file"`
+		TLSClientCAFile string   `mapstructure:"tls_client_ca_file"` // require client certs signed by this CA
+		HostFrom        string   `mapstructure:"host_from"`          // hostname|address
+		AllowCIDRs      []string `mapstructure:"allow_cidrs"`        // senders accepted; empty only with client certificates
+		MaxFieldBytes   int      `mapstructure:"max_field_bytes"`    // largest field in an upload
+		MaxUploadBytes  int64    `mapstructure:"max_upload_bytes"`   // largest request body
+	} `mapstructure:"journal_remote"`
+}
+
+// DefaultSystemAccounts matches key comments naming an OS or cloud image
+// account rather than a person (root@host, ec2-user@host).
+const DefaultSystemAccounts = `^(root|admin|administrator|ubuntu|ec2-user|centos|rocky|almalinux|debian|fedora|azureuser|opc|cloud-user|core|vagrant|pi|git|nobody|daemon|www-data|oracle|postgres|jenkins|ansible|deploy)@`
+
+// CommentPattern maps a key comment to an owner. Name and Label expand the
+// regex's named groups (${user}, ${host}).
+type CommentPattern struct {
+	Regex   string `mapstructure:"regex"`
+	Exclude string `mapstructure:"exclude"` // comments matching this are skipped (system accounts)
+	Kind    string `mapstructure:"kind"`    // person|service|team
+	Name    string `mapstructure:"name"`
+	Label   string `mapstructure:"label"`
 }
 
 func Load(path string) (*Config, error) {
@@ -52,16 +196,65 @@ func Load(path string) (*Config, error) {
 	v.SetDefault("api.listen", "127.0.0.1:8080")
 	v.SetDefault("ssh.user", "root")
 	v.SetDefault("ssh.connect_timeout_seconds", 10)
+	v.SetDefault("ssh.control_persist_seconds", 60)
+	v.SetDefault("ssh.control_dir", "")
+	v.SetDefault("reach.ttl_seconds", 600)
+	v.SetDefault("reach.negative_ttl_seconds", 120)
+	v.SetDefault("reach.workers", 4)
+	v.SetDefault("reach.queue_size", 1024)
+	v.SetDefault("commands.overrides", map[string]string{})
+	v.SetDefault("audit.enabled", true)
+	v.SetDefault("audit.hash_chain", false)
 	v.SetDefault("discovery.dns.enabled", true)
+	v.SetDefault("discovery.dns.server", "")
+	v.SetDefault("discovery.dns.timeout_seconds", 2)
+	v.SetDefault("discovery.dns.ttl_seconds", 3600)
+	v.SetDefault("discovery.dns.negative_ttl_seconds", 300)
+	v.SetDefault("discovery.dns.require_fcrdns", true)
+	v.SetDefault("discovery.dns.max_entries", 100000)
+	v.SetDefault("identities.comment_patterns", []map[string]any{
+		{"regex": `^(?P<user>[A-Za-z][A-Za-z0-9._-]*)@(?P<host>[A-Za-z0-9._-]+)$`, "exclude": DefaultSystemAccounts, "kind": "person", "name": "${user}", "label": "${host} key"},
+	})
+	v.SetDefault("key_findings.unused_days", 90)
+	v.SetDefault("key_findings.max_private_hosts", 1)
+	v.SetDefault("key_findings.max_authorized_accounts", 20)
 	v.SetDefault("key_hunt.enabled", true)
 	v.SetDefault("key_hunt.allow_roots", []string{"/home", "/root", "/etc"})
 	v.SetDefault("key_hunt.max_files", 20000)
 	v.SetDefault("key_hunt.max_depth", 10)
+	v.SetDefault("worker.lease_seconds", 120)
+	v.SetDefault("worker.heartbeat_seconds", 30)
+	v.SetDefault("worker.reaper_interval_seconds", 30)
+	v.SetDefault("leader.check_interval_seconds", 10)
+	v.SetDefault("scheduler.enabled", true)
+	v.SetDefault("scheduler.interval_seconds", 30)
+	v.SetDefault("scheduler.misfire_grace_seconds", 300)
+	v.SetDefault("scheduler.export_dir", "./exports")
 	v.SetDefault("watcher.enabled", false)
 	v.SetDefault("watcher.hosts", []string{})
 	v.SetDefault("watcher.default_mode", "auto")
 	v.SetDefault("watcher.host_modes", map[string]string{})
 	v.SetDefault("watcher.dedupe_window", 256)
+	v.SetDefault("watcher.reconcile_seconds", 15)
+	v.SetDefault("watcher.silent_after_seconds", 3600)
+	v.SetDefault("watcher.backoff_base_seconds", 2)
+	v.SetDefault("watcher.backoff_max_seconds", 300)
+	v.SetDefault("watcher.breaker_threshold", 5)
+	v.SetDefault("watcher.breaker_cooldown_seconds", 900)
+	v.SetDefault("syslog.enabled", false)
+	v.SetDefault("syslog.udp_listen", ":5514")
+	v.SetDefault("syslog.tcp_listen", ":5514")
+	v.SetDefault("syslog.tls_listen", "")
+	v.SetDefault("syslog.host_from", "address")
+	v.SetDefault("syslog.allow_cidrs", []string{})
+	v.SetDefault("syslog.max_message_bytes", 65536)
+	v.SetDefault("syslog.max_connections", 1024)
+	v.SetDefault("journal_remote.enabled", false)
+	v.SetDefault("journal_remote.listen", ":19532")
+	v.SetDefault("journal_remote.host_from", "address")
+	v.SetDefault("journal_remote.allow_cidrs", []string{})
+	v.SetDefault("journal_remote.max_field_bytes", 1048576)
+	v.SetDefault("journal_remote.max_upload_bytes", 268435456)
 
 	// Env overrides
 	v.SetEnvPrefix("KEYSPIDER")
@@ -85,5 +278,139 @@ func Load(path string) (*Config, error) {
 	if c.DB.DSN == "" {
 		return nil, fmt.Errorf("db.dsn is required (set KEYSPIDER_DB_DSN or config file)")
 	}
+	if err := validateScope(&c); err != nil {
+		return nil, err
+	}
+	if err := validateDNS(&c); err != nil {
+		return nil, err
+	}
+	if err := validateIdentities(&c); err != nil {
+		return nil, err
+	}
+	if c.KeyFindings.UnusedDays <= 0 {
+		return nil, fmt.Errorf("key_findings.unused_days must be positive")
+	}
+	if c.KeyFindings.MaxPrivateHosts < 0 || c.KeyFindings.MaxAuthorizedAccounts < 0 {
+		return nil, fmt.Errorf("key_findings: thresholds must not be negative")
+	}
+	if err := validateSyslog(&c); err != nil {
+		return nil, err
+	}
+	if err := validateJournalRemote(&c); err != nil {
+		return nil, err
+	}
+	if _, err := remotecmd.New(c.Commands.Overrides); err != nil {
+		return nil, err
+	}
 	return &c, nil
 }
+
+func validateDNS(c *Config) error {
+	d := &c.Discovery.DNS
+	if d.Server == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(d.Server); err != nil {
+		// A bare address means port 53.
+		if net.ParseIP(strings.Trim(d.Server, "[]")) == nil {
+			return fmt.Errorf("discovery.dns.server: %q is not host:port or an IP", d.Server)
+		}
+		d.Server = net.JoinHostPort(strings.Trim(d.Server, "[]"), "53")
+	}
+	return nil
+}
+
+func validateIdentities(c *Config) error {
+	for i, p := range c.Identities.CommentPatterns {
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("identities.comment_patterns[%d]: %w", i, err)
+		}
+		if _, err := regexp.Compile(p.Exclude); err != nil {
+			return fmt.Errorf("identities.comment_patterns[%d].exclude: %w", i, err)
+		}
+		switch p.Kind {
+		case "person", "service", "team":
+		default:
+			return fmt.Errorf("identities.comment_patterns[%d]: kind must be person, service or team", i)
+		}
+		if p.Name == "" {
+			return fmt.Errorf("identities.comment_patterns[%d]: name is required", i)
+		}
+	}
+	return nil
+}
+
+func validateSyslog(c *Config) error {
+	sl := &c.Syslog
+	if !sl.Enabled {
+		return nil
+	}
+	if sl.HostFrom != "hostname" && sl.HostFrom != "address" {
+		return fmt.Errorf("syslog.host_from: must be hostname or address, got %q", sl.HostFrom)
+	}
+	if sl.TLSListen != "" && (sl.TLSCertFile == "" || sl.TLSKeyFile == "") {
+		return fmt.Errorf("syslog.tls_listen needs syslog.tls_cert_file and syslog.tls_key_file")
+	}
+	// Anyone who can send a message can forge logins, so senders must be
+	// limited by address or by client certificate.
+	if len(sl.AllowCIDRs) == 0 && (sl.UDPListen != "" || sl.TCPListen != "" || sl.TLSClientCAFile == "") {
+		return fmt.Errorf("syslog: set allow_cidrs, or listen on tls_listen only with tls_client_ca_file")
+	}
+	for _, s := range sl.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(s)); err != nil {
+			return fmt.Errorf("syslog: bad cidr %q: %w", s, err)
+		}
+	}
+	return nil
+}
+
+func validateJournalRemote(c *Config) error {
+	jr := &c.JournalRemote
+	if !jr.Enabled {
+		return nil
+	}
+	if (jr.TLSCertFile == "") != (jr.TLSKeyFile == "") {
+		return fmt.Errorf("journal_remote: tls_cert_file and tls_key_file go together")
+	}
+	if jr.TLSClientCAFile != "" && jr.TLSCertFile == "" {
+		return fmt.Errorf("journal_remote.tls_client_ca_file needs tls_cert_file and tls_key_file")
+	}
+	if jr.HostFrom != "hostname" && jr.HostFrom != "address" {
+		return fmt.Errorf("journal_remote.host_from: must be hostname or address, got %q", jr.HostFrom)
+	}
+	// As with syslog, any accepted sender can forge logins.
+	if len(jr.AllowCIDRs) == 0 && jr.TLSClientCAFile == "" {
+		return fmt.Errorf("journal_remote: set allow_cidrs or tls_client_ca_file")
+	}
+	if jr.MaxFieldBytes <= 0 || jr.MaxUploadBytes <= 0 {
+		return fmt.Errorf("journal_remote: max_field_bytes and max_upload_bytes must be positive")
+	}
+	for _, s := range jr.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(s)); err != nil {
+			return fmt.Errorf("journal_remote: bad cidr %q: %w", s, err)
+		}
+	}
+	return nil
+}
+
+func validateScope(c *Config) error {
+	for _, list := range [][]string{c.Scope.AllowCIDRs, c.Scope.DenyCIDRs} {
+		for _, s := range list {
+			s = strings.TrimSpace(s)
+			if net.ParseIP(s) != nil {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return fmt.Errorf("scope: bad cidr %q: %w", s, err)
+			}
+		}
+	}
+	for _, list := range [][]string{c.Scope.AllowHostnameRegex, c.Scope.DenyHostnameRegex} {
+		for _, s := range list {
+			if _, err := regexp.Compile(s); err != nil {
+				return fmt.Errorf("scope: bad hostname regex %q: %w", s, err)
+			}
+		}
+	}
+	return nil
+}
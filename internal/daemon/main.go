@@ -7,12 +7,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/api"
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/journalremote"
+	"github.com/jsherman999/openclaw_keyspider/internal/leader"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
+	"github.com/jsherman999/openclaw_keyspider/internal/schedule"
+	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/jsherman999/openclaw_keyspider/internal/syslogd"
 	"github.com/jsherman999/openclaw_keyspider/internal/watcher"
 	"github.com/jsherman999/openclaw_keyspider/internal/watchhub"
 	"github.com/jsherman999/openclaw_keyspider/internal/worker"
@@ -76,21 +84,65 @@ func serveCmd(cfgPath *string) *cobra.Command {
 
 			hub := watchhub.New()
 			h := api.New(cfg, dbConn, hub)
+			// One prober for the spider and the watchers, so they share
+			// reachability answers and the probe worker pool.
+			st := store.New(dbConn)
+			rp := reach.New(cfg, st, sshclient.NewAudited(cfg, st))
 			srv := &http.Server{Addr: cfg.API.Listen, Handler: h.Router()}
 
 			bgCtx, bgCancel := context.WithCancel(context.Background())
 			defer bgCancel()
 
-			// Phase 3 watcher (streaming)
+			// Background scan worker (web/UI-triggered scan jobs). Runs in every replica.
+			workerDone := make(chan struct{})
 			go func() {
-				w := watcher.New(cfg, dbConn, hub)
-				w.Run(bgCtx)
+				defer close(workerDone)
+				sw := worker.NewScanWorker(cfg, dbConn, rp)
+				sw.Run(bgCtx)
 			}()
 
-			// Background scan worker (web/UI-triggered scan jobs)
+			// Push-mode receivers (syslog, journal-remote). Run in every
+			// replica: senders pick the replica they forward to.
+			pushDone := make(chan struct{})
 			go func() {
-				sw := worker.NewScanWorker(cfg, dbConn)
-				sw.Run(bgCtx)
+				defer close(pushDone)
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					syslogd.New(cfg, dbConn, hub, rp).Run(bgCtx)
+				}()
+				go func() {
+					defer wg.Done()
+					journalremote.New(cfg, dbConn, hub, rp).Run(bgCtx)
+				}()
+				wg.Wait()
+			}()
+
+			// Singleton duties run only in the elected leader.
+			leaderDone := make(chan struct{})
+			go func() {
+				defer close(leaderDone)
+				leader.New(cfg, dbConn, leader.RoleSingleton).Run(bgCtx, func(ctx context.Context) {
+					var wg sync.WaitGroup
+					run := func(f func(context.Context)) {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							f(ctx)
+						}()
+					}
+					// Phase 3 watcher (streaming)
+					run(watcher.New(cfg, dbConn, hub, rp).Run)
+					// Requeue or fail scan jobs whose worker lease expired.
+					run(worker.NewReaper(cfg, dbConn).Run)
+					// Recurring jobs and exports (schedules table).
+					if cfg.Scheduler.Enabled {
+						run(schedule.New(cfg, dbConn).Run)
+					}
+					<-ctx.Done()
+					wg.Wait()
+				})
 			}()
 
 			go func() {
@@ -110,6 +162,25 @@ func serveCmd(cfgPath *string) *cobra.Command {
 			if err := srv.Shutdown(shCtx); err != nil {
 				return fmt.Errorf("shutdown: %w", err)
 			}
+
+			// Let the scan worker checkpoint and requeue its job, and the leader
+			// step down, before the DB closes.
+			bgCancel()
+			select {
+			case <-workerDone:
+			case <-shCtx.Done():
+				log.Printf("scan worker did not stop in time")
+			}
+			select {
+			case <-pushDone:
+			case <-shCtx.Done():
+				log.Printf("push receivers did not stop in time")
+			}
+			select {
+			case <-leaderDone:
+			case <-shCtx.Done():
+				log.Printf("leader duties did not stop in time")
+			}
 			return nil
 		},
 	}
@@ -0,0 +1,9 @@
+-- Scope policy: inventory tags on hosts, out-of-scope edges
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS hosts_tags_idx ON hosts USING gin(tags);
+
+ALTER TABLE edges
+  ADD COLUMN IF NOT EXISTS out_of_scope boolean NOT NULL DEFAULT false;
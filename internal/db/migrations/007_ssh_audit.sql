@@ -0,0 +1,31 @@
+-- Audit trail of every remote command keyspider executes (append-only)
+
+CREATE TABLE IF NOT EXISTS ssh_audit (
+  id bigserial PRIMARY KEY,
+  ts timestamptz NOT NULL DEFAULT now(),
+  host text NOT NULL,
+  command_sha256 text NOT NULL,
+  command text NOT NULL,
+  initiator text NOT NULL, -- e.g. scan_job:42, watcher:host1, cli:scan
+  mode text NOT NULL,      -- run|stream
+  exit_status int NOT NULL,
+  duration_ms bigint NOT NULL,
+  bytes_returned bigint NOT NULL,
+  error text,
+  prev_hash text,          -- hash chain (optional; NULL when disabled)
+  row_hash text
+);
+
+CREATE INDEX IF NOT EXISTS ssh_audit_host_ts_idx ON ssh_audit(host, ts);
+CREATE INDEX IF NOT EXISTS ssh_audit_ts_idx ON ssh_audit(ts);
+
+CREATE OR REPLACE FUNCTION ssh_audit_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'ssh_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ssh_audit_no_update ON ssh_audit;
+CREATE TRIGGER ssh_audit_no_update
+  BEFORE UPDATE OR DELETE ON ssh_audit
+  FOR EACH ROW EXECUTE FUNCTION ssh_audit_append_only();
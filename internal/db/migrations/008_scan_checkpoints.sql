@@ -0,0 +1,17 @@
+-- Resumable scans: persisted BFS frontier, visited set and per-host phase.
+-- A row per host reached by a job; phase queued = frontier, anything else = visited.
+
+CREATE TABLE IF NOT EXISTS scan_job_hosts (
+  id bigserial PRIMARY KEY,
+  job_id bigint NOT NULL REFERENCES scan_jobs(id) ON DELETE CASCADE,
+  host text NOT NULL,
+  depth int NOT NULL,
+  phase text NOT NULL DEFAULT 'queued', -- queued|logs|authorized_keys|keyhunt|done|unreachable|out_of_scope
+  os_type text,
+  sources text[] NOT NULL DEFAULT '{}',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS scan_job_hosts_job_host_uq ON scan_job_hosts(job_id, host);
+CREATE INDEX IF NOT EXISTS scan_job_hosts_job_phase_idx ON scan_job_hosts(job_id, phase, id);
@@ -0,0 +1,10 @@
+-- Worker leases for scan_jobs so several keyspiderd replicas can share the queue.
+
+ALTER TABLE scan_jobs
+  ADD COLUMN IF NOT EXISTS worker_id text,
+  ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz,
+  ADD COLUMN IF NOT EXISTS heartbeat_at timestamptz,
+  ADD COLUMN IF NOT EXISTS attempts int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS max_attempts int NOT NULL DEFAULT 3;
+
+CREATE INDEX IF NOT EXISTS scan_jobs_lease_idx ON scan_jobs(lease_expires_at) WHERE status='running';
@@ -0,0 +1,9 @@
+-- Scan job priorities and cancellation
+
+ALTER TABLE scan_jobs
+  ADD COLUMN IF NOT EXISTS priority int NOT NULL DEFAULT 0, -- higher runs first
+  ADD COLUMN IF NOT EXISTS cancel_requested_at timestamptz;
+
+DROP INDEX IF EXISTS scan_jobs_status_idx;
+CREATE INDEX IF NOT EXISTS scan_jobs_status_idx ON scan_jobs(status, priority DESC, created_at);
+CREATE INDEX IF NOT EXISTS scan_jobs_target_idx ON scan_jobs(target_host, created_at);
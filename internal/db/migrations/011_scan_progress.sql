@@ -0,0 +1,14 @@
+-- Scan job results and per-host progress
+
+ALTER TABLE scan_jobs
+  ADD COLUMN IF NOT EXISTS result jsonb;
+
+ALTER TABLE scan_job_hosts
+  ADD COLUMN IF NOT EXISTS parent text,
+  ADD COLUMN IF NOT EXISTS events int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS keys int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS edges int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS concerns int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS errors text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS scan_job_hosts_updated_idx ON scan_job_hosts(job_id, updated_at);
@@ -0,0 +1,16 @@
+-- Job kinds beyond "scan": per-kind JSON params, fan-out parent, sshd config audit results
+
+ALTER TABLE scan_jobs
+  ADD COLUMN IF NOT EXISTS params jsonb NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS parent_job_id bigint REFERENCES scan_jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS scan_jobs_parent_idx ON scan_jobs(parent_job_id);
+
+CREATE TABLE IF NOT EXISTS sshd_configs (
+  host_id bigint PRIMARY KEY REFERENCES hosts(id) ON DELETE CASCADE,
+  source text NOT NULL,    -- sshd -T | sshd_config
+  settings jsonb NOT NULL, -- lowercase keyword -> value
+  collected_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS concerns_open_type_idx ON concerns(host_id, type) WHERE resolved_at IS NULL;
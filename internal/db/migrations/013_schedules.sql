@@ -0,0 +1,22 @@
+-- Recurring jobs and exports, fired by the keyspiderd scheduler
+
+CREATE TABLE IF NOT EXISTS schedules (
+  id bigserial PRIMARY KEY,
+  name text NOT NULL UNIQUE,
+  cron text NOT NULL,
+  timezone text NOT NULL DEFAULT 'UTC',
+  action text NOT NULL,                          -- job|export
+  spec jsonb NOT NULL DEFAULT '{}',
+  misfire_policy text NOT NULL DEFAULT 'run_once', -- run_once|skip
+  enabled boolean NOT NULL DEFAULT true,
+  next_run_at timestamptz NOT NULL,
+  last_run_at timestamptz,
+  last_status text,                              -- ok|error|skipped
+  last_error text,
+  last_job_id bigint REFERENCES scan_jobs(id) ON DELETE SET NULL,
+  last_output text,                              -- export file written by the last run
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS schedules_due_idx ON schedules(next_run_at) WHERE enabled;
@@ -0,0 +1,9 @@
+-- Current holder of each keyspiderd leader role (informational; the advisory
+-- lock is the source of truth)
+
+CREATE TABLE IF NOT EXISTS leader_status (
+  role text PRIMARY KEY,
+  holder text NOT NULL,
+  acquired_at timestamptz NOT NULL DEFAULT now(),
+  heartbeat_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+-- Watchers managed at runtime through the API; config only seeds the table
+
+ALTER TABLE watchers
+  ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'api', -- config|api
+  ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
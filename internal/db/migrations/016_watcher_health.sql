@@ -0,0 +1,10 @@
+-- Watcher health, reported by the leader's running watchers
+
+ALTER TABLE watchers
+  ADD COLUMN IF NOT EXISTS status text,          -- connecting|connected|backing_off|failed|stopped
+  ADD COLUMN IF NOT EXISTS active_mode text,     -- journal|tail (what auto resolved to)
+  ADD COLUMN IF NOT EXISTS last_line_at timestamptz,
+  ADD COLUMN IF NOT EXISTS connected_at timestamptz,
+  ADD COLUMN IF NOT EXISTS reconnects integer NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS last_error text,
+  ADD COLUMN IF NOT EXISTS lines_per_min double precision NOT NULL DEFAULT 0;
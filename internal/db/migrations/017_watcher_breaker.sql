@@ -0,0 +1,18 @@
+-- Watcher backoff and circuit breaker state
+
+ALTER TABLE watchers
+  ADD COLUMN IF NOT EXISTS breaker_state text,   -- closed|open|half_open
+  ADD COLUMN IF NOT EXISTS consecutive_failures integer NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS next_retry_at timestamptz;
+
+-- Collapse the duplicate UNREACHABLE_HOST concerns raised on every retry:
+-- keep the oldest open one per host.
+UPDATE concerns c
+SET resolved_at = now()
+WHERE c.type = 'UNREACHABLE_HOST'
+  AND c.resolved_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM concerns o
+    WHERE o.type = c.type AND o.host_id IS NOT DISTINCT FROM c.host_id
+      AND o.resolved_at IS NULL AND o.id < c.id
+  );
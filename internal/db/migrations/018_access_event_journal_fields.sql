@@ -0,0 +1,6 @@
+-- Structured journal fields kept with access events (journal JSON and
+-- journal-remote uploads; NULL for text sources)
+
+ALTER TABLE access_events
+  ADD COLUMN IF NOT EXISTS sshd_pid integer,
+  ADD COLUMN IF NOT EXISTS boot_id text;
@@ -0,0 +1,5 @@
+-- When reachable_from_jump was last probed (the reachability cache TTL runs
+-- from here)
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS reach_checked_at timestamptz;
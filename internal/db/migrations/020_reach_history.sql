@@ -0,0 +1,19 @@
+-- Every reachability probe, with a classified failure reason
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS reach_reason text;  -- reason of the last probe (NULL when reachable)
+
+CREATE TABLE IF NOT EXISTS reach_history (
+  id bigserial PRIMARY KEY,
+  host_id bigint NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+  checked_at timestamptz NOT NULL DEFAULT now(),
+  reachable boolean NOT NULL,
+  -- dns_failure|tcp_refused|timeout|network_unreachable|auth_denied|
+  -- host_key_mismatch|permission_denied|error (NULL when reachable)
+  reason text,
+  detail text,       -- ssh's own message
+  duration_ms bigint
+);
+
+CREATE INDEX IF NOT EXISTS reach_history_host_idx ON reach_history(host_id, checked_at);
+CREATE INDEX IF NOT EXISTS reach_history_checked_idx ON reach_history(checked_at);
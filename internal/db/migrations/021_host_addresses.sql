@@ -0,0 +1,16 @@
+-- Which host an address belonged to, over time. A row covers one run of
+-- sightings of ip as the same host; when reverse DNS names a different host
+-- (DHCP churn) a new row starts, so different machines are not merged.
+
+CREATE TABLE IF NOT EXISTS host_addresses (
+  id bigserial PRIMARY KEY,
+  ip inet NOT NULL,
+  host_id bigint REFERENCES hosts(id) ON DELETE CASCADE, -- NULL: no usable name
+  ptr_names text[] NOT NULL DEFAULT '{}',                -- every PTR name at last sighting
+  fcrdns boolean NOT NULL DEFAULT false,                 -- a PTR name resolved back to ip
+  first_seen timestamptz NOT NULL DEFAULT now(),
+  last_seen timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS host_addresses_ip_idx ON host_addresses(ip, last_seen);
+CREATE INDEX IF NOT EXISTS host_addresses_host_idx ON host_addresses(host_id, last_seen);
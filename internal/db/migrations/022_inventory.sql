@@ -0,0 +1,16 @@
+-- Inventory/CMDB metadata on hosts (keyspider inventory import) and when a
+-- host was last scanned, to report inventory hosts never scanned and scanned
+-- hosts missing from inventory.
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS environment text,
+  ADD COLUMN IF NOT EXISTS owner_team text,
+  ADD COLUMN IF NOT EXISTS ip_addresses text[] NOT NULL DEFAULT '{}', -- from inventory
+  ADD COLUMN IF NOT EXISTS in_inventory boolean NOT NULL DEFAULT false,
+  ADD COLUMN IF NOT EXISTS inventory_source text,                     -- --source of the last import naming the host
+  ADD COLUMN IF NOT EXISTS inventory_seen_at timestamptz,
+  ADD COLUMN IF NOT EXISTS last_scanned_at timestamptz;               -- spider logs phase or a host job step
+
+CREATE INDEX IF NOT EXISTS hosts_environment_idx ON hosts(environment);
+CREATE INDEX IF NOT EXISTS hosts_owner_team_idx ON hosts(owner_team);
+CREATE INDEX IF NOT EXISTS hosts_inventory_source_idx ON hosts(inventory_source) WHERE in_inventory;
@@ -0,0 +1,31 @@
+-- Who is behind a key: people, service accounts and teams, and which key
+-- fingerprints belong to them. Owners are linked by fingerprint so keys seen
+-- only in logs (no ssh_keys row) can be attributed too.
+
+CREATE TABLE IF NOT EXISTS identities (
+  id bigserial PRIMARY KEY,
+  kind text NOT NULL,                 -- person|service|team
+  name text NOT NULL,                 -- e.g. alice@corp, svc-backup, platform
+  display_name text,
+  email text,
+  team_id bigint REFERENCES identities(id) ON DELETE SET NULL,
+  source text NOT NULL DEFAULT 'manual', -- manual|comment|csv|ldif
+  external_id text,                   -- directory DN or id
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS identities_kind_name_uq ON identities(kind, name);
+
+CREATE TABLE IF NOT EXISTS key_owners (
+  fingerprint_sha256 text NOT NULL,
+  identity_id bigint NOT NULL REFERENCES identities(id) ON DELETE CASCADE,
+  label text,                         -- e.g. "laptop key"
+  source text NOT NULL DEFAULT 'manual',
+  confidence int NOT NULL DEFAULT 50, -- the highest wins when a key has several owners
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (fingerprint_sha256, identity_id)
+);
+
+CREATE INDEX IF NOT EXISTS key_owners_identity_idx ON key_owners(identity_id);
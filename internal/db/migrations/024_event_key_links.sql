@@ -0,0 +1,38 @@
+-- Link access events to the key and the authorized_keys entry (host + user
+-- file) that granted them. key_id was in the schema but never set.
+--
+-- An authorized_keys file holds many keys, so authorized_key instances are
+-- now one row per (host, file, key); other instance types stay one row per
+-- (host, path, type).
+
+ALTER TABLE access_events
+  ADD COLUMN IF NOT EXISTS key_instance_id bigint REFERENCES key_instances(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS access_events_key_idx ON access_events(key_id);
+CREATE INDEX IF NOT EXISTS access_events_key_instance_idx ON access_events(key_instance_id);
+
+DROP INDEX IF EXISTS key_instances_host_path_type_uq;
+CREATE UNIQUE INDEX IF NOT EXISTS key_instances_authorized_uq
+  ON key_instances(host_id, path, key_id) WHERE instance_type = 'authorized_key';
+CREATE UNIQUE INDEX IF NOT EXISTS key_instances_file_uq
+  ON key_instances(host_id, path, instance_type) WHERE instance_type <> 'authorized_key';
+
+-- The account an authorized_keys file belongs to, from its path.
+UPDATE key_instances
+SET username = CASE WHEN path LIKE '/root/%' THEN 'root' ELSE substring(path from '/home/([^/]+)/') END
+WHERE instance_type = 'authorized_key' AND username IS NULL;
+
+-- Backfill events ingested before this migration.
+UPDATE access_events e
+SET key_id = k.id
+FROM ssh_keys k
+WHERE e.key_id IS NULL AND e.fingerprint_sha256 = k.fingerprint_sha256;
+
+UPDATE access_events e
+SET key_instance_id = (
+  SELECT ki.id FROM key_instances ki
+  WHERE ki.key_id = e.key_id AND ki.host_id = e.dest_host_id AND ki.instance_type = 'authorized_key'
+    AND (ki.username IS NULL OR ki.username = e.dest_user)
+  ORDER BY (ki.username = e.dest_user) DESC NULLS LAST, ki.last_seen DESC
+  LIMIT 1)
+WHERE e.key_instance_id IS NULL AND e.key_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Key cleanup findings are concerns recomputed as a set (KEY_UNUSED,
+-- KEY_UNKNOWN, KEY_ORPHANED_PRIVATE). subject names what a finding is about,
+-- so a refresh keeps one open concern per subject and resolves the rest.
+
+ALTER TABLE concerns
+  ADD COLUMN IF NOT EXISTS subject text,            -- e.g. key_instance:42, host:7:SHA256:...
+  ADD COLUMN IF NOT EXISTS fingerprint_sha256 text; -- the key, also when there is no ssh_keys row
+
+CREATE UNIQUE INDEX IF NOT EXISTS concerns_open_subject_uq
+  ON concerns(type, subject) WHERE resolved_at IS NULL AND subject IS NOT NULL;
+CREATE INDEX IF NOT EXISTS concerns_type_created_idx ON concerns(type, created_at);
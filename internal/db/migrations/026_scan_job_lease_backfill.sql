@@ -0,0 +1,6 @@
+-- Jobs already running when 009 added leases have no lease_expires_at, so the
+-- reaper (lease_expires_at < now()) never requeues them. Expire their leases
+-- now; the next reap hands them back to the queue.
+
+UPDATE scan_jobs SET lease_expires_at = now()
+WHERE status = 'running' AND lease_expires_at IS NULL;
@@ -0,0 +1,5 @@
+-- Version 2 of the audit row hash also covers the error column. Rows chained
+-- before it keep version 1 so existing chains still verify.
+
+ALTER TABLE ssh_audit
+  ADD COLUMN IF NOT EXISTS hash_version int NOT NULL DEFAULT 1;
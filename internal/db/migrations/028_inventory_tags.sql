@@ -0,0 +1,9 @@
+-- The tags each inventory source put on a host ({"source": ["tag", ...]}),
+-- so an import replaces only its own tags and keeps those of other sources.
+-- Imports so far replaced tags wholesale: credit them to the last source.
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS inventory_tags jsonb NOT NULL DEFAULT '{}';
+
+UPDATE hosts SET inventory_tags = jsonb_build_object(inventory_source, to_jsonb(tags))
+WHERE inventory_source IS NOT NULL AND inventory_tags = '{}';
@@ -0,0 +1,5 @@
+-- When os_type was last read from the host (uname), so the spider and the
+-- watcher reuse it instead of asking on every scan and reconnect.
+
+ALTER TABLE hosts
+  ADD COLUMN IF NOT EXISTS os_checked_at timestamptz;
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// ExportConcerns renders the concerns matching f as json or csv (one row per
+// concern), e.g. the key findings for a cleanup. It also returns the content type.
+func ExportConcerns(ctx context.Context, st *store.Store, format string, f store.ConcernFilter) ([]byte, string, error) {
+	cs, err := st.ListConcerns(ctx, f)
+	if err != nil {
+		return nil, "", err
+	}
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(cs, "", "  ")
+		if err != nil {
+			return nil, "", err
+		}
+		return b, "application/json", nil
+	case "csv":
+		deref := func(p *string) string {
+			if p == nil {
+				return ""
+			}
+			return *p
+		}
+		buf := new(bytes.Buffer)
+		w := csv.NewWriter(buf)
+		_ = w.Write([]string{"id", "type", "severity", "host", "fingerprint_sha256", "owner", "details", "created_at", "resolved_at"})
+		for _, c := range cs {
+			resolved := ""
+			if c.ResolvedAt != nil {
+				resolved = c.ResolvedAt.Format(time.RFC3339)
+			}
+			_ = w.Write([]string{fmt.Sprintf("%d", c.ID), c.Type, c.Severity, deref(c.Hostname), deref(c.Fingerprint), deref(c.Owner),
+				deref(c.Details), c.CreatedAt.Format(time.RFC3339), resolved})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/csv", nil
+	default:
+		return nil, "", fmt.Errorf("unknown format %q (use json|csv)", format)
+	}
+}
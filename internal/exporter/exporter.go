@@ -39,9 +39,9 @@ func ExportGraphCSV(ctx context.Context, st *store.Store, limit int) ([]byte, st
 	}
 	buf := new(bytes.Buffer)
 	w := csv.NewWriter(buf)
-	_ = w.Write([]string{"src_label", "dest_host_id", "evidence_type", "confidence", "first_seen", "last_seen"})
+	_ = w.Write([]string{"src_label", "dest_host_id", "evidence_type", "confidence", "first_seen", "last_seen", "identities"})
 	for _, e := range edges {
-		_ = w.Write([]string{e.SrcLabel, fmt.Sprintf("%d", e.DestHostID), e.Evidence, fmt.Sprintf("%d", e.Confidence), e.FirstSeen.Format("2006-01-02T15:04:05Z07:00"), e.LastSeen.Format("2006-01-02T15:04:05Z07:00")})
+		_ = w.Write([]string{e.SrcLabel, fmt.Sprintf("%d", e.DestHostID), e.Evidence, fmt.Sprintf("%d", e.Confidence), e.FirstSeen.Format("2006-01-02T15:04:05Z07:00"), e.LastSeen.Format("2006-01-02T15:04:05Z07:00"), strings.Join(e.Identities, "; ")})
 	}
 	w.Flush()
 	if err := w.Error(); err != nil {
@@ -71,7 +71,17 @@ func ExportGraphGraphML(ctx context.Context, st *store.Store, limit int) ([]byte
 	for _, h := range hosts {
 		id := fmt.Sprintf("host:%d", h.ID)
 		nodes[id] = true
-		sb.WriteString(fmt.Sprintf(`<node id="%s"><data key="hostname">%s</data></node>\n`, xmlEscape(id), xmlEscape(h.Hostname)))
+		sb.WriteString(fmt.Sprintf(`<node id="%s"><data key="hostname">%s</data>`, xmlEscape(id), xmlEscape(h.Hostname)))
+		if h.Environment != nil {
+			sb.WriteString(fmt.Sprintf(`<data key="environment">%s</data>`, xmlEscape(*h.Environment)))
+		}
+		if h.OwnerTeam != nil {
+			sb.WriteString(fmt.Sprintf(`<data key="owner_team">%s</data>`, xmlEscape(*h.OwnerTeam)))
+		}
+		if len(h.Tags) > 0 {
+			sb.WriteString(fmt.Sprintf(`<data key="tags">%s</data>`, xmlEscape(strings.Join(h.Tags, ","))))
+		}
+		sb.WriteString(`</node>\n`)
 	}
 
 	for _, e := range edges {
@@ -90,7 +100,11 @@ func ExportGraphGraphML(ctx context.Context, st *store.Store, limit int) ([]byte
 			nodes[dst] = true
 			sb.WriteString(fmt.Sprintf(`<node id="%s"><data key="label">%s</data></node>\n`, xmlEscape(dst), xmlEscape(dst)))
 		}
-		sb.WriteString(fmt.Sprintf(`<edge source="%s" target="%s"><data key="evidence">%s</data><data key="confidence">%d</data></edge>\n`, xmlEscape(src), xmlEscape(dst), xmlEscape(e.Evidence), e.Confidence))
+		sb.WriteString(fmt.Sprintf(`<edge source="%s" target="%s"><data key="evidence">%s</data><data key="confidence">%d</data>`, xmlEscape(src), xmlEscape(dst), xmlEscape(e.Evidence), e.Confidence))
+		if len(e.Identities) > 0 {
+			sb.WriteString(fmt.Sprintf(`<data key="identities">%s</data>`, xmlEscape(strings.Join(e.Identities, "; "))))
+		}
+		sb.WriteString(`</edge>\n`)
 	}
 
 	sb.WriteString(`</graph>\n</graphml>\n`)
@@ -105,3 +119,21 @@ func xmlEscape(s string) string {
 	s = strings.ReplaceAll(s, "'", "&apos;")
 	return s
 }
+
+// ExportGraph renders the graph in format (json|csv|graphml). It also returns
+// the file extension for the format.
+func ExportGraph(ctx context.Context, st *store.Store, format string, limit int) ([]byte, string, error) {
+	var b []byte
+	var err error
+	switch format {
+	case "json":
+		b, _, err = ExportGraphJSON(ctx, st, limit)
+	case "csv":
+		b, _, err = ExportGraphCSV(ctx, st, limit)
+	case "graphml":
+		b, _, err = ExportGraphGraphML(ctx, st, limit)
+	default:
+		return nil, "", fmt.Errorf("unknown format %q (use json|csv|graphml)", format)
+	}
+	return b, format, err
+}
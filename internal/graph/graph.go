@@ -0,0 +1,159 @@
+// Package graph answers path questions over the access graph: what a host
+// or key can reach, who can reach a host, and the shortest path between two.
+//
+// Nodes are hostnames, source addresses that are not known hosts, and key
+// fingerprints; edges are store.GraphEdges ("src can log into dest"). The
+// graph is loaded into memory and searched breadth first, so paths are
+// shortest by hops, preferring confident edges among equally short ones.
+package graph
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Graph is a directed graph of access edges.
+type Graph struct {
+	out map[string][]store.GraphEdge
+	in  map[string][]store.GraphEdge
+}
+
+// Reached is a node found by Reach, with the path that reaches it in
+// edge order (first edge starts at the source of access).
+type Reached struct {
+	Node  string            `json:"node"`
+	Depth int               `json:"depth"`
+	Path  []store.GraphEdge `json:"path"`
+}
+
+// IsKey reports whether node names a key rather than a host.
+func IsKey(node string) bool { return strings.HasPrefix(node, "SHA256:") }
+
+// New builds a graph from edges.
+func New(edges []store.GraphEdge) *Graph {
+	g := &Graph{out: map[string][]store.GraphEdge{}, in: map[string][]store.GraphEdge{}}
+	for _, e := range edges {
+		if e.Src == e.Dest {
+			continue
+		}
+		g.out[e.Src] = append(g.out[e.Src], e)
+		g.in[e.Dest] = append(g.in[e.Dest], e)
+	}
+	for _, m := range []map[string][]store.GraphEdge{g.out, g.in} {
+		for _, es := range m {
+			sort.SliceStable(es, func(i, j int) bool {
+				if es[i].Confidence != es[j].Confidence {
+					return es[i].Confidence > es[j].Confidence
+				}
+				return es[i].LastSeen.After(es[j].LastSeen)
+			})
+		}
+	}
+	return g
+}
+
+// Load builds the graph of the edges matching f. When from is a key
+// fingerprint, the hosts it is authorized on are added as its edges.
+func Load(ctx context.Context, st *store.Store, f store.GraphEdgeFilter, from string) (*Graph, error) {
+	edges, err := st.ListGraphEdges(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if IsKey(from) {
+		ke, err := st.ListKeyAccessEdges(ctx, from, f.Since)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, ke...)
+	}
+	return New(edges), nil
+}
+
+// Has reports whether node has any edge.
+func (g *Graph) Has(node string) bool {
+	return len(g.out[node]) > 0 || len(g.in[node]) > 0
+}
+
+// Reach returns every node reachable from node within maxDepth hops (0 = no
+// limit), nearest first. With reverse it returns every node that can reach
+// node instead.
+func (g *Graph) Reach(node string, reverse bool, maxDepth int) []Reached {
+	via, depth := g.bfs(node, reverse, maxDepth, "")
+	out := make([]Reached, 0, len(via))
+	for n := range via {
+		out = append(out, Reached{Node: n, Depth: depth[n], Path: g.path(node, n, via, reverse)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Depth != out[j].Depth {
+			return out[i].Depth < out[j].Depth
+		}
+		return out[i].Node < out[j].Node
+	})
+	return out
+}
+
+// ShortestPath returns the shortest path from one node to another within
+// maxDepth hops (0 = no limit), or nil if there is none.
+func (g *Graph) ShortestPath(from, to string, maxDepth int) []store.GraphEdge {
+	if from == to {
+		return nil
+	}
+	via, _ := g.bfs(from, false, maxDepth, to)
+	if _, ok := via[to]; !ok {
+		return nil
+	}
+	return g.path(from, to, via, false)
+}
+
+// bfs searches from start, stopping early once stop is found. via holds, for
+// each node reached, the edge it was reached by.
+func (g *Graph) bfs(start string, reverse bool, maxDepth int, stop string) (map[string]store.GraphEdge, map[string]int) {
+	adj, next := g.out, func(e store.GraphEdge) string { return e.Dest }
+	if reverse {
+		adj, next = g.in, func(e store.GraphEdge) string { return e.Src }
+	}
+	via := map[string]store.GraphEdge{}
+	depth := map[string]int{start: 0}
+	frontier := []string{start}
+	for d := 1; len(frontier) > 0 && (maxDepth <= 0 || d <= maxDepth); d++ {
+		var nextFrontier []string
+		for _, n := range frontier {
+			for _, e := range adj[n] {
+				m := next(e)
+				if _, seen := depth[m]; seen {
+					continue
+				}
+				depth[m], via[m] = d, e
+				if m == stop {
+					return via, depth
+				}
+				nextFrontier = append(nextFrontier, m)
+			}
+		}
+		frontier = nextFrontier
+	}
+	return via, depth
+}
+
+// path walks via back from n to start and returns the edges in access order.
+func (g *Graph) path(start, n string, via map[string]store.GraphEdge, reverse bool) []store.GraphEdge {
+	var p []store.GraphEdge
+	for n != start {
+		e := via[n]
+		p = append(p, e)
+		if reverse {
+			n = e.Dest
+		} else {
+			n = e.Src
+		}
+	}
+	if !reverse {
+		for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+			p[i], p[j] = p[j], p[i]
+		}
+	}
+	return p
+}
@@ -0,0 +1,264 @@
+package identity
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/keys"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Entry is one identity from a directory export, with its keys.
+type Entry struct {
+	Identity store.Identity
+	Keys     []KeyRef
+}
+
+// KeyRef is a key an entry owns.
+type KeyRef struct {
+	Fingerprint string
+	Label       string
+}
+
+// Formats of directory exports.
+const (
+	FormatCSV  = "csv"
+	FormatLDIF = "ldif"
+)
+
+// FormatFor guesses the format from a file name.
+func FormatFor(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".ldif") {
+		return FormatLDIF
+	}
+	return FormatCSV
+}
+
+// LDIFOptions says which attributes of an LDIF entry hold what.
+type LDIFOptions struct {
+	Kind     string // kind of every entry
+	NameAttr string // default: uid, else mail
+	TeamAttr string // optional, e.g. ou or departmentNumber
+}
+
+// ParseCSV reads rows of kind,name,display_name,email,team,external_id and
+// one of fingerprint or public_key, with an optional label. The header names
+// the columns; an identity may span several rows, one per key.
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("identities csv: header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, errors.New("identities csv: a name column is required")
+	}
+
+	var out []Entry
+	index := map[string]int{}
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("identities csv: %w", err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		id := store.Identity{Kind: get("kind"), Name: get("name"), Source: "csv"}
+		if id.Kind == "" {
+			id.Kind = store.IdentityPerson
+		}
+		if !store.ValidIdentityKind(id.Kind) {
+			return nil, fmt.Errorf("identities csv line %d: kind must be person, service or team", line)
+		}
+		if id.Name == "" {
+			continue
+		}
+		id.DisplayName, id.Email, id.Team, id.ExternalID = opt(get("display_name")), opt(get("email")), opt(get("team")), opt(get("external_id"))
+
+		key := id.Kind + "\x00" + id.Name
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, Entry{Identity: id})
+		}
+		for _, k := range []string{get("fingerprint"), get("public_key")} {
+			if k == "" {
+				continue
+			}
+			fp, err := Fingerprint(k)
+			if err != nil {
+				return nil, fmt.Errorf("identities csv line %d: %w", line, err)
+			}
+			out[i].Keys = append(out[i].Keys, KeyRef{Fingerprint: fp, Label: get("label")})
+			break
+		}
+	}
+}
+
+// ParseLDIF reads an LDIF export (e.g. ldapsearch -LLL ... sshPublicKey).
+// Each entry with a name becomes an identity, owning its sshPublicKey
+// values; a key's comment is its label.
+func ParseLDIF(r io.Reader, opts LDIFOptions) ([]Entry, error) {
+	if opts.Kind == "" {
+		opts.Kind = store.IdentityPerson
+	}
+	var out []Entry
+	err := readLDIF(r, func(attrs map[string][]string) error {
+		first := func(name string) string {
+			if v := attrs[strings.ToLower(name)]; len(v) > 0 {
+				return v[0]
+			}
+			return ""
+		}
+		name := ""
+		if opts.NameAttr != "" {
+			name = first(opts.NameAttr)
+		} else if name = first("uid"); name == "" {
+			name = first("mail")
+		}
+		if name == "" {
+			return nil
+		}
+		e := Entry{Identity: store.Identity{Kind: opts.Kind, Name: name, Source: "ldif"}}
+		e.Identity.DisplayName = opt(first("displayName"))
+		if e.Identity.DisplayName == nil {
+			e.Identity.DisplayName = opt(first("cn"))
+		}
+		e.Identity.Email = opt(first("mail"))
+		e.Identity.ExternalID = opt(first("dn"))
+		if opts.TeamAttr != "" {
+			e.Identity.Team = opt(first(opts.TeamAttr))
+		}
+		for _, v := range attrs["sshpublickey"] {
+			k, ok := keys.ParseAuthorizedKeysLine(v)
+			if !ok {
+				return fmt.Errorf("ldif %s: bad sshPublicKey", name)
+			}
+			label := k.Comment
+			if label == "" {
+				label = "directory key"
+			}
+			e.Keys = append(e.Keys, KeyRef{Fingerprint: k.FP256, Label: label})
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// readLDIF calls fn per entry with attributes keyed by lowercased name.
+// It unfolds continuation lines and decodes "attr:: base64" values.
+func readLDIF(r io.Reader, fn func(map[string][]string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	attrs := map[string][]string{}
+	var cur string // the logical line being unfolded
+
+	flushLine := func() error {
+		if cur == "" {
+			return nil
+		}
+		line := cur
+		cur = ""
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("ldif: bad line %q", line)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasPrefix(value, ":") {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				return fmt.Errorf("ldif: %s: %w", name, err)
+			}
+			value = string(b)
+		} else if strings.HasPrefix(value, "<") {
+			return fmt.Errorf("ldif: %s: URL values are not supported", name)
+		}
+		if name != "version" {
+			attrs[name] = append(attrs[name], strings.TrimSpace(value))
+		}
+		return nil
+	}
+	flushEntry := func() error {
+		if err := flushLine(); err != nil {
+			return err
+		}
+		if len(attrs) == 0 {
+			return nil
+		}
+		err := fn(attrs)
+		attrs = map[string][]string{}
+		return err
+	}
+
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, " "):
+			cur += line[1:]
+		case line == "":
+			if err := flushEntry(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			if err := flushLine(); err != nil {
+				return err
+			}
+			cur = line
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("ldif: %w", err)
+	}
+	return flushEntry()
+}
+
+// Import stores entries and links their keys. It returns the number of
+// identities and key links written.
+func Import(ctx context.Context, st *store.Store, entries []Entry) (identities, links int, err error) {
+	for _, e := range entries {
+		id := e.Identity
+		iid, err := st.UpsertIdentity(ctx, &id)
+		if err != nil {
+			return identities, links, err
+		}
+		identities++
+		for _, k := range e.Keys {
+			if err := st.LinkKey(ctx, k.Fingerprint, iid, opt(k.Label), id.Source, ConfidenceDirectory); err != nil {
+				return identities, links, err
+			}
+			links++
+		}
+	}
+	return identities, links, nil
+}
+
+func opt(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
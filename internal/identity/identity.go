@@ -0,0 +1,128 @@
+// Package identity attributes SSH keys to people, service accounts and
+// teams: by key comment patterns, directory exports (CSV, LDIF) or by hand.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/keys"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Confidence of a key link by how it was made; when a key has several
+// owners the most confident is the one shown.
+const (
+	ConfidenceManual    = 100
+	ConfidenceDirectory = 80
+	ConfidenceComment   = 30
+)
+
+// Fingerprint accepts a "SHA256:..." fingerprint or an authorized_keys style
+// public key and returns the SHA256 fingerprint.
+func Fingerprint(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "SHA256:") && !strings.ContainsAny(s, " \t") {
+		return s, nil
+	}
+	fp, err := keys.FingerprintFromAuthorizedKey(s)
+	if err != nil {
+		return "", fmt.Errorf("not a SHA256 fingerprint or public key: %w", err)
+	}
+	return fp, nil
+}
+
+// Deriver guesses owners from key comments with identities.comment_patterns.
+type Deriver struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re    *regexp.Regexp
+	skip  *regexp.Regexp // nil: no exclusions
+	kind  string
+	name  string
+	label string
+}
+
+// NewDeriver compiles the configured patterns (config.Load validated them).
+func NewDeriver(cfg *config.Config) *Deriver {
+	d := &Deriver{}
+	for _, p := range cfg.Identities.CommentPatterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			continue
+		}
+		pt := pattern{re: re, kind: p.Kind, name: p.Name, label: p.Label}
+		if p.Exclude != "" {
+			if pt.skip, err = regexp.Compile(p.Exclude); err != nil {
+				continue
+			}
+		}
+		d.patterns = append(d.patterns, pt)
+	}
+	return d
+}
+
+// Match applies the first pattern matching comment that its exclude does not.
+func (d *Deriver) Match(comment string) (kind, name, label string, ok bool) {
+	comment = strings.TrimSpace(comment)
+	for _, p := range d.patterns {
+		if p.skip != nil && p.skip.MatchString(comment) {
+			continue
+		}
+		m := p.re.FindStringSubmatchIndex(comment)
+		if m == nil {
+			continue
+		}
+		name = string(p.re.ExpandString(nil, p.name, comment, m))
+		label = string(p.re.ExpandString(nil, p.label, comment, m))
+		if name == "" {
+			continue
+		}
+		return p.kind, name, label, true
+	}
+	return "", "", "", false
+}
+
+// Derive links fingerprint to the owner its comment names, if any pattern
+// matches. It reports whether a link was made.
+func (d *Deriver) Derive(ctx context.Context, st *store.Store, fingerprint, comment string) (bool, error) {
+	kind, name, label, ok := d.Match(comment)
+	if !ok {
+		return false, nil
+	}
+	id, err := st.UpsertIdentity(ctx, &store.Identity{Kind: kind, Name: name, Source: "comment"})
+	if err != nil {
+		return false, err
+	}
+	var lp *string
+	if label != "" {
+		lp = &label
+	}
+	if err := st.LinkKey(ctx, fingerprint, id, lp, "comment", ConfidenceComment); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeriveAll runs Derive over every key with a comment.
+func (d *Deriver) DeriveAll(ctx context.Context, st *store.Store) (matched, total int, err error) {
+	ks, err := st.ListCommentedKeys(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, k := range ks {
+		ok, err := d.Derive(ctx, st, k.Fingerprint, k.Comment)
+		if err != nil {
+			return matched, len(ks), err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched, len(ks), nil
+}
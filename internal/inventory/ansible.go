@@ -0,0 +1,441 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleInv is an Ansible inventory: groups with hosts, vars and children.
+// Group names become tags; environment, owner_team etc. come from host or
+// group vars, deeper groups overriding their parents and host vars
+// overriding groups, as in Ansible.
+type ansibleInv struct {
+	groups   map[string]*ansibleGroup
+	hostVars map[string]map[string]any
+	hosts    []string
+}
+
+type ansibleGroup struct {
+	hosts    []string
+	vars     map[string]any
+	children []string
+}
+
+func newAnsibleInv() *ansibleInv {
+	return &ansibleInv{groups: map[string]*ansibleGroup{}, hostVars: map[string]map[string]any{}}
+}
+
+func (inv *ansibleInv) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{vars: map[string]any{}}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+func (inv *ansibleInv) addHost(group, host string, vars map[string]any) {
+	g := inv.group(group)
+	if !slices.Contains(g.hosts, host) {
+		g.hosts = append(g.hosts, host)
+	}
+	hv, ok := inv.hostVars[host]
+	if !ok {
+		hv = map[string]any{}
+		inv.hostVars[host] = hv
+		inv.hosts = append(inv.hosts, host)
+	}
+	for k, v := range vars {
+		hv[k] = v
+	}
+}
+
+func (inv *ansibleInv) addChild(group, child string) {
+	g := inv.group(group)
+	inv.group(child)
+	if !slices.Contains(g.children, child) {
+		g.children = append(g.children, child)
+	}
+}
+
+// parseAnsible reads an INI or YAML inventory, or ansible-inventory --list
+// JSON.
+func parseAnsible(r io.Reader) ([]store.InventoryHost, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ansible inventory: %w", err)
+	}
+	trimmed := bytes.TrimSpace(b)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return parseAnsibleJSON(trimmed)
+	case looksLikeINI(b):
+		inv, err := parseAnsibleINI(b)
+		if err != nil {
+			return nil, err
+		}
+		return inv.resolve(), nil
+	}
+	inv, err := parseAnsibleYAML(b)
+	if err != nil {
+		return nil, err
+	}
+	return inv.resolve(), nil
+}
+
+// looksLikeINI reports whether the first significant line is a [section]
+// or a host line rather than YAML ("key:").
+func looksLikeINI(b []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line == "---" {
+			continue
+		}
+		return line[0] == '[' || !strings.HasSuffix(strings.Fields(line)[0], ":")
+	}
+	return false
+}
+
+func parseAnsibleINI(b []byte) (*ansibleInv, error) {
+	inv := newAnsibleInv()
+	section, kind := "ungrouped", "hosts"
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind = line[1:len(line)-1], "hosts"
+			if name, k, ok := strings.Cut(section, ":"); ok {
+				section, kind = name, k
+			}
+			inv.group(section)
+			continue
+		}
+		switch kind {
+		case "hosts":
+			fields, err := splitINI(line)
+			if err != nil {
+				return nil, fmt.Errorf("ansible inventory line %d: %w", n, err)
+			}
+			vars := map[string]any{}
+			for _, kv := range fields[1:] {
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					return nil, fmt.Errorf("ansible inventory line %d: %q is not key=value", n, kv)
+				}
+				vars[k] = v
+			}
+			hosts, err := expandHostRange(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("ansible inventory line %d: %w", n, err)
+			}
+			for _, h := range hosts {
+				inv.addHost(section, h, vars)
+			}
+		case "vars":
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("ansible inventory line %d: %q is not key=value", n, line)
+			}
+			inv.group(section).vars[strings.TrimSpace(k)] = unquote(strings.TrimSpace(v))
+		case "children":
+			inv.addChild(section, strings.Fields(line)[0])
+		default:
+			return nil, fmt.Errorf("ansible inventory line %d: unknown section type %q", n, kind)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ansible inventory: %w", err)
+	}
+	return inv, nil
+}
+
+// splitINI splits a host line on spaces, keeping quoted values together.
+func splitINI(line string) ([]string, error) {
+	var out []string
+	var cur strings.Builder
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && cur.Len() == 0:
+			return out, nil // trailing comment
+		case c == ' ' || c == '\t':
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+var hostRange = regexp.MustCompile(`\[([0-9]+|[a-z]):([0-9]+|[a-z])(?::([0-9]+))?\]`)
+
+// expandHostRange expands Ansible host patterns like web[01:20].example.com
+// or db-[a:c] (with an optional :stride).
+func expandHostRange(pattern string) ([]string, error) {
+	m := hostRange.FindStringSubmatchIndex(pattern)
+	if m == nil {
+		return []string{pattern}, nil
+	}
+	prefix, suffix := pattern[:m[0]], pattern[m[1]:]
+	lo, hi := pattern[m[2]:m[3]], pattern[m[4]:m[5]]
+	stride := 1
+	if m[6] >= 0 {
+		stride, _ = strconv.Atoi(pattern[m[6]:m[7]])
+		if stride <= 0 {
+			return nil, fmt.Errorf("host range %s: bad stride", pattern)
+		}
+	}
+
+	var items []string
+	loN, errLo := strconv.Atoi(lo)
+	hiN, errHi := strconv.Atoi(hi)
+	switch {
+	case errLo == nil && errHi == nil:
+		width := 0
+		if len(lo) > 1 && lo[0] == '0' {
+			width = len(lo)
+		}
+		for i := loN; i <= hiN; i += stride {
+			items = append(items, fmt.Sprintf("%0*d", width, i))
+		}
+	case errLo != nil && errHi != nil:
+		for c := int(lo[0]); c <= int(hi[0]); c += stride {
+			items = append(items, string(rune(c)))
+		}
+	default:
+		return nil, fmt.Errorf("host range %s: mixed numeric and alphabetic bounds", pattern)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("host range %s is empty", pattern)
+	}
+
+	rest, err := expandHostRange(suffix)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, it := range items {
+		for _, r := range rest {
+			out = append(out, prefix+it+r)
+		}
+	}
+	return out, nil
+}
+
+// parseAnsibleYAML reads the YAML inventory layout:
+// group: {hosts: {name: vars}, vars: {...}, children: {group: {...}}}.
+func parseAnsibleYAML(b []byte) (*ansibleInv, error) {
+	var top map[string]any
+	if err := yaml.Unmarshal(b, &top); err != nil {
+		return nil, fmt.Errorf("ansible inventory yaml: %w", err)
+	}
+	inv := newAnsibleInv()
+	for name, spec := range top {
+		if err := inv.yamlGroup(name, spec); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+func (inv *ansibleInv) yamlGroup(name string, spec any) error {
+	g := inv.group(name)
+	m, _ := spec.(map[string]any)
+	if hosts, ok := m["hosts"].(map[string]any); ok {
+		for pattern, hv := range hosts {
+			vars, _ := hv.(map[string]any)
+			expanded, err := expandHostRange(pattern)
+			if err != nil {
+				return fmt.Errorf("ansible inventory group %s: %w", name, err)
+			}
+			for _, h := range expanded {
+				inv.addHost(name, h, vars)
+			}
+		}
+	}
+	if vars, ok := m["vars"].(map[string]any); ok {
+		for k, v := range vars {
+			g.vars[k] = v
+		}
+	}
+	if children, ok := m["children"].(map[string]any); ok {
+		for child, cs := range children {
+			inv.addChild(name, child)
+			if err := inv.yamlGroup(child, cs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseAnsibleJSON reads ansible-inventory --list output.
+func parseAnsibleJSON(b []byte) ([]store.InventoryHost, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(b, &top); err != nil {
+		return nil, fmt.Errorf("ansible inventory json: %w", err)
+	}
+	inv := newAnsibleInv()
+	var meta struct {
+		HostVars map[string]map[string]any `json:"hostvars"`
+	}
+	if raw, ok := top["_meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return nil, fmt.Errorf("ansible inventory json: _meta: %w", err)
+		}
+	}
+	for name, raw := range top {
+		if name == "_meta" {
+			continue
+		}
+		var g struct {
+			Hosts    []string       `json:"hosts"`
+			Vars     map[string]any `json:"vars"`
+			Children []string       `json:"children"`
+		}
+		if err := json.Unmarshal(raw, &g); err != nil {
+			return nil, fmt.Errorf("ansible inventory json: group %s: %w", name, err)
+		}
+		for _, h := range g.Hosts {
+			inv.addHost(name, h, meta.HostVars[h])
+		}
+		for k, v := range g.Vars {
+			inv.group(name).vars[k] = v
+		}
+		for _, c := range g.Children {
+			inv.addChild(name, c)
+		}
+	}
+	for h, vars := range meta.HostVars {
+		if _, ok := inv.hostVars[h]; !ok {
+			inv.addHost("ungrouped", h, vars)
+		}
+	}
+	return inv.resolve(), nil
+}
+
+// resolve flattens groups into hosts.
+func (inv *ansibleInv) resolve() []store.InventoryHost {
+	parents := map[string][]string{}
+	for name, g := range inv.groups {
+		for _, c := range g.children {
+			parents[c] = append(parents[c], name)
+		}
+	}
+	depth := map[string]int{}
+	var depthOf func(name string, seen map[string]bool) int
+	depthOf = func(name string, seen map[string]bool) int {
+		if name == "all" {
+			return 0
+		}
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if seen[name] {
+			return 1 // a cycle; Ansible rejects these
+		}
+		seen[name] = true
+		d := 1
+		for _, p := range parents[name] {
+			d = max(d, depthOf(p, seen)+1)
+		}
+		depth[name] = d
+		return d
+	}
+
+	memberOf := map[string][]string{}
+	for name, g := range inv.groups {
+		for _, h := range g.hosts {
+			memberOf[h] = append(memberOf[h], name)
+		}
+	}
+
+	// YAML and JSON groups come from maps, so their order is random.
+	slices.Sort(inv.hosts)
+	out := make([]store.InventoryHost, 0, len(inv.hosts))
+	for _, h := range inv.hosts {
+		// The host's groups and all their ancestors, shallowest first.
+		groups := []string{}
+		queue := append([]string{"all"}, memberOf[h]...)
+		for len(queue) > 0 {
+			g := queue[0]
+			queue = queue[1:]
+			if slices.Contains(groups, g) {
+				continue
+			}
+			groups = append(groups, g)
+			queue = append(queue, parents[g]...)
+		}
+		slices.SortFunc(groups, func(a, b string) int {
+			if da, db := depthOf(a, map[string]bool{}), depthOf(b, map[string]bool{}); da != db {
+				return da - db
+			}
+			return strings.Compare(a, b)
+		})
+
+		vars := map[string]any{}
+		for _, g := range groups {
+			if gr, ok := inv.groups[g]; ok {
+				for k, v := range gr.vars {
+					vars[k] = v
+				}
+			}
+		}
+		for k, v := range inv.hostVars[h] {
+			vars[k] = v
+		}
+
+		rec := record{}
+		for k, v := range vars {
+			rec[strings.ToLower(k)] = values(v)
+		}
+		for _, k := range []string{"hostname", "host", "name", "groups"} {
+			delete(rec, k)
+		}
+		rec["inventory_hostname"] = []string{h}
+		for _, g := range groups {
+			if g != "all" && g != "ungrouped" {
+				rec["groups"] = append(rec["groups"], g)
+			}
+		}
+		out = append(out, rec.toHost())
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// parseCSV reads a CSV file whose header names the columns (see
+// fieldAliases); tags and ip_addresses hold lists separated by ; , | or
+// spaces. Unknown columns are ignored.
+func parseCSV(r io.Reader) ([]store.InventoryHost, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("inventory csv: header: %w", err)
+	}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	var out []store.InventoryHost
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("inventory csv: %w", err)
+		}
+		rec := record{}
+		for i, v := range row {
+			if i < len(header) {
+				rec[header[i]] = append(rec[header[i]], v)
+			}
+		}
+		if rec.first("hostname") == "" && rec.first("fqdn") == "" {
+			continue
+		}
+		out = append(out, rec.toHost())
+	}
+}
@@ -0,0 +1,186 @@
+// Package inventory reads host inventories (CSV, JSON, Ansible) for
+// keyspider inventory import.
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Formats Parse accepts.
+const (
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatAnsible = "ansible" // INI or YAML inventory, or ansible-inventory --list output
+)
+
+// FormatFor guesses the format from a file name: .csv and .json by
+// extension, anything else (.ini, .yml, hosts) as an Ansible inventory.
+func FormatFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	}
+	return FormatAnsible
+}
+
+// Parse reads an inventory. Hosts listed more than once are merged; every
+// host gets "env:<environment>" and "team:<owner_team>" tags so scope rules
+// can select them.
+func Parse(r io.Reader, format string) ([]store.InventoryHost, error) {
+	var hosts []store.InventoryHost
+	var err error
+	switch format {
+	case FormatCSV:
+		hosts, err = parseCSV(r)
+	case FormatJSON:
+		hosts, err = parseJSON(r)
+	case FormatAnsible:
+		hosts, err = parseAnsible(r)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q (use csv|json|ansible)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normalize(hosts)
+}
+
+// record is one host's fields by (lowercased) name, before normalizing.
+type record map[string][]string
+
+// Field names accepted for each attribute, in every format.
+var fieldAliases = map[string][]string{
+	"hostname":    {"hostname", "host", "name", "inventory_hostname"},
+	"fqdn":        {"fqdn"},
+	"os_type":     {"os_type", "os", "keyspider_os_type"},
+	"environment": {"environment", "env"},
+	"owner_team":  {"owner_team", "team", "owner"},
+	"tags":        {"tags", "groups", "keyspider_tags"},
+	"ips":         {"ip_addresses", "ips", "ip", "ip_address", "ansible_host"},
+}
+
+func (rec record) first(field string) string {
+	for _, k := range fieldAliases[field] {
+		for _, v := range rec[k] {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func (rec record) all(field string) []string {
+	var out []string
+	for _, k := range fieldAliases[field] {
+		for _, v := range rec[k] {
+			out = append(out, splitList(v)...)
+		}
+	}
+	return out
+}
+
+// splitList splits "a, b;c|d e" into its items.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || r == ' ' || r == '\t'
+	})
+}
+
+// toHost maps a record to a host. A non-IP ansible_host is the host's FQDN.
+func (rec record) toHost() store.InventoryHost {
+	h := store.InventoryHost{
+		Hostname:    rec.first("hostname"),
+		FQDN:        rec.first("fqdn"),
+		OSType:      strings.ToLower(rec.first("os_type")),
+		Environment: rec.first("environment"),
+		OwnerTeam:   rec.first("owner_team"),
+		Tags:        rec.all("tags"),
+	}
+	for _, v := range rec.all("ips") {
+		if net.ParseIP(v) != nil {
+			h.IPs = append(h.IPs, v)
+		} else if h.FQDN == "" && strings.Contains(v, ".") {
+			h.FQDN = v
+		}
+	}
+	return h
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+}
+
+// normalize lowercases names, keys each host by its FQDN when it has one,
+// checks addresses, derives env:/team: tags and merges repeated hosts.
+func normalize(in []store.InventoryHost) ([]store.InventoryHost, error) {
+	byName := map[string]int{}
+	var out []store.InventoryHost
+	for _, h := range in {
+		h.Hostname, h.FQDN = normalizeName(h.Hostname), normalizeName(h.FQDN)
+		if h.FQDN == "" && strings.Contains(h.Hostname, ".") && net.ParseIP(h.Hostname) == nil {
+			h.FQDN = h.Hostname
+		}
+		if h.FQDN != "" {
+			h.Hostname = h.FQDN
+		}
+		if h.Hostname == "" {
+			return nil, fmt.Errorf("inventory: host without a hostname")
+		}
+		for i, ip := range h.IPs {
+			addr := net.ParseIP(strings.TrimSpace(ip))
+			if addr == nil {
+				return nil, fmt.Errorf("inventory: host %s: bad ip %q", h.Hostname, ip)
+			}
+			h.IPs[i] = addr.String()
+		}
+		if h.Environment != "" {
+			h.Tags = append(h.Tags, "env:"+h.Environment)
+		}
+		if h.OwnerTeam != "" {
+			h.Tags = append(h.Tags, "team:"+h.OwnerTeam)
+		}
+
+		i, seen := byName[h.Hostname]
+		if !seen {
+			byName[h.Hostname] = len(out)
+			out = append(out, h)
+			continue
+		}
+		prev := &out[i]
+		prev.Tags = append(prev.Tags, h.Tags...)
+		prev.IPs = append(prev.IPs, h.IPs...)
+		for _, f := range []struct{ dst, src *string }{
+			{&prev.OSType, &h.OSType}, {&prev.Environment, &h.Environment}, {&prev.OwnerTeam, &h.OwnerTeam},
+		} {
+			if *f.dst == "" {
+				*f.dst = *f.src
+			}
+		}
+	}
+	for i := range out {
+		out[i].Tags = dedupe(out[i].Tags)
+		out[i].IPs = dedupe(out[i].IPs)
+	}
+	return out, nil
+}
+
+func dedupe(in []string) []string {
+	out := []string{}
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" && !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
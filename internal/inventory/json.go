@@ -0,0 +1,72 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// parseJSON reads an array of host objects, or {"hosts": [...]}, with the
+// fields of fieldAliases; list fields may be arrays or separated strings.
+// ansible-inventory --list output (an object with "_meta") is read as an
+// Ansible inventory.
+func parseJSON(r io.Reader) ([]store.InventoryHost, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("inventory json: %w", err)
+	}
+	b = bytes.TrimSpace(b)
+
+	var objs []map[string]any
+	if bytes.HasPrefix(b, []byte("{")) {
+		var top map[string]json.RawMessage
+		if err := json.Unmarshal(b, &top); err != nil {
+			return nil, fmt.Errorf("inventory json: %w", err)
+		}
+		if _, ok := top["_meta"]; ok {
+			return parseAnsibleJSON(b)
+		}
+		raw, ok := top["hosts"]
+		if !ok {
+			return nil, fmt.Errorf(`inventory json: want an array of hosts, {"hosts": [...]} or ansible-inventory --list output`)
+		}
+		b = raw
+	}
+	if err := json.Unmarshal(b, &objs); err != nil {
+		return nil, fmt.Errorf("inventory json: %w", err)
+	}
+
+	out := make([]store.InventoryHost, 0, len(objs))
+	for _, o := range objs {
+		rec := record{}
+		for k, v := range o {
+			rec[strings.ToLower(k)] = values(v)
+		}
+		out = append(out, rec.toHost())
+	}
+	return out, nil
+}
+
+// values flattens a JSON or YAML value to strings: a list gives its items,
+// a scalar itself.
+func values(v any) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, x := range v {
+			out = append(out, values(x)...)
+		}
+		return out
+	case map[string]any:
+		return nil
+	}
+	return []string{fmt.Sprint(v)}
+}
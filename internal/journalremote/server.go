@@ -0,0 +1,136 @@
+package journalremote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/parsers"
+	"github.com/jsherman999/openclaw_keyspider/internal/pushauth"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
+	"github.com/jsherman999/openclaw_keyspider/internal/watcher"
+	"github.com/jsherman999/openclaw_keyspider/internal/watchhub"
+)
+
+// Server speaks the receiving side of systemd-journal-remote's HTTP protocol:
+// systemd-journal-upload POSTs the journal export format to /upload and keeps
+// its own position (--save-state), so hosts push without an SSH pull. sshd
+// entries are attributed by sender (or _HOSTNAME, with host_from: hostname)
+// and handled like a watcher's.
+type Server struct {
+	cfg   *config.Config
+	w     *watcher.Watcher
+	allow pushauth.AllowList
+	dns   *resolver.Resolver
+}
+
+const contentType = "application/vnd.fdo.journal"
+
+func New(cfg *config.Config, dbc *db.DB, hub *watchhub.Hub, rp *reach.Prober) *Server {
+	return &Server{
+		cfg:   cfg,
+		w:     watcher.New(cfg, dbc, hub, rp),
+		allow: pushauth.ParseAllowList(cfg.JournalRemote.AllowCIDRs),
+		dns:   resolver.New(cfg),
+	}
+}
+
+func (s *Server) Router() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/upload", s.upload)
+	return r
+}
+
+// Run serves until ctx ends.
+func (s *Server) Run(ctx context.Context) {
+	jr := s.cfg.JournalRemote
+	if !jr.Enabled {
+		return
+	}
+	srv := &http.Server{Addr: jr.Listen, Handler: s.Router(), ReadHeaderTimeout: 10 * time.Second}
+	if jr.TLSCertFile != "" {
+		tc, err := pushauth.TLSConfig(jr.TLSCertFile, jr.TLSKeyFile, jr.TLSClientCAFile)
+		if err != nil {
+			log.Printf("journal-remote: %v", err)
+			return
+		}
+		srv.TLSConfig = tc
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shCtx)
+	}()
+
+	log.Printf("journal-remote: listening on %s", jr.Listen)
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("journal-remote: %v", err)
+	}
+	<-done
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	if !s.allow.AllowsAddr(r.RemoteAddr) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != contentType {
+		http.Error(w, "Content-Type must be "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	ctx := r.Context()
+	jr := s.cfg.JournalRemote
+	r.Body = http.MaxBytesReader(w, r.Body, jr.MaxUploadBytes)
+	sender, _, _ := net.SplitHostPort(r.RemoteAddr)
+	ip := net.ParseIP(sender)
+	var entries, ingested int
+	err := parsers.ReadJournalExport(r.Body, jr.MaxFieldBytes, func(e parsers.JournalEntry) error {
+		entries++
+		if !e.IsSSHD() {
+			return nil
+		}
+		host := pushauth.Attribute(ctx, s.dns, jr.HostFrom, e["_HOSTNAME"], ip)
+		if host == "" {
+			return nil
+		}
+		hid, err := s.w.PushHostID(ctx, host)
+		if err != nil {
+			return err
+		}
+		s.w.IngestJournal(ctx, hid, host, e)
+		ingested++
+		return nil
+	})
+	if err != nil {
+		log.Printf("journal-remote: upload from %s after %d entries: %v", r.RemoteAddr, entries, err)
+		code := http.StatusBadRequest
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintf(w, "OK.\n")
+}
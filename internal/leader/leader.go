@@ -0,0 +1,141 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Role of the keyspiderd duties that must run in exactly one process: the
+// watchers, the scheduler and the scan job reaper. The API and scan workers
+// run in every replica.
+const RoleSingleton = "singleton"
+
+// Elector runs duties in at most one keyspiderd at a time, using a session
+// advisory lock on a dedicated Postgres connection. The lock is released when
+// the holder stops or its connection dies, so a standby takes over within
+// one check interval.
+type Elector struct {
+	dbc      *db.DB
+	st       *store.Store
+	role     string
+	id       string
+	interval time.Duration
+}
+
+func New(cfg *config.Config, dbc *db.DB, role string) *Elector {
+	iv := time.Duration(cfg.Leader.CheckIntervalSeconds) * time.Second
+	if iv <= 0 {
+		iv = 10 * time.Second
+	}
+	return &Elector{dbc: dbc, st: store.New(dbc), role: role, id: InstanceID(), interval: iv}
+}
+
+// InstanceID identifies this process in leader status (host:pid).
+func InstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// ID returns this process's instance id.
+func (e *Elector) ID() string { return e.id }
+
+// Run campaigns until ctx ends. Each time this process becomes leader, lead
+// is called with a context that is cancelled when leadership is lost; lead
+// must block until then and return once its duties have stopped.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	log.Printf("leader(%s): campaigning as %s", e.role, e.id)
+	for {
+		conn, err := e.tryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("leader(%s): %v", e.role, err)
+		}
+		if conn != nil {
+			e.hold(ctx, conn, lead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+// tryAcquire takes the role's advisory lock on a connection removed from the
+// pool. It returns nil if another process holds the lock.
+func (e *Elector) tryAcquire(ctx context.Context) (*pgx.Conn, error) {
+	pc, err := e.dbc.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	// A session lock lives as long as the connection, so it must never be
+	// returned to the pool.
+	conn := pc.Hijack()
+
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext('keyspider_leader:' || $1))`, e.role).Scan(&ok); err != nil {
+		_ = conn.Close(context.Background())
+		return nil, fmt.Errorf("try lock: %w", err)
+	}
+	if !ok {
+		_ = conn.Close(context.Background())
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// hold runs lead while the lock's connection stays healthy.
+func (e *Elector) hold(ctx context.Context, conn *pgx.Conn, lead func(ctx context.Context)) {
+	defer conn.Close(context.Background())
+
+	if err := e.st.SetLeader(ctx, e.role, e.id); err != nil {
+		log.Printf("leader(%s): record status: %v", e.role, err)
+	}
+	log.Printf("leader(%s): %s is now leader", e.role, e.id)
+
+	leadCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			<-done
+			_ = e.st.ClearLeader(context.WithoutCancel(ctx), e.role, e.id)
+			return
+		case <-done:
+			stop()
+			return
+		case <-t.C:
+			pingCtx, cancel := context.WithTimeout(ctx, e.interval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				// The lock died with the connection; someone else may already lead.
+				log.Printf("leader(%s): lost leadership: %v", e.role, err)
+				stop()
+				<-done
+				return
+			}
+			if err := e.st.HeartbeatLeader(ctx, e.role, e.id); err != nil && ctx.Err() == nil {
+				log.Printf("leader(%s): heartbeat: %v", e.role, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package osdetect names a host's OS family (remotecmd.OSes, or whatever
+// uname says) so the right command templates are rendered for it.
+package osdetect
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
+	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// recheck is how long an os_type read from a host is trusted before uname
+// is run again (reinstalls are rare).
+const recheck = 7 * 24 * time.Hour
+
+// Detector runs uname over ssh and caches the answer in hosts.os_type, so
+// the spider and the watcher ask a host at most once per recheck.
+type Detector struct {
+	st   *store.Store
+	ssh  *sshclient.Client
+	cmds *remotecmd.Catalog
+}
+
+func New(st *store.Store, ssh *sshclient.Client, cmds *remotecmd.Catalog) *Detector {
+	return &Detector{st: st, ssh: ssh, cmds: cmds}
+}
+
+// OSType returns host's OS family. When uname fails it falls back to the
+// last known one, or "linux".
+func (d *Detector) OSType(ctx context.Context, host string) string {
+	known, checkedAt, err := d.st.HostOSType(ctx, host)
+	if err != nil {
+		log.Printf("osdetect(%s): %v", host, err)
+	}
+	if !checkedAt.IsZero() && time.Since(checkedAt) < recheck {
+		return known
+	}
+	if known == "" {
+		known = "linux"
+	}
+
+	cmd, err := d.cmds.Render("posix", remotecmd.Uname, nil)
+	if err != nil {
+		return known
+	}
+	out, err := d.ssh.Run(ctx, host, cmd)
+	if err != nil {
+		return known
+	}
+	osType := FromUname(out)
+	if err := d.st.SetHostOSType(ctx, host, osType); err != nil && ctx.Err() == nil {
+		log.Printf("osdetect(%s): %v", host, err)
+	}
+	return osType
+}
+
+// FromUname maps `uname` output to an OS family.
+func FromUname(out string) string {
+	switch o := strings.ToLower(strings.TrimSpace(out)); o {
+	case "sunos":
+		return "solaris"
+	case "":
+		return "linux"
+	default:
+		return o
+	}
+}
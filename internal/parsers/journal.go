@@ -0,0 +1,184 @@
+package parsers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalEntry is one systemd journal record, keyed by field name
+// (MESSAGE, _PID, _HOSTNAME, __REALTIME_TIMESTAMP, __CURSOR, ...).
+type JournalEntry map[string]string
+
+// ParseJournalJSON parses one line of journalctl --output=json. Field values
+// are strings, or arrays when a field repeats (the first is kept) or holds
+// non-UTF-8 data (byte arrays).
+func ParseJournalJSON(line string) (JournalEntry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil, fmt.Errorf("journal json: %w", err)
+	}
+	e := make(JournalEntry, len(raw))
+	for k, v := range raw {
+		if s, ok := journalJSONValue(v); ok {
+			e[k] = s
+		}
+	}
+	return e, nil
+}
+
+func journalJSONValue(v json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s, true
+	}
+	var b []byte
+	var nums []int
+	if err := json.Unmarshal(v, &nums); err == nil {
+		for _, n := range nums {
+			b = append(b, byte(n))
+		}
+		return string(b), true
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal(v, &arr); err == nil && len(arr) > 0 {
+		return journalJSONValue(arr[0])
+	}
+	return "", false
+}
+
+// ReadJournalExport reads the journal export format (what
+// systemd-journal-upload sends as application/vnd.fdo.journal): entries are
+// separated by blank lines; a field is "NAME=value\n", or "NAME\n" followed by
+// a little-endian uint64 length, the binary value and "\n". fn is called per
+// entry; maxField bounds one field, text or binary.
+func ReadJournalExport(r io.Reader, maxField int, fn func(JournalEntry) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	e := JournalEntry{}
+	for {
+		line, err := readLine(br, maxField)
+		if errors.Is(err, io.EOF) {
+			if line != "" {
+				return io.ErrUnexpectedEOF
+			}
+			if len(e) > 0 {
+				return fn(e)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(e) > 0 {
+				if err := fn(e); err != nil {
+					return err
+				}
+				e = JournalEntry{}
+			}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			e[name] = value
+			continue
+		}
+		var n uint64
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return fmt.Errorf("journal export: field %s: %w", line, err)
+		}
+		if n > uint64(maxField) {
+			return fmt.Errorf("journal export: field %s is %d bytes", line, n)
+		}
+		buf := make([]byte, n+1)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return fmt.Errorf("journal export: field %s: %w", line, err)
+		}
+		if buf[n] != '\n' {
+			return fmt.Errorf("journal export: field %s not newline-terminated", line)
+		}
+		e[line] = string(buf[:n])
+	}
+}
+
+// readLine reads up to and including the next LF, failing once the line
+// grows past limit bytes.
+func readLine(br *bufio.Reader, limit int) (string, error) {
+	var b []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(b)+len(chunk) > limit+1 {
+			return "", fmt.Errorf("journal export: field exceeds %d bytes", limit)
+		}
+		b = append(b, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return string(b), err
+	}
+}
+
+// Time is __REALTIME_TIMESTAMP (microseconds since the epoch), falling back
+// to _SOURCE_REALTIME_TIMESTAMP.
+func (e JournalEntry) Time() (time.Time, bool) {
+	for _, k := range []string{"__REALTIME_TIMESTAMP", "_SOURCE_REALTIME_TIMESTAMP"} {
+		if us, err := strconv.ParseInt(e[k], 10, 64); err == nil && us > 0 {
+			return time.UnixMicro(us).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// PID is _PID, or SYSLOG_PID for entries relayed from syslog.
+func (e JournalEntry) PID() int {
+	for _, k := range []string{"_PID", "SYSLOG_PID"} {
+		if n, err := strconv.Atoi(e[k]); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// Identifier is SYSLOG_IDENTIFIER, falling back to _COMM.
+func (e JournalEntry) Identifier() string {
+	if id := e["SYSLOG_IDENTIFIER"]; id != "" {
+		return id
+	}
+	return e["_COMM"]
+}
+
+// IsSSHD reports whether the entry came from sshd (or the ssh unit).
+func (e JournalEntry) IsSSHD() bool {
+	if strings.HasPrefix(e.Identifier(), "sshd") {
+		return true
+	}
+	unit := e["_SYSTEMD_UNIT"]
+	return unit == "ssh.service" || unit == "sshd.service" || strings.HasPrefix(unit, "sshd@")
+}
+
+// Line renders the entry like journalctl --output=short-iso, which is what
+// ParseLineEnhanced reads and what access_events.raw_line keeps.
+func (e JournalEntry) Line() string {
+	ts, ok := e.Time()
+	if !ok {
+		ts = time.Now().UTC()
+	}
+	host := e["_HOSTNAME"]
+	if host == "" {
+		host = "-"
+	}
+	tag := e.Identifier()
+	if tag == "" {
+		tag = "-"
+	}
+	if pid := e.PID(); pid > 0 {
+		tag += "[" + strconv.Itoa(pid) + "]"
+	}
+	return fmt.Sprintf("%s %s %s: %s", ts.Format(time.RFC3339), host, tag, e["MESSAGE"])
+}
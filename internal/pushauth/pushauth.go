@@ -0,0 +1,94 @@
+// Package pushauth is what the push receivers (syslog, journal-remote) share
+// to decide whom to accept logs from and which host a log belongs to: sender
+// allow lists, TLS with optional client certificates, and host attribution.
+package pushauth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
+)
+
+// AllowList is the sender networks a receiver accepts. Config validation
+// only lets it be empty when client certificates are required.
+type AllowList []*net.IPNet
+
+// ParseAllowList parses cidrs, skipping invalid entries (config validation
+// has already rejected them).
+func ParseAllowList(cidrs []string) AllowList {
+	var out AllowList
+	for _, c := range cidrs {
+		if _, n, err := net.ParseCIDR(strings.TrimSpace(c)); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Allows reports whether ip may send; an empty list allows everyone.
+func (a AllowList) Allows(ip net.IP) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for _, n := range a {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAddr is Allows for a "host:port" remote address.
+func (a AllowList) AllowsAddr(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	return a.Allows(net.ParseIP(host))
+}
+
+// TLSConfig loads the server key pair; with clientCAFile set, clients must
+// present a certificate signed by it.
+func TLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	tc := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client ca %s: no certificates", clientCAFile)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// Attribute names the host a log came from. With hostFrom "hostname" the
+// name the sender claims wins (relays forward other hosts' logs); otherwise,
+// or when the claim is missing or an address, the sender's reverse DNS name
+// (as discovery.dns allows it) is used, falling back to its IP.
+func Attribute(ctx context.Context, dns *resolver.Resolver, hostFrom, claimed string, ip net.IP) string {
+	if hostFrom == "hostname" && claimed != "" && net.ParseIP(claimed) == nil {
+		return strings.ToLower(strings.TrimSuffix(claimed, "."))
+	}
+	if ip == nil {
+		return ""
+	}
+	if name := dns.Name(dns.Reverse(ctx, ip.String())); name != "" {
+		return name
+	}
+	return ip.String()
+}
@@ -0,0 +1,201 @@
+package reach
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Prober answers "can the jump server ssh to this host?" without a handshake
+// per question. Answers are cached in memory and on hosts (reach_checked_at),
+// so the watcher, the spider and other replicas reuse each other's probes.
+// Reachable answers live for reach.ttl_seconds, unreachable ones for
+// reach.negative_ttl_seconds.
+type Prober struct {
+	ssh    *sshclient.Client
+	st     *store.Store
+	ttl    time.Duration
+	negTTL time.Duration
+
+	workers int
+	queue   chan asyncCheck
+	start   sync.Once
+
+	mu       sync.Mutex
+	cache    map[string]Result
+	inflight map[string]*call
+}
+
+// Result is one probe outcome.
+type Result struct {
+	Host      string
+	Reachable bool
+	Reason    string // sshclient.Reason*; empty when reachable
+	Detail    string
+	CheckedAt time.Time
+}
+
+// Summary is Reason and Detail in one line, for concern details.
+func (r Result) Summary() string {
+	if r.Reachable || r.Reason == "" {
+		return ""
+	}
+	if r.Detail == "" {
+		return r.Reason
+	}
+	return r.Reason + ": " + r.Detail
+}
+
+type asyncCheck struct {
+	host string
+	then func(Result)
+}
+
+// call is a probe in progress that concurrent checks of the same host wait on.
+type call struct {
+	done chan struct{}
+	res  Result
+}
+
+func New(cfg *config.Config, st *store.Store, ssh *sshclient.Client) *Prober {
+	return &Prober{
+		ssh:      ssh,
+		st:       st,
+		ttl:      time.Duration(cfg.Reach.TTLSeconds) * time.Second,
+		negTTL:   time.Duration(cfg.Reach.NegativeTTLSeconds) * time.Second,
+		workers:  max(cfg.Reach.Workers, 1),
+		queue:    make(chan asyncCheck, max(cfg.Reach.QueueSize, 1)),
+		cache:    map[string]Result{},
+		inflight: map[string]*call{},
+	}
+}
+
+// Check returns whether host is reachable, probing only when no fresh answer
+// is cached. Concurrent checks of one host share a single probe.
+func (p *Prober) Check(ctx context.Context, host string) bool {
+	return p.check(ctx, host).Reachable
+}
+
+// Lookup is Check returning the whole result, including why a host is unreachable.
+func (p *Prober) Lookup(ctx context.Context, host string) Result {
+	return p.check(ctx, host)
+}
+
+// Async calls then (if not nil) with host's reachability without blocking:
+// at once from a background worker when a fresh answer is cached, else after
+// a probe. When the queue is full the check is dropped; the next sighting of
+// the host asks again.
+func (p *Prober) Async(host string, then func(Result)) {
+	p.start.Do(func() {
+		for i := 0; i < p.workers; i++ {
+			go p.work()
+		}
+	})
+	select {
+	case p.queue <- asyncCheck{host: host, then: then}:
+	default:
+		log.Printf("reach: probe queue full, skipping %s", host)
+	}
+}
+
+// Record stores a probe made some other way (a watcher connecting, say) in
+// the cache and in the host's reachability history.
+func (p *Prober) Record(ctx context.Context, host string, pr sshclient.ProbeResult) Result {
+	res := Result{Host: host, Reachable: pr.OK, Reason: pr.Reason, Detail: pr.Detail, CheckedAt: time.Now()}
+	p.mu.Lock()
+	p.cache[host] = res
+	p.mu.Unlock()
+	rp := store.ReachProbe{Reachable: pr.OK, DurationMS: pr.Duration.Milliseconds()}
+	if !pr.OK {
+		rp.Reason, rp.Detail = &pr.Reason, &pr.Detail
+	}
+	if _, err := p.st.RecordReachability(ctx, host, rp); err != nil && ctx.Err() == nil {
+		log.Printf("reach: %v", err)
+	}
+	return res
+}
+
+// work runs async checks for the life of the process.
+func (p *Prober) work() {
+	for c := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		res := p.check(ctx, c.host)
+		if c.then != nil {
+			c.then(res)
+		}
+		cancel()
+	}
+}
+
+func (p *Prober) check(ctx context.Context, host string) Result {
+	if res, ok := p.cached(ctx, host); ok {
+		return res
+	}
+
+	p.mu.Lock()
+	if c, ok := p.inflight[host]; ok {
+		p.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.res
+		case <-ctx.Done():
+			return Result{Host: host}
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	p.inflight[host] = c
+	p.mu.Unlock()
+
+	pr := p.ssh.Probe(ctx, host)
+	if ctx.Err() == nil {
+		c.res = p.Record(ctx, host, pr)
+	} else {
+		// A probe cut short by the caller says nothing about the host.
+		c.res = Result{Host: host}
+	}
+
+	p.mu.Lock()
+	delete(p.inflight, host)
+	p.mu.Unlock()
+	close(c.done)
+	return c.res
+}
+
+// cached returns a fresh answer from memory or, failing that, from hosts.
+func (p *Prober) cached(ctx context.Context, host string) (Result, bool) {
+	p.mu.Lock()
+	res, ok := p.cache[host]
+	p.mu.Unlock()
+	if ok && p.fresh(res) {
+		return res, true
+	}
+
+	rp, err := p.st.HostReachability(ctx, host)
+	if err != nil || rp.CheckedAt.IsZero() {
+		return Result{}, false
+	}
+	res = Result{Host: host, Reachable: rp.Reachable, CheckedAt: rp.CheckedAt}
+	if rp.Reason != nil {
+		res.Reason = *rp.Reason
+	}
+	if !p.fresh(res) {
+		return Result{}, false
+	}
+	p.mu.Lock()
+	p.cache[host] = res
+	p.mu.Unlock()
+	return res, true
+}
+
+func (p *Prober) fresh(res Result) bool {
+	ttl := p.ttl
+	if !res.Reachable {
+		ttl = p.negTTL
+	}
+	return time.Since(res.CheckedAt) < ttl
+}
@@ -0,0 +1,204 @@
+package remotecmd
+
+// Built-in templates. Every script here has been reviewed as read-only: it
+// only reads logs, authorized_keys and file metadata, and never writes outside
+// /dev/null. Bump Version (and CatalogVersion) when changing a script.
+
+var (
+	sinceParams = []Param{{Name: "since", Kind: KindString}, {Name: "max_lines", Kind: KindInt}}
+	findParams  = []Param{{Name: "roots", Kind: KindPaths}, {Name: "max_depth", Kind: KindInt}, {Name: "max_files", Kind: KindInt}}
+	watchParams = []Param{{Name: "cursor", Kind: KindString, Optional: true}, {Name: "since", Kind: KindString}}
+	tailParams  = []Param{{Name: "inode", Kind: KindString, Optional: true}, {Name: "offset", Kind: KindInt, Optional: true}}
+)
+
+const keyNamesExpr = `\( -name id_rsa -o -name id_ed25519 -o -name id_ecdsa -o -name identity -o -name '*.pem' -o -name 'id_*' \)`
+
+func builtins() []Template {
+	return []Template{
+		{
+			Name: Uname, OS: "posix", Version: 1, ReadOnly: true,
+			Review: "prints the kernel name",
+			Script: `uname -s`,
+		},
+
+		// sshd logs (historical scan).
+		{
+			Name: SSHDLogs, OS: "posix", Version: 1, Params: sinceParams, ReadOnly: true,
+			Review: "reads journald or auth log files",
+			Script: `(command -v journalctl >/dev/null 2>&1 && journalctl -u ssh -u sshd --since {{q .since}} --no-pager) || (test -r /var/log/secure && tail -n {{.max_lines}} /var/log/secure) || (test -r /var/log/auth.log && tail -n {{.max_lines}} /var/log/auth.log)`,
+		},
+		{
+			Name: SSHDLogs, OS: "aix", Version: 1, Params: sinceParams, ReadOnly: true,
+			Review: "reads syslog auth files; AIX location depends on syslog.conf",
+			Script: `(test -r /var/adm/ras/authlog && tail -n {{.max_lines}} /var/adm/ras/authlog) || (test -r /var/adm/messages && tail -n {{.max_lines}} /var/adm/messages) || (test -r /var/log/messages && tail -n {{.max_lines}} /var/log/messages)`,
+		},
+		{
+			Name: SSHDLogs, OS: "solaris", Version: 1, Params: sinceParams, ReadOnly: true,
+			Review: "reads syslog auth files",
+			Script: `(test -r /var/log/authlog && tail -{{.max_lines}} /var/log/authlog) || (test -r /var/adm/messages && tail -{{.max_lines}} /var/adm/messages)`,
+		},
+		{
+			Name: SSHDLogs, OS: "freebsd", Version: 1, Params: sinceParams, ReadOnly: true,
+			Review: "reads syslog auth file",
+			Script: `test -r /var/log/auth.log && tail -n {{.max_lines}} /var/log/auth.log`,
+		},
+
+		// authorized_keys dump, one "---FILE <path>" header per file.
+		{
+			Name: AuthorizedKeys, OS: "posix", Version: 1, ReadOnly: true,
+			Review: "cats readable authorized_keys files under /root and /home",
+			Script: `for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys; do
+  [ -r "$f" ] || continue
+  echo "---FILE $f"
+  cat "$f"
+  echo
+done`,
+		},
+		{
+			Name: AuthorizedKeys, OS: "solaris", Version: 1, ReadOnly: true,
+			Review: "cats readable authorized_keys files under /root, /home and /export/home",
+			Script: `for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys /export/home/*/.ssh/authorized_keys; do
+  [ -r "$f" ] || continue
+  echo "---FILE $f"
+  cat "$f"
+  echo
+done`,
+		},
+
+		// Key hunt: list candidate private key paths (never contents).
+		{
+			Name: KeyHuntFind, OS: "posix", Version: 1, Params: findParams, ReadOnly: true,
+			Review: "lists file names matching key patterns under allow_roots",
+			Script: `find {{qs .roots}} -xdev -maxdepth {{.max_depth}} -type f ` + keyNamesExpr + ` -size -2M 2>/dev/null | head -n {{.max_files}}`,
+		},
+		{
+			// AIX and Solaris find lack -maxdepth; -size is in 512-byte blocks.
+			Name: KeyHuntFind, OS: "aix", Version: 1, Params: findParams, ReadOnly: true,
+			Review: "lists file names matching key patterns under allow_roots",
+			Script: `find {{qs .roots}} -xdev -type f ` + keyNamesExpr + ` -size -4096 2>/dev/null | head -n {{.max_files}}`,
+		},
+		{
+			Name: KeyHuntFind, OS: "solaris", Version: 1, Params: findParams, ReadOnly: true,
+			Review: "lists file names matching key patterns under allow_roots",
+			Script: `find {{qs .roots}} -xdev -type f ` + keyNamesExpr + ` -size -4096 2>/dev/null | head -{{.max_files}}`,
+		},
+		{
+			Name: KeyHuntDerive, OS: "posix", Version: 1, Params: []Param{{Name: "path", Kind: KindPath}}, ReadOnly: true,
+			Review: "reads the first line of a key file and derives its public half with ssh-keygen -y; private key material never leaves the host",
+			Script: `if head -n 1 {{q .path}} | grep -q "BEGIN OPENSSH PRIVATE KEY"; then
+  echo PRIV
+  ssh-keygen -y -f {{q .path}} 2>/dev/null | sed -e "s/[[:space:]]*$//"
+fi`,
+		},
+
+		// sshd configuration (config_audit jobs). Prefers the effective config from
+		// sshd -T; falls back to the raw file. Output starts with "---SOURCE <what>".
+		{
+			Name: SSHDConfig, OS: "posix", Version: 1, ReadOnly: true,
+			Review: "prints the effective sshd configuration (sshd -T only parses config) or cats sshd_config",
+			Script: `for d in /usr/sbin /usr/local/sbin /opt/ssh/sbin /usr/lib/ssh; do
+  if [ -x "$d/sshd" ] && out=$("$d/sshd" -T 2>/dev/null); then echo "---SOURCE sshd -T"; echo "$out"; exit 0; fi
+done
+for f in /etc/ssh/sshd_config /etc/sshd_config /usr/local/etc/ssh/sshd_config /etc/opt/ssh/sshd_config; do
+  if [ -r "$f" ]; then echo "---SOURCE $f"; cat "$f"; exit 0; fi
+done
+exit 2`,
+		},
+
+		// Watcher streams.
+		{
+			Name: WatchJournal, OS: "posix", Version: 2, Params: watchParams, ReadOnly: true,
+			Review: "follows the ssh/sshd journal units as JSON records (each carries its cursor)",
+			Script: `command -v journalctl >/dev/null 2>&1 || exit 2
+{{if .cursor}}exec journalctl -f -u ssh -u sshd --no-pager --output=json --after-cursor {{q .cursor}}{{else}}exec journalctl -f -u ssh -u sshd --no-pager --output=json --since {{q .since}}{{end}}`,
+		},
+		{
+			Name: WatchTail, OS: "posix", Version: 3, Params: tailParams, ReadOnly: true,
+			Review: "polls the auth log file from a byte offset; on rotation reads the rest of the rotated file (.1, dated or .gz)",
+			Script: tailScript("/var/log/secure /var/log/auth.log"),
+		},
+		{
+			Name: WatchTail, OS: "aix", Version: 3, Params: tailParams, ReadOnly: true,
+			Review: "polls the auth log file from a byte offset; on rotation reads the rest of the rotated file (.0, .1, dated or .gz)",
+			Script: tailScript("/var/adm/ras/authlog /var/adm/messages"),
+		},
+		{
+			Name: WatchTail, OS: "solaris", Version: 3, Params: tailParams, ReadOnly: true,
+			Review: "polls the auth log file from a byte offset; on rotation reads the rest of the rotated file (.0, .1, dated or .gz)",
+			Script: tailScript("/var/log/authlog /var/adm/messages"),
+		},
+	}
+}
+
+// tailScript follows the first readable file of files by polling from a byte
+// offset instead of tail -F, so a reconnecting watcher loses nothing.
+//
+// inode/offset are the watcher's cursor. Without one it starts at the end of
+// the file. If the file's inode changed (rotation), the rest of the old file is
+// read first: it is found by inode among the rotated names, or, once
+// compressed, taken from the newest .gz. Marker lines start with "---KS " and
+// are preceded by a newline, since the bytes before them may not end in one:
+// "FILE <path> <inode> <offset>" says where the following bytes come from;
+// ROTATED, GAP and TRUNCATED are informational.
+func tailScript(files string) string {
+	return `LC_ALL=C; export LC_ALL
+f=
+for c in ` + files + `; do if [ -r "$c" ]; then f=$c; break; fi; done
+[ -n "$f" ] || exit 2
+ino() { ls -i "$1" 2>/dev/null | awk '{print $1}'; }
+size() { wc -c < "$1" | awk '{print $1}'; }
+rest() {
+  r=
+  for c in "$f.0" "$f.1" $(ls -t "$f"-* "$f".[0-9]* 2>/dev/null); do
+    case "$c" in *.gz|*.Z) continue ;; esac
+    if [ -r "$c" ] && [ "$(ino "$c")" = "$1" ]; then r=$c; break; fi
+  done
+  if [ -n "$r" ]; then
+    printf '\n---KS ROTATED %s\n' "$r"
+    tail -c +$(($2 + 1)) "$r"
+    return
+  fi
+  for g in "$f.0.gz" "$f.1.gz" $(ls -t "$f"-*.gz 2>/dev/null); do
+    if [ -r "$g" ]; then
+      printf '\n---KS ROTATED %s\n' "$g"
+      gzip -dc "$g" | tail -c +$(($2 + 1))
+      return
+    fi
+  done
+  printf '\n---KS GAP rotated file for inode %s not found\n' "$1"
+}
+want={{q .inode}}
+off={{.offset}}
+cur=$(ino "$f")
+if [ -z "$want" ]; then
+  off=$(size "$f")
+elif [ "$cur" != "$want" ]; then
+  rest "$want" "$off"
+  off=0
+elif [ "$off" -gt "$(size "$f")" ]; then
+  printf '\n---KS TRUNCATED\n'
+  off=0
+fi
+printf '\n---KS FILE %s %s %s\n' "$f" "$cur" "$off"
+while :; do
+  n=$(ino "$f")
+  if [ -n "$n" ] && [ "$n" != "$cur" ]; then
+    rest "$cur" "$off"
+    cur=$n; off=0
+    printf '\n---KS FILE %s %s %s\n' "$f" "$cur" "$off"
+  fi
+  sz=$(size "$f")
+  if [ -n "$sz" ] && [ "$sz" -lt "$off" ]; then
+    printf '\n---KS TRUNCATED\n'
+    off=0
+    printf '\n---KS FILE %s %s %s\n' "$f" "$cur" "$off"
+  fi
+  if [ -n "$sz" ] && [ "$sz" -gt "$off" ]; then
+    chunk=$(tail -c +$((off + 1)) "$f"; echo .)
+    chunk=${chunk%.}
+    printf '%s' "$chunk"
+    off=$((off + ${#chunk}))
+  fi
+  sleep 2
+done`
+}
@@ -0,0 +1,338 @@
+package remotecmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// CatalogVersion changes whenever a built-in template changes, so audit rows and
+// reviews can be tied to a specific set of scripts.
+const CatalogVersion = "2026.10-5"
+
+// Names of the commands keyspider runs remotely.
+const (
+	Uname          = "uname"
+	SSHDLogs       = "sshd_logs"
+	AuthorizedKeys = "authorized_keys"
+	KeyHuntFind    = "keyhunt_find"
+	KeyHuntDerive  = "keyhunt_derive"
+	WatchJournal   = "watch_journal"
+	WatchTail      = "watch_tail"
+	SSHDConfig     = "sshd_config"
+)
+
+// OS families with their own templates. Anything else falls back to posix.
+var OSes = []string{"linux", "aix", "solaris", "freebsd"}
+
+type Kind int
+
+const (
+	KindString  Kind = iota // arbitrary text, always shell-quoted
+	KindPath                // absolute path, no newlines, shell-quoted
+	KindInt                 // decimal integer
+	KindStrings             // list of strings, each shell-quoted
+	KindPaths               // list of absolute paths, each shell-quoted
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindPath:
+		return "path"
+	case KindInt:
+		return "int"
+	case KindStrings:
+		return "strings"
+	case KindPaths:
+		return "paths"
+	}
+	return "unknown"
+}
+
+type Param struct {
+	Name     string
+	Kind     Kind
+	Optional bool
+}
+
+// Template is one reviewed remote script. Script is a text/template rendered
+// with typed params; use {{q .x}} / {{qs .xs}} for quoting. ReadOnly is the
+// reviewed guarantee that the script only reads host state; every template
+// (built-in or override) is also checked by CheckReadOnly.
+type Template struct {
+	Name     string
+	OS       string // "posix" for the fallback
+	Version  int
+	Params   []Param
+	Script   string
+	ReadOnly bool
+	Review   string // why this is read-only / what it touches
+	Override bool   // true if supplied via commands.overrides
+
+	tmpl *template.Template
+}
+
+type Catalog struct {
+	byKey map[string]*Template // "os/name"
+}
+
+var funcs = template.FuncMap{
+	"q":  Quote,
+	"qs": QuoteAll,
+}
+
+// New builds the catalog from the built-ins plus overrides (commands.overrides
+// in config). Overrides are keyed "os/name" (e.g. "aix/sshd_logs"), keep the
+// built-in params and must pass CheckReadOnly.
+func New(overrides map[string]string) (*Catalog, error) {
+	c := &Catalog{byKey: map[string]*Template{}}
+	for _, t := range builtins() {
+		t := t
+		if err := c.add(&t); err != nil {
+			return nil, fmt.Errorf("builtin %s/%s: %w", t.OS, t.Name, err)
+		}
+	}
+	for key, script := range overrides {
+		osName, name, ok := strings.Cut(strings.ToLower(key), "/")
+		if !ok {
+			return nil, fmt.Errorf("commands.overrides: key %q must be os/name", key)
+		}
+		base := c.lookup(osName, name)
+		if base == nil {
+			return nil, fmt.Errorf("commands.overrides: unknown command %q", name)
+		}
+		t := Template{
+			Name:     name,
+			OS:       osName,
+			Version:  base.Version,
+			Params:   base.Params,
+			Script:   script,
+			ReadOnly: true,
+			Review:   "operator override of " + base.OS + "/" + name,
+			Override: true,
+		}
+		if err := c.add(&t); err != nil {
+			return nil, fmt.Errorf("commands.overrides %s: %w", key, err)
+		}
+	}
+	return c, nil
+}
+
+// MustNew is New for overrides already validated by config.Load.
+func MustNew(overrides map[string]string) *Catalog {
+	c, err := New(overrides)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Catalog) add(t *Template) error {
+	if !t.ReadOnly {
+		return fmt.Errorf("template is not marked read-only")
+	}
+	if err := CheckReadOnly(t.Script); err != nil {
+		return err
+	}
+	tm, err := template.New(t.OS + "/" + t.Name).Funcs(funcs).Option("missingkey=error").Parse(t.Script)
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	t.tmpl = tm
+	c.byKey[t.OS+"/"+t.Name] = t
+	return nil
+}
+
+func (c *Catalog) lookup(osName, name string) *Template {
+	if t, ok := c.byKey[osName+"/"+name]; ok {
+		return t
+	}
+	return c.byKey["posix/"+name]
+}
+
+// Get returns the template used for name on osName (falling back to posix).
+func (c *Catalog) Get(osName, name string) (*Template, error) {
+	t := c.lookup(strings.ToLower(osName), name)
+	if t == nil {
+		return nil, fmt.Errorf("no command %q for os %q", name, osName)
+	}
+	return t, nil
+}
+
+// List returns every template sorted by name then OS.
+func (c *Catalog) List() []*Template {
+	out := make([]*Template, 0, len(c.byKey))
+	for _, t := range c.byKey {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].OS < out[j].OS
+	})
+	return out
+}
+
+// Params maps parameter names to values; see Kind for accepted Go types.
+type Params map[string]any
+
+// Render validates params against the template and returns the full remote
+// command line (sh -lc '<script>'), safe to pass to sshclient.Run/Stream.
+func (c *Catalog) Render(osName, name string, p Params) (string, error) {
+	t, err := c.Get(osName, name)
+	if err != nil {
+		return "", err
+	}
+	data := map[string]any{}
+	for _, spec := range t.Params {
+		v, ok := p[spec.Name]
+		if !ok {
+			if spec.Optional {
+				data[spec.Name] = zero(spec.Kind)
+				continue
+			}
+			return "", fmt.Errorf("%s: missing param %q", name, spec.Name)
+		}
+		if err := checkKind(spec, v); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		data[spec.Name] = v
+	}
+	for k := range p {
+		if !hasParam(t.Params, k) {
+			return "", fmt.Errorf("%s: unknown param %q", name, k)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%s: render: %w", name, err)
+	}
+	return "sh -lc " + Quote(buf.String()), nil
+}
+
+func hasParam(ps []Param, name string) bool {
+	for _, p := range ps {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func zero(k Kind) any {
+	switch k {
+	case KindInt:
+		return 0
+	case KindStrings, KindPaths:
+		return []string(nil)
+	}
+	return ""
+}
+
+func checkKind(spec Param, v any) error {
+	switch spec.Kind {
+	case KindString:
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("param %q must be a string", spec.Name)
+		}
+	case KindPath:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("param %q must be a path string", spec.Name)
+		}
+		return checkPath(spec.Name, s)
+	case KindInt:
+		if _, ok := v.(int); !ok {
+			return fmt.Errorf("param %q must be an int", spec.Name)
+		}
+	case KindStrings, KindPaths:
+		ss, ok := v.([]string)
+		if !ok {
+			return fmt.Errorf("param %q must be a []string", spec.Name)
+		}
+		if spec.Kind == KindPaths {
+			for _, s := range ss {
+				if err := checkPath(spec.Name, s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkPath(name, s string) error {
+	if !strings.HasPrefix(s, "/") {
+		return fmt.Errorf("param %q: path %q must be absolute", name, s)
+	}
+	if strings.ContainsAny(s, "\x00\n\r") {
+		return fmt.Errorf("param %q: path contains control characters", name)
+	}
+	return nil
+}
+
+// Quote returns s as a single POSIX shell word.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// QuoteAll quotes each element and joins them with spaces.
+func QuoteAll(ss []string) string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = Quote(s)
+	}
+	return strings.Join(out, " ")
+}
+
+var (
+	// Commands that change host state. Matched as whole words, also by
+	// absolute path (/bin/rm) and inside quoted nested commands (sh -c "rm x").
+	reWriteCmd   = regexp.MustCompile(`(^|[\s;|&(/"'` + "`" + `])(rm|rmdir|mv|cp|dd|ln|touch|mkdir|install|chmod|chown|chgrp|truncate|tee|kill|pkill|killall|shutdown|reboot|halt|mkfs|useradd|usermod|userdel|passwd|crontab|systemctl|service|curl|wget|scp|sftp)(\s|$|[;|&)"'` + "`" + `])`)
+	reSedInPlace = regexp.MustCompile(`\bsed\s+(-\w*\s+)*-i`)
+	// find actions that delete, run commands or write files.
+	reFindAction  = regexp.MustCompile(`(^|\s)-(delete|exec|execdir|ok|okdir|fprint|fprint0|fprintf|fls)(\s|$)`)
+	rePerlInPlace = regexp.MustCompile(`\bperl(\s+-\w+)*\s+-\w*i`)
+	// Interpreters running code given on the command line, and awk's system().
+	reInlineCode = regexp.MustCompile(`\b(perl|python[0-9.]*|ruby|node|php)\b[^;|&\n]*\s-\w*[ce]\b|\bsystem\s*\(`)
+	// Output redirections; only /dev/null and fd duplication are allowed.
+	reRedirect = regexp.MustCompile(`\d?>>?\s*(&\d|/dev/null|[^\s;|&)]+)`)
+)
+
+// CheckReadOnly rejects scripts that contain state-changing commands (also
+// by absolute path or inside quotes), find actions that delete, execute or
+// write, perl -i, interpreters running inline code, or that redirect output
+// anywhere other than /dev/null. It is deliberately strict: it is a review
+// aid, not a sandbox.
+func CheckReadOnly(script string) error {
+	if m := reWriteCmd.FindStringSubmatch(script); m != nil {
+		return fmt.Errorf("not read-only: uses %q", m[2])
+	}
+	if reSedInPlace.MatchString(script) {
+		return fmt.Errorf("not read-only: uses sed -i")
+	}
+	if m := reFindAction.FindStringSubmatch(script); m != nil {
+		return fmt.Errorf("not read-only: uses find -%s", m[2])
+	}
+	if rePerlInPlace.MatchString(script) {
+		return fmt.Errorf("not read-only: uses perl -i")
+	}
+	if m := reInlineCode.FindString(script); m != "" {
+		return fmt.Errorf("not read-only: runs inline code (%q)", strings.TrimSpace(m))
+	}
+	for _, m := range reRedirect.FindAllStringSubmatch(script, -1) {
+		target := m[1]
+		if target == "/dev/null" || strings.HasPrefix(target, "&") {
+			continue
+		}
+		return fmt.Errorf("not read-only: redirects output to %q", target)
+	}
+	return nil
+}
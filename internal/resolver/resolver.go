@@ -0,0 +1,193 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+)
+
+// Resolver does reverse DNS for log sources: every PTR name of an address,
+// each checked forward (FCrDNS: the name's A/AAAA records include the
+// address). Answers are cached for discovery.dns.ttl_seconds, or
+// negative_ttl_seconds when they give no usable name, and concurrent lookups of
+// one address share a single query.
+type Resolver struct {
+	r        *net.Resolver
+	timeout  time.Duration
+	ttl      time.Duration
+	negTTL   time.Duration
+	require  bool
+	maxItems int
+
+	mu       sync.Mutex
+	cache    map[string]Names
+	inflight map[string]*call
+}
+
+// Names is what reverse DNS says about one address.
+type Names struct {
+	IP         string    `json:"ip"`
+	PTR        []string  `json:"ptr"`       // every PTR name, lowercased, without the trailing dot
+	Confirmed  []string  `json:"confirmed"` // the PTR names that resolve back to IP
+	Err        string    `json:"error,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// FCrDNS reports whether some PTR name resolves back to the address.
+func (n Names) FCrDNS() bool { return len(n.Confirmed) > 0 }
+
+type call struct {
+	done chan struct{}
+	res  Names
+}
+
+func New(cfg *config.Config) *Resolver {
+	d := cfg.Discovery.DNS
+	r := &Resolver{
+		r:        net.DefaultResolver,
+		timeout:  time.Duration(max(d.TimeoutSeconds, 1)) * time.Second,
+		ttl:      time.Duration(d.TTLSeconds) * time.Second,
+		negTTL:   time.Duration(d.NegativeTTLSeconds) * time.Second,
+		require:  d.RequireFCrDNS,
+		maxItems: max(d.MaxEntries, 1),
+		cache:    map[string]Names{},
+		inflight: map[string]*call{},
+	}
+	if d.Server != "" {
+		server := d.Server
+		r.r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dl net.Dialer
+				return dl.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return r
+}
+
+// Name is the name to label ip by: its first forward-confirmed PTR name or,
+// unless discovery.dns.require_fcrdns is set, its first PTR name. It is ""
+// when there is none.
+func (r *Resolver) Name(n Names) string {
+	if len(n.Confirmed) > 0 {
+		return n.Confirmed[0]
+	}
+	if !r.require && len(n.PTR) > 0 {
+		return n.PTR[0]
+	}
+	return ""
+}
+
+// Reverse looks ip up, from the cache when a fresh answer is there.
+func (r *Resolver) Reverse(ctx context.Context, ip string) Names {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Names{IP: ip, Err: "not an ip address", ResolvedAt: time.Now()}
+	}
+	key := addr.String()
+
+	r.mu.Lock()
+	if n, ok := r.cache[key]; ok && r.fresh(n) {
+		r.mu.Unlock()
+		return n
+	}
+	c, ok := r.inflight[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		r.inflight[key] = c
+		// The lookup is shared, so it must not end with the caller that
+		// started it: it runs detached, bounded by the resolver's timeout.
+		go r.resolve(context.WithoutCancel(ctx), key, addr, c)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.res
+	case <-ctx.Done():
+		return Names{IP: key, Err: ctx.Err().Error()}
+	}
+}
+
+// resolve runs the lookup for c, caches it and releases its waiters.
+func (r *Resolver) resolve(ctx context.Context, key string, addr net.IP, c *call) {
+	c.res = r.lookup(ctx, addr)
+
+	r.mu.Lock()
+	delete(r.inflight, key)
+	if len(r.cache) >= r.maxItems {
+		r.prune()
+	}
+	r.cache[key] = c.res
+	r.mu.Unlock()
+	close(c.done)
+}
+
+func (r *Resolver) lookup(ctx context.Context, addr net.IP) Names {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	n := Names{IP: addr.String(), PTR: []string{}, Confirmed: []string{}}
+
+	ptrs, err := r.r.LookupAddr(ctx, n.IP)
+	if err != nil {
+		n.Err = err.Error()
+	}
+	for _, p := range ptrs {
+		p = strings.ToLower(strings.TrimSuffix(p, "."))
+		if p == "" || slices.Contains(n.PTR, p) {
+			continue
+		}
+		n.PTR = append(n.PTR, p)
+		if r.confirms(ctx, p, addr) {
+			n.Confirmed = append(n.Confirmed, p)
+		}
+	}
+	n.ResolvedAt = time.Now()
+	return n
+}
+
+// confirms reports whether name resolves forward to addr.
+func (r *Resolver) confirms(ctx context.Context, name string, addr net.IP) bool {
+	ips, err := r.r.LookupIPAddr(ctx, name)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip.IP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) fresh(n Names) bool {
+	ttl := r.ttl
+	if r.Name(n) == "" {
+		ttl = r.negTTL
+	}
+	return time.Since(n.ResolvedAt) < ttl
+}
+
+// prune drops stale answers and, if the cache is still full, half of the
+// rest. Callers hold r.mu.
+func (r *Resolver) prune() {
+	for k, n := range r.cache {
+		if !r.fresh(n) {
+			delete(r.cache, k)
+		}
+	}
+	drop := len(r.cache) - r.maxItems/2
+	for k := range r.cache {
+		if drop <= 0 {
+			break
+		}
+		delete(r.cache, k)
+		drop--
+	}
+}
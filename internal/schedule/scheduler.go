@@ -0,0 +1,219 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/exporter"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+var reName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Prepare validates a new schedule, fills defaults and computes its first
+// run after now. Used by the API and CLI before store.CreateSchedule.
+func Prepare(sc *store.Schedule, now time.Time) error {
+	if !reName.MatchString(sc.Name) {
+		return fmt.Errorf("schedule name %q: use letters, digits, '.', '_' and '-' (max 64)", sc.Name)
+	}
+	if sc.Timezone == "" {
+		sc.Timezone = "UTC"
+	}
+	if sc.MisfirePolicy == "" {
+		sc.MisfirePolicy = store.MisfireRunOnce
+	}
+	if sc.MisfirePolicy != store.MisfireRunOnce && sc.MisfirePolicy != store.MisfireSkip {
+		return fmt.Errorf("misfire_policy must be %s or %s", store.MisfireRunOnce, store.MisfireSkip)
+	}
+	switch sc.Action {
+	case store.ScheduleActionJob:
+		js := sc.Spec.JobSpec()
+		if err := js.Validate(); err != nil {
+			return err
+		}
+	case store.ScheduleActionExport:
+		if sc.Spec.Format == "" {
+			sc.Spec.Format = "json"
+		}
+		switch sc.Spec.Format {
+		case "json", "csv", "graphml":
+		default:
+			return fmt.Errorf("unknown export format %q (use json|csv|graphml)", sc.Spec.Format)
+		}
+	default:
+		return fmt.Errorf("action must be %s or %s", store.ScheduleActionJob, store.ScheduleActionExport)
+	}
+	next, err := NextRun(sc.Cron, sc.Timezone, now)
+	if err != nil {
+		return err
+	}
+	sc.NextRunAt = next
+	return nil
+}
+
+// NextRun returns the first fire time of cronExpr (in timezone tz) after t.
+func NextRun(cronExpr, tz string, t time.Time) (time.Time, error) {
+	c, err := ParseCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timezone %q: %w", tz, err)
+	}
+	next := c.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron %q never fires", cronExpr)
+	}
+	return next, nil
+}
+
+// Scheduler fires due schedules: job schedules enqueue scan_jobs for the
+// workers, export schedules write a graph export file to scheduler.export_dir.
+type Scheduler struct {
+	st        *store.Store
+	interval  time.Duration
+	grace     time.Duration
+	exportDir string
+}
+
+func New(cfg *config.Config, dbc *db.DB) *Scheduler {
+	iv := time.Duration(cfg.Scheduler.IntervalSeconds) * time.Second
+	if iv <= 0 {
+		iv = 30 * time.Second
+	}
+	grace := time.Duration(cfg.Scheduler.MisfireGraceSeconds) * time.Second
+	if grace <= 0 {
+		grace = 5 * time.Minute
+	}
+	dir := cfg.Scheduler.ExportDir
+	if dir == "" {
+		dir = "./exports"
+	}
+	return &Scheduler{st: store.New(dbc), interval: iv, grace: grace, exportDir: dir}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("scheduler: started interval=%s misfire_grace=%s export_dir=%s", s.interval, s.grace, s.exportDir)
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	now := time.Now()
+	due, err := s.st.ClaimDueSchedules(ctx, func(sc *store.Schedule) store.ScheduleDecision {
+		return s.decide(sc, now)
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("scheduler: %v", err)
+		}
+		return
+	}
+	for i := range due {
+		s.fire(ctx, &due[i])
+	}
+}
+
+// decide computes the next run of a due schedule and applies its misfire policy.
+// Missed runs are coalesced: the next run is always the first one after now.
+func (s *Scheduler) decide(sc *store.Schedule, now time.Time) store.ScheduleDecision {
+	next, err := NextRun(sc.Cron, sc.Timezone, now)
+	if err != nil {
+		// Cannot happen for schedules created through Prepare; park it for a day.
+		return store.ScheduleDecision{Next: now.Add(24 * time.Hour), Note: err.Error()}
+	}
+	late := now.Sub(sc.NextRunAt)
+	if late > s.grace && sc.MisfirePolicy == store.MisfireSkip {
+		log.Printf("scheduler: schedule %s misfired (%s late), skipping to %s", sc.Name, late.Round(time.Second), next.Format(time.RFC3339))
+		return store.ScheduleDecision{Next: next, Note: fmt.Sprintf("misfire: %s late, skipped", late.Round(time.Second))}
+	}
+	if late > s.grace {
+		log.Printf("scheduler: schedule %s misfired (%s late), running once now", sc.Name, late.Round(time.Second))
+	}
+	return store.ScheduleDecision{Next: next, Fire: true}
+}
+
+func (s *Scheduler) fire(ctx context.Context, sc *store.Schedule) {
+	var jobID *int64
+	var output *string
+	var err error
+
+	switch sc.Action {
+	case store.ScheduleActionJob:
+		var id int64
+		id, err = s.st.EnqueueJob(ctx, sc.Spec.JobSpec())
+		if err == nil {
+			jobID = &id
+			log.Printf("scheduler: schedule %s enqueued %s job id=%d", sc.Name, sc.Spec.JobSpec().Kind, id)
+		}
+	case store.ScheduleActionExport:
+		var path string
+		path, err = s.export(ctx, sc)
+		if err == nil {
+			output = &path
+			log.Printf("scheduler: schedule %s wrote %s", sc.Name, path)
+		}
+	default:
+		err = fmt.Errorf("unknown action %q", sc.Action)
+	}
+	if err != nil {
+		log.Printf("scheduler: schedule %s error=%v", sc.Name, err)
+	}
+	if err2 := s.st.FinishScheduleRun(context.WithoutCancel(ctx), sc.ID, err, jobID, output); err2 != nil {
+		log.Printf("scheduler: %v", err2)
+	}
+}
+
+// export writes <export_dir>/<name>-<fire time>.<format>. The file is written
+// under a temporary name and renamed, so readers never see a partial export.
+func (s *Scheduler) export(ctx context.Context, sc *store.Schedule) (string, error) {
+	limit := sc.Spec.Limit
+	if limit <= 0 {
+		limit = 10000
+	}
+	b, ext, err := exporter.ExportGraph(ctx, s.st, sc.Spec.Format, limit)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.exportDir, 0o755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.%s", sc.Name, sc.NextRunAt.UTC().Format("20060102T150405Z"), ext)
+	path := filepath.Join(s.exportDir, name)
+	tmp, err := os.CreateTemp(s.exportDir, "."+strings.TrimSuffix(name, "."+ext)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,222 @@
+package scope
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+)
+
+// Policy decides whether keyspider is allowed to touch a host.
+//
+// Deny rules always win. If any allow rule is configured, a host must match at
+// least one allow rule to be in scope; with no allow rules everything not
+// denied is in scope.
+type Policy struct {
+	allowNets    []*net.IPNet
+	denyNets     []*net.IPNet
+	allowDomains []string
+	denyDomains  []string
+	allowRegex   []*regexp.Regexp
+	denyRegex    []*regexp.Regexp
+	allowTags    map[string]bool
+	denyTags     map[string]bool
+
+	resolveTimeout time.Duration
+}
+
+// Target is a host as seen by the spider or watcher.
+type Target struct {
+	Host string   // hostname, fqdn or IP literal
+	IPs  []string // known addresses (e.g. the source IP from a log line)
+	Tags []string // inventory tags
+}
+
+type Decision struct {
+	InScope bool
+	Reason  string
+}
+
+// New builds a Policy from cfg.Scope. Patterns are validated by config.Load.
+func New(cfg *config.Config) *Policy {
+	sc := cfg.Scope
+	p := &Policy{
+		allowNets:      parseCIDRs(sc.AllowCIDRs),
+		denyNets:       parseCIDRs(sc.DenyCIDRs),
+		allowDomains:   normDomains(sc.AllowDomains),
+		denyDomains:    normDomains(sc.DenyDomains),
+		allowRegex:     compileAll(sc.AllowHostnameRegex),
+		denyRegex:      compileAll(sc.DenyHostnameRegex),
+		allowTags:      tagSet(sc.AllowTags),
+		denyTags:       tagSet(sc.DenyTags),
+		resolveTimeout: 2 * time.Second,
+	}
+	return p
+}
+
+func (p *Policy) hasAllowRules() bool {
+	return len(p.allowNets) > 0 || len(p.allowDomains) > 0 || len(p.allowRegex) > 0 || len(p.allowTags) > 0
+}
+
+func (p *Policy) hasCIDRRules() bool {
+	return len(p.allowNets) > 0 || len(p.denyNets) > 0
+}
+
+// Check evaluates t against the policy. Hostnames are resolved (bounded by a
+// short timeout) only when CIDR rules exist and no IPs were supplied.
+func (p *Policy) Check(ctx context.Context, t Target) Decision {
+	if p == nil {
+		return Decision{InScope: true}
+	}
+	host := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(t.Host), "."))
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+
+	var ips []net.IP
+	for _, s := range t.IPs {
+		if ip := net.ParseIP(s); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else if len(ips) == 0 && p.hasCIDRRules() && host != "" {
+		ips = p.resolve(ctx, host)
+	}
+
+	// Deny rules first.
+	for _, ip := range ips {
+		for _, n := range p.denyNets {
+			if n.Contains(ip) {
+				return Decision{Reason: "deny_cidr " + n.String()}
+			}
+		}
+	}
+	if d, ok := matchDomain(host, p.denyDomains); ok {
+		return Decision{Reason: "deny_domain " + d}
+	}
+	for _, re := range p.denyRegex {
+		if re.MatchString(host) {
+			return Decision{Reason: "deny_hostname_regex " + re.String()}
+		}
+	}
+	for _, tag := range t.Tags {
+		if p.denyTags[tag] {
+			return Decision{Reason: "deny_tag " + tag}
+		}
+	}
+
+	if !p.hasAllowRules() {
+		return Decision{InScope: true}
+	}
+
+	for _, ip := range ips {
+		for _, n := range p.allowNets {
+			if n.Contains(ip) {
+				return Decision{InScope: true, Reason: "allow_cidr " + n.String()}
+			}
+		}
+	}
+	if d, ok := matchDomain(host, p.allowDomains); ok {
+		return Decision{InScope: true, Reason: "allow_domain " + d}
+	}
+	for _, re := range p.allowRegex {
+		if re.MatchString(host) {
+			return Decision{InScope: true, Reason: "allow_hostname_regex " + re.String()}
+		}
+	}
+	for _, tag := range t.Tags {
+		if p.allowTags[tag] {
+			return Decision{InScope: true, Reason: "allow_tag " + tag}
+		}
+	}
+	return Decision{Reason: "no allow rule matched"}
+}
+
+func (p *Policy) resolve(ctx context.Context, host string) []net.IP {
+	ctx2, cancel := context.WithTimeout(ctx, p.resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx2, host)
+	if err != nil {
+		return nil
+	}
+	out := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, a.IP)
+	}
+	return out
+}
+
+func matchDomain(host string, suffixes []string) (string, bool) {
+	if host == "" {
+		return "", false
+	}
+	for _, d := range suffixes {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func parseCIDRs(in []string) []*net.IPNet {
+	var out []*net.IPNet
+	for _, s := range in {
+		if n, err := ParseCIDR(s); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// ParseCIDR accepts a CIDR or a bare IP (treated as a single-address network).
+func ParseCIDR(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		if ip := net.ParseIP(s); ip != nil {
+			if ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
+
+func normDomains(in []string) []string {
+	var out []string
+	for _, d := range in {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		d = strings.TrimPrefix(d, "*.")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func compileAll(in []string) []*regexp.Regexp {
+	var out []*regexp.Regexp
+	for _, s := range in {
+		if re, err := regexp.Compile(s); err == nil {
+			out = append(out, re)
+		}
+	}
+	return out
+}
+
+func tagSet(in []string) map[string]bool {
+	m := map[string]bool{}
+	for _, t := range in {
+		if t = strings.TrimSpace(t); t != "" {
+			m[t] = true
+		}
+	}
+	return m
+}
@@ -8,22 +8,18 @@ import (
 	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/keys"
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
 
-func (s *Spider) scanAuthorizedKeysAndPersist(ctx context.Context, hostID int64, host string) (int, error) {
+func (s *Spider) scanAuthorizedKeysAndPersist(ctx context.Context, hostID int64, host string, osType string) (int, error) {
 	// Pull authorized_keys and persist as:
 	// - ssh_keys (fingerprint)
 	// - key_instances (authorized_key)
-	cmd := `sh -lc '
-set -e
-for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys; do
-  [ -r "$f" ] || continue
-  echo "---FILE $f"
-  cat "$f"
-  echo
-done
-'`
+	cmd, err := s.cmds.Render(osType, remotecmd.AuthorizedKeys, nil)
+	if err != nil {
+		return 0, err
+	}
 	out, err := s.ssh.Run(ctx, host, cmd)
 	if err != nil {
 		return 0, err
@@ -50,8 +46,14 @@ done
 		if err != nil {
 			return count, err
 		}
+		if comment != "" {
+			if _, err := s.ids.Derive(ctx, s.store, k.FP256, comment); err != nil {
+				return count, err
+			}
+		}
 		ki := &store.KeyInstance{
 			HostID:       hostID,
+			Username:     accountOf(currentPath),
 			Path:         currentPath,
 			KeyID:        &kid,
 			InstanceType: "authorized_key",
@@ -66,3 +68,20 @@ done
 	}
 	return count, nil
 }
+
+// accountOf returns the account an authorized_keys file belongs to, from its
+// path (/root/..., /home/<user>/..., /export/home/<user>/...), or nil.
+func accountOf(path string) *string {
+	if strings.HasPrefix(path, "/root/") {
+		return ptr("root")
+	}
+	_, rest, ok := strings.Cut(path, "/home/")
+	if !ok {
+		return nil
+	}
+	user, _, ok := strings.Cut(rest, "/")
+	if !ok || user == "" {
+		return nil
+	}
+	return &user
+}
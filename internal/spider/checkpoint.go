@@ -0,0 +1,74 @@
+package spider
+
+import (
+	"context"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// frontier holds the BFS queue, visited set and per-host phase of a scan.
+// dbFrontier persists it in scan_job_hosts so a restarted worker resumes a job
+// where it stopped; memFrontier is used for one-shot CLI scans.
+type frontier interface {
+	// add enqueues host unless the scan already reached it. parent is the host
+	// whose logs named it (nil for the scan target).
+	add(ctx context.Context, host string, depth int, parent *string) error
+	// next returns the next unfinished host, or nil when the scan is complete.
+	next(ctx context.Context) (*store.ScanJobHost, error)
+	// save checkpoints h after a phase change.
+	save(ctx context.Context, h *store.ScanJobHost) error
+}
+
+type dbFrontier struct {
+	st    *store.Store
+	jobID int64
+}
+
+func (f *dbFrontier) add(ctx context.Context, host string, depth int, parent *string) error {
+	return f.st.AddScanJobHost(ctx, f.jobID, host, depth, parent)
+}
+
+func (f *dbFrontier) next(ctx context.Context) (*store.ScanJobHost, error) {
+	return f.st.NextScanJobHost(ctx, f.jobID)
+}
+
+func (f *dbFrontier) save(ctx context.Context, h *store.ScanJobHost) error {
+	return f.st.SaveScanJobHost(ctx, h)
+}
+
+type memFrontier struct {
+	hosts   []*store.ScanJobHost
+	visited map[string]bool
+}
+
+func newMemFrontier() *memFrontier {
+	return &memFrontier{visited: map[string]bool{}}
+}
+
+func (f *memFrontier) add(_ context.Context, host string, depth int, parent *string) error {
+	if f.visited[host] {
+		return nil
+	}
+	f.visited[host] = true
+	f.hosts = append(f.hosts, &store.ScanJobHost{ID: int64(len(f.hosts) + 1), Host: host, Parent: parent, Depth: depth, Phase: store.PhaseQueued})
+	return nil
+}
+
+func (f *memFrontier) next(context.Context) (*store.ScanJobHost, error) {
+	for _, h := range f.hosts {
+		if !terminalPhase(h.Phase) {
+			return h, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *memFrontier) save(context.Context, *store.ScanJobHost) error { return nil }
+
+func terminalPhase(p string) bool {
+	switch p {
+	case store.PhaseDone, store.PhaseUnreachable, store.PhaseOutOfScope:
+		return true
+	}
+	return false
+}
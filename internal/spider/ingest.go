@@ -3,16 +3,17 @@ package spider
 import (
 	"bufio"
 	"context"
-	"net"
 	"strings"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/parsers"
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
 
 func (s *Spider) ingestLogs(ctx context.Context, destID int64, logText string, p *parsers.LinuxSSHDParser) (inserted int, edgesUp int, concerns int, sources []string) {
 	scanner := bufio.NewScanner(strings.NewReader(logText))
 	sourceSet := map[string]bool{}
+	addrSeen := map[string]bool{} // host_addresses rows touched by this ingest
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -40,17 +41,30 @@ func (s *Spider) ingestLogs(ctx context.Context, destID int64, logText string, p
 
 		// DNS enrichment (reverse lookup) into source_host label.
 		srcLabel := ""
+		var names *resolver.Names
 		if s.cfg.Discovery.DNS.Enabled && ev.SourceIP != "" {
-			if names, _ := net.LookupAddr(ev.SourceIP); len(names) > 0 {
-				srcLabel = strings.TrimSuffix(names[0], ".")
+			n := s.dns.Reverse(ctx, ev.SourceIP)
+			names = &n
+			if name := s.dns.Name(n); name != "" {
+				srcLabel = name
 				storeEv.SourceHost = &srcLabel
 			}
 		}
 		if srcLabel == "" {
 			srcLabel = ev.SourceIP
 		}
+		recordAddr := func(hostID *int64) {
+			if names == nil || addrSeen[ev.SourceIP] {
+				return
+			}
+			addrSeen[ev.SourceIP] = true
+			if storeEv.SourceHost == nil {
+				hostID = nil
+			}
+			_ = s.store.RecordAddress(ctx, ev.SourceIP, hostID, names.PTR, names.FCrDNS())
+		}
 
-		id, err := s.store.InsertAccessEvent(ctx, storeEv)
+		_, err := s.store.InsertAccessEvent(ctx, storeEv)
 		if err != nil {
 			// best effort: keep going
 			continue
@@ -59,20 +73,32 @@ func (s *Spider) ingestLogs(ctx context.Context, destID int64, logText string, p
 
 		// Edge
 		var srcHostID *int64
+		if srcLabel != "" && !s.checkScope(ctx, srcLabel, ev.SourceIP).InScope {
+			// Record the edge, but never probe or follow an out-of-scope source.
+			if _, err := s.store.UpsertEdge(ctx, nil, srcLabel, destID, "log", 80, true); err == nil {
+				edgesUp++
+			}
+			recordAddr(nil)
+			continue
+		}
 		if srcLabel != "" {
 			// If DNS gives us a hostname, record it as a host and probe reachability.
 			if strings.Contains(srcLabel, ".") {
-				reach := s.ssh.CanConnect(ctx, srcLabel)
-				hid, _ := s.store.UpsertHost(ctx, srcLabel, &srcLabel, "linux", reach)
+				r := s.reach.Lookup(ctx, srcLabel)
+				hid, _ := s.store.UpsertHost(ctx, srcLabel, &srcLabel, "", r.Reachable)
 				srcHostID = &hid
-				if !reach {
-					concerns++
-					_, _ = s.store.InsertConcern(ctx, "high", "UNREACHABLE_SOURCE", &hid, nil, &id, "source seen in logs but not reachable from jump")
+				if !r.Reachable {
+					if _, created, err := s.store.EnsureOpenConcern(ctx, "high", "UNREACHABLE_SOURCE", &hid, "source seen in logs but not reachable from jump: "+r.Summary()); err == nil && created {
+						concerns++
+					}
+				} else {
+					_, _ = s.store.ResolveConcerns(ctx, "UNREACHABLE_SOURCE", hid)
 				}
 			}
-			if _, err := s.store.UpsertEdge(ctx, srcHostID, srcLabel, destID, "log", 80); err == nil {
+			if _, err := s.store.UpsertEdge(ctx, srcHostID, srcLabel, destID, "log", 80, false); err == nil {
 				edgesUp++
 			}
+			recordAddr(srcHostID)
 		}
 
 		if srcLabel != "" {
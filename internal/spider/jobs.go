@@ -0,0 +1,121 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// hostStep is the kind-specific work of a host-list job, run once the host is
+// known to be in scope and reachable.
+type hostStep func(ctx context.Context, h *store.ScanJobHost, hostID int64, osType string) error
+
+// AuthorizedKeysJob collects authorized_keys from each host, without reading logs or spidering.
+func (s *Spider) AuthorizedKeysJob(ctx context.Context, jobID int64, hosts []string) error {
+	return s.eachHost(ctx, jobID, hosts, store.PhaseAuthorizedKeys, func(ctx context.Context, h *store.ScanJobHost, hostID int64, osType string) error {
+		n, err := s.scanAuthorizedKeysAndPersist(ctx, hostID, h.Host, osType)
+		h.Keys += n
+		return err
+	})
+}
+
+// KeyHuntJob runs the private key hunt on each host. It requires keyhunt.enabled.
+func (s *Spider) KeyHuntJob(ctx context.Context, jobID int64, hosts []string) error {
+	if !s.cfg.KeyHunt.Enabled {
+		return fmt.Errorf("keyhunt is disabled (keyhunt.enabled=false)")
+	}
+	return s.eachHost(ctx, jobID, hosts, store.PhaseKeyHunt, func(ctx context.Context, h *store.ScanJobHost, hostID int64, osType string) error {
+		return s.keyHunt(ctx, hostID, h.Host, osType)
+	})
+}
+
+// ConfigAuditJob collects and audits the sshd configuration of each host.
+func (s *Spider) ConfigAuditJob(ctx context.Context, jobID int64, hosts []string) error {
+	return s.eachHost(ctx, jobID, hosts, store.PhaseConfigAudit, func(ctx context.Context, h *store.ScanJobHost, hostID int64, osType string) error {
+		n, err := s.auditSSHDConfig(ctx, hostID, h.Host, osType)
+		h.Concerns += n
+		return err
+	})
+}
+
+// eachHost runs step on every host of a job, checkpointing in scan_job_hosts
+// like ScanJob does. A failure on one host is recorded on its progress row
+// and does not stop the job.
+func (s *Spider) eachHost(ctx context.Context, jobID int64, hosts []string, phase string, step hostStep) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("job has no hosts")
+	}
+	f := &dbFrontier{st: s.store, jobID: jobID}
+	for _, host := range hosts {
+		if err := f.add(ctx, host, 0, nil); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h, err := f.next(ctx)
+		if err != nil {
+			return err
+		}
+		if h == nil {
+			return nil
+		}
+
+		switch h.Phase {
+		case store.PhaseQueued:
+			if !s.checkScope(ctx, h.Host).InScope {
+				h.Phase = store.PhaseOutOfScope
+				break
+			}
+			reachable := s.reach.Check(ctx, h.Host)
+			osType := "linux"
+			if reachable {
+				osType = s.osd.OSType(ctx, h.Host)
+			}
+			id, err := s.store.UpsertHost(ctx, h.Host, nil, "", reachable)
+			if err != nil {
+				return err
+			}
+			h.OSType = &osType
+			if !reachable {
+				if _, created, err := s.store.EnsureOpenConcern(ctx, "high", "UNREACHABLE_HOST", &id, "jump server cannot ssh to host"); err == nil && created {
+					h.Concerns++
+				}
+				h.Phase = store.PhaseUnreachable
+				break
+			}
+			_, _ = s.store.ResolveConcerns(ctx, "UNREACHABLE_HOST", id)
+			h.Phase = phase
+
+		case phase:
+			osType := "linux"
+			if h.OSType != nil {
+				osType = *h.OSType
+			}
+			id, err := s.store.UpsertHost(ctx, h.Host, nil, "", true)
+			if err != nil {
+				return err
+			}
+			if err := step(ctx, h, id, osType); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				h.Errors = append(h.Errors, phase+": "+err.Error())
+			} else {
+				_ = s.store.MarkHostScanned(ctx, id)
+			}
+			h.Phase = store.PhaseDone
+
+		default:
+			return fmt.Errorf("job %d host %s: unexpected phase %q", jobID, h.Host, h.Phase)
+		}
+
+		if err := f.save(ctx, h); err != nil {
+			return err
+		}
+	}
+}
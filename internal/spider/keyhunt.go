@@ -8,22 +8,36 @@ import (
 	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/keys"
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
 
 // bestEffortKeyHunt tries to locate private key files on a source host (reachable from jump only).
 // It does NOT pull private key contents; it only records paths and (if ssh-keygen works) the derived public fingerprint.
+// Sources found while spidering are checked here; KeyHuntJob's hosts were checked by eachHost and go to keyHunt directly.
 func (s *Spider) bestEffortKeyHunt(ctx context.Context, sourceHost string) error {
-	if sourceHost == "" {
+	if sourceHost == "" || len(s.cfg.KeyHunt.AllowRoots) == 0 {
 		return nil
 	}
-	if !s.ssh.CanConnect(ctx, sourceHost) {
+	if !s.checkScope(ctx, sourceHost).InScope {
+		return nil
+	}
+	if !s.reach.Check(ctx, sourceHost) {
 		// unreachable sources are handled via concerns in ingestLogs.
 		return nil
 	}
 
-	hid, _ := s.store.UpsertHost(ctx, sourceHost, &sourceHost, "linux", true)
+	osType := s.osd.OSType(ctx, sourceHost)
+	hid, err := s.store.UpsertHost(ctx, sourceHost, &sourceHost, "", true)
+	if err != nil {
+		return err
+	}
+	return s.keyHunt(ctx, hid, sourceHost, osType)
+}
 
+// keyHunt is the hunt itself, for a host already known to be in scope and
+// reachable.
+func (s *Spider) keyHunt(ctx context.Context, hid int64, sourceHost, osType string) error {
 	roots := s.cfg.KeyHunt.AllowRoots
 	if len(roots) == 0 {
 		return nil
@@ -32,23 +46,15 @@ func (s *Spider) bestEffortKeyHunt(ctx context.Context, sourceHost string) error
 	// Find likely key files with a bounded search.
 	// We intentionally avoid scanning arbitrary paths outside allow_roots.
 	// Output format: one path per line.
-	findExpr := ""
-	for i, r := range roots {
-		if i > 0 {
-			findExpr += " "
-		}
-		findExpr += fmt.Sprintf("%q", r)
+	cmd, err := s.cmds.Render(osType, remotecmd.KeyHuntFind, remotecmd.Params{
+		"roots":     roots,
+		"max_depth": s.cfg.KeyHunt.MaxDepth,
+		"max_files": s.cfg.KeyHunt.MaxFiles,
+	})
+	if err != nil {
+		return err
 	}
 
-	cmd := fmt.Sprintf(`sh -lc '
-set -e
-roots=%s
-# Candidate filenames + basic sanity: regular file, not huge.
-find $roots -xdev -maxdepth %d -type f \
-  \( -name "id_rsa" -o -name "id_ed25519" -o -name "id_ecdsa" -o -name "identity" -o -name "*.pem" -o -name "id_*" \) \
-  -size -2M 2>/dev/null | head -n %d
-'`, findExpr, s.cfg.KeyHunt.MaxDepth, s.cfg.KeyHunt.MaxFiles)
-
 	out, err := s.ssh.Run(ctx, sourceHost, cmd)
 	if err != nil {
 		return err
@@ -64,10 +70,10 @@ find $roots -xdev -maxdepth %d -type f \
 		// Verify it looks like a private key without exfiltrating it.
 		// If it is, derive public key via ssh-keygen -y, then compute fingerprint locally via sshd-style tools later.
 		// Here we store the public key string returned.
-		deriveCmd := fmt.Sprintf(`sh -lc 'set -e; 
-if head -n 1 %q | grep -q "BEGIN OPENSSH PRIVATE KEY"; then echo "PRIV"; 
-  ssh-keygen -y -f %q 2>/dev/null | sed -e "s/[[:space:]]\+$//"; 
-else exit 0; fi'`, path, path)
+		deriveCmd, err := s.cmds.Render(osType, remotecmd.KeyHuntDerive, remotecmd.Params{"path": path})
+		if err != nil {
+			continue
+		}
 		derived, derr := s.ssh.Run(ctx, sourceHost, deriveCmd)
 		if derr != nil {
 			// Still record the path as a potential key file.
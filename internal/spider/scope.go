@@ -0,0 +1,23 @@
+package spider
+
+import (
+	"context"
+	"log"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/scope"
+)
+
+// checkScope must be called before any probe (reachability, OS detection, key hunt) touches host.
+// ips are addresses already known for the host (e.g. the log source IP).
+func (s *Spider) checkScope(ctx context.Context, host string, ips ...string) scope.Decision {
+	tags, _ := s.store.HostTags(ctx, host)
+	if len(ips) == 0 {
+		// Inventory addresses spare a DNS lookup for CIDR rules.
+		ips, _ = s.store.HostIPs(ctx, host)
+	}
+	d := s.scope.Check(ctx, scope.Target{Host: host, IPs: ips, Tags: tags})
+	if !d.InScope {
+		log.Printf("spider: %s out of scope (%s)", host, d.Reason)
+	}
+	return d
+}
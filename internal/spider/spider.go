@@ -7,7 +7,13 @@ import (
 
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/identity"
+	"github.com/jsherman999/openclaw_keyspider/internal/osdetect"
 	"github.com/jsherman999/openclaw_keyspider/internal/parsers"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
+	"github.com/jsherman999/openclaw_keyspider/internal/scope"
 	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
@@ -17,105 +23,217 @@ type Spider struct {
 	db    *db.DB
 	store *store.Store
 	ssh   *sshclient.Client
+	scope *scope.Policy
+	cmds  *remotecmd.Catalog
+	reach *reach.Prober
+	dns   *resolver.Resolver
+	ids   *identity.Deriver
+	osd   *osdetect.Detector
 }
 
 type ScanResult struct {
-	EventsInserted int
-	KeysSeen       int
-	HostsVisited   int
-	EdgesUpserted  int
-	ConcernsRaised int
+	EventsInserted int `json:"events_inserted"`
+	KeysSeen       int `json:"keys_seen"`
+	HostsVisited   int `json:"hosts_visited"`
+	EdgesUpserted  int `json:"edges_upserted"`
+	ConcernsRaised int `json:"concerns_raised"`
 }
 
-func New(cfg *config.Config, dbc *db.DB) *Spider {
-	return &Spider{cfg: cfg, db: dbc, store: store.New(dbc), ssh: sshclient.New(cfg)}
+// New returns a spider probing hosts through rp.
+func New(cfg *config.Config, dbc *db.DB, rp *reach.Prober) *Spider {
+	st := store.New(dbc)
+	ssh := sshclient.NewAudited(cfg, st)
+	cmds := remotecmd.MustNew(cfg.Commands.Overrides)
+	return &Spider{cfg: cfg, db: dbc, store: st, ssh: ssh, scope: scope.New(cfg), cmds: cmds, reach: rp, dns: resolver.New(cfg), ids: identity.NewDeriver(cfg), osd: osdetect.New(st, ssh, cmds)}
 }
 
+// ScanHost scans destHost and spiders out to its sources, keeping the BFS state in memory.
 func (s *Spider) ScanHost(ctx context.Context, destHost string, since time.Duration, spiderDepth int) (*ScanResult, error) {
+	return s.scan(ctx, newMemFrontier(), destHost, since, spiderDepth)
+}
+
+// ScanJob is ScanHost for a scan_jobs row: the frontier, visited set and
+// per-host phase are checkpointed in scan_job_hosts, so calling it again for
+// the same job resumes where the previous run stopped.
+//
+// Progress is recorded per host as it runs; the returned result totals every
+// run of the job, not just this one.
+func (s *Spider) ScanJob(ctx context.Context, jobID int64, destHost string, since time.Duration, spiderDepth int) (*ScanResult, error) {
+	if _, err := s.scan(ctx, &dbFrontier{st: s.store, jobID: jobID}, destHost, since, spiderDepth); err != nil {
+		return nil, err
+	}
+	t, err := s.store.ScanJobTotals(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &ScanResult{
+		EventsInserted: t.EventsInserted,
+		KeysSeen:       t.KeysSeen,
+		HostsVisited:   t.HostsVisited,
+		EdgesUpserted:  t.EdgesUpserted,
+		ConcernsRaised: t.ConcernsRaised,
+	}, nil
+}
+
+func (s *Spider) scan(ctx context.Context, f frontier, destHost string, since time.Duration, spiderDepth int) (*ScanResult, error) {
 	// Phase 2: BFS spider expansion from the jump server only.
 	// The only "identity" resolution is DNS (reverse + forward best-effort).
-	type item struct {
-		host  string
-		depth int
+	if d := s.checkScope(ctx, destHost); !d.InScope {
+		return nil, fmt.Errorf("host %s is out of scope: %s", destHost, d.Reason)
+	}
+	if err := f.add(ctx, destHost, 0, nil); err != nil {
+		return nil, err
 	}
-	queue := []item{{host: destHost, depth: 0}}
-	visited := map[string]bool{}
 
 	res := &ScanResult{}
-	for len(queue) > 0 {
-		it := queue[0]
-		queue = queue[1:]
-		if visited[it.host] {
-			continue
-		}
-		visited[it.host] = true
-		res.HostsVisited++
-
-		// Determine reachability from jump server.
-		reachable := s.ssh.CanConnect(ctx, it.host)
-
-		osType := "linux"
-		if reachable {
-			osType = s.detectOSType(ctx, it.host)
-		}
-		destID, err := s.store.UpsertHost(ctx, it.host, nil, osType, reachable)
+	for {
+		h, err := f.next(ctx)
 		if err != nil {
 			return nil, err
 		}
-		if !reachable {
-			res.ConcernsRaised++
-			_, _ = s.store.InsertConcern(ctx, "high", "UNREACHABLE_HOST", &destID, nil, nil, "jump server cannot ssh to host")
-			continue
+		if h == nil {
+			break
 		}
-
-		logText, err := s.fetchSSHDLogs(ctx, it.host, osType, since)
-		if err != nil {
+		if err := s.scanOne(ctx, f, h, since, spiderDepth, res); err != nil {
+			// Keep the failure on the host's progress row; the checkpoint stays at the failed phase.
+			h.Errors = append(h.Errors, h.Phase+": "+err.Error())
+			_ = f.save(context.WithoutCancel(ctx), h)
 			return nil, err
 		}
+	}
+	return res, nil
+}
 
-		p := parsers.NewLinuxSSHDParser(time.Now)
-		inserted, edgesUp, concerns, sources := s.ingestLogs(ctx, destID, logText, p)
-		res.EventsInserted += inserted
-		res.EdgesUpserted += edgesUp
-		res.ConcernsRaised += concerns
+// scanOne advances h through the pipeline (probe, logs, authorized_keys,
+// keyhunt), checkpointing after each phase. A host interrupted mid-pipeline
+// resumes at the phase it was in.
+func (s *Spider) scanOne(ctx context.Context, f frontier, h *store.ScanJobHost, since time.Duration, spiderDepth int, res *ScanResult) error {
+	osType := "linux"
+	if h.OSType != nil {
+		osType = *h.OSType
+	}
+	var destID int64
 
-		keysSeen, err := s.scanAuthorizedKeysAndPersist(ctx, destID, it.host)
-		if err != nil {
-			return nil, err
+	for !terminalPhase(h.Phase) {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		res.KeysSeen += keysSeen
 
-		// Key hunt for sources (private key locations only; no key contents stored).
-		// Note: This is best-effort and bounded by allow_roots.
-		if s.cfg.KeyHunt.Enabled {
-			for _, src := range sources {
-				_ = s.bestEffortKeyHunt(ctx, src)
+		switch h.Phase {
+		case store.PhaseQueued:
+			// Sources are filtered in ingestLogs, but never probe anything the policy forbids.
+			if h.Depth > 0 && !s.checkScope(ctx, h.Host).InScope {
+				h.Phase = store.PhaseOutOfScope
+				break
 			}
-		}
+			res.HostsVisited++
 
-		if it.depth < spiderDepth {
-			for _, src := range sources {
-				if src == "" {
-					continue
+			// Determine reachability from jump server.
+			reachable := s.reach.Check(ctx, h.Host)
+			if reachable {
+				osType = s.osd.OSType(ctx, h.Host)
+			}
+			id, err := s.store.UpsertHost(ctx, h.Host, nil, "", reachable)
+			if err != nil {
+				return err
+			}
+			destID = id
+			h.OSType = &osType
+			if !reachable {
+				if _, created, err := s.store.EnsureOpenConcern(ctx, "high", "UNREACHABLE_HOST", &destID, "jump server cannot ssh to host"); err == nil && created {
+					res.ConcernsRaised++
+					h.Concerns++
+				}
+				h.Phase = store.PhaseUnreachable
+				break
+			}
+			_, _ = s.store.ResolveConcerns(ctx, "UNREACHABLE_HOST", destID)
+			h.Phase = store.PhaseLogs
+
+		case store.PhaseLogs:
+			if destID == 0 {
+				id, err := s.store.UpsertHost(ctx, h.Host, nil, "", true)
+				if err != nil {
+					return err
 				}
-				queue = append(queue, item{host: src, depth: it.depth + 1})
+				destID = id
 			}
+			logText, err := s.fetchSSHDLogs(ctx, h.Host, osType, since)
+			if err != nil {
+				return err
+			}
+			_ = s.store.MarkHostScanned(ctx, destID)
+
+			p := parsers.NewLinuxSSHDParser(time.Now)
+			inserted, edgesUp, concerns, sources := s.ingestLogs(ctx, destID, logText, p)
+			res.EventsInserted += inserted
+			res.EdgesUpserted += edgesUp
+			res.ConcernsRaised += concerns
+			h.Events += inserted
+			h.Edges += edgesUp
+			h.Concerns += concerns
+			h.Sources = sources
+
+			if h.Depth < spiderDepth {
+				for _, src := range sources {
+					if src == "" {
+						continue
+					}
+					if err := f.add(ctx, src, h.Depth+1, &h.Host); err != nil {
+						return err
+					}
+				}
+			}
+			h.Phase = store.PhaseAuthorizedKeys
+
+		case store.PhaseAuthorizedKeys:
+			if destID == 0 {
+				id, err := s.store.UpsertHost(ctx, h.Host, nil, "", true)
+				if err != nil {
+					return err
+				}
+				destID = id
+			}
+			keysSeen, err := s.scanAuthorizedKeysAndPersist(ctx, destID, h.Host, osType)
+			if err != nil {
+				return err
+			}
+			res.KeysSeen += keysSeen
+			h.Keys += keysSeen
+			h.Phase = store.PhaseKeyHunt
+
+		case store.PhaseKeyHunt:
+			// Key hunt for sources (private key locations only; no key contents stored).
+			// Note: This is best-effort and bounded by allow_roots.
+			if s.cfg.KeyHunt.Enabled {
+				for _, src := range h.Sources {
+					if err := s.bestEffortKeyHunt(ctx, src); err != nil {
+						h.Errors = append(h.Errors, "keyhunt "+src+": "+err.Error())
+					}
+				}
+			}
+			h.Phase = store.PhaseDone
+
+		default:
+			return fmt.Errorf("scan host %s: unknown phase %q", h.Host, h.Phase)
 		}
-	}
 
-	return res, nil
+		if err := f.save(ctx, h); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Spider) fetchSSHDLogs(ctx context.Context, host string, osType string, since time.Duration) (string, error) {
-	// Prefer journalctl if available; otherwise fall back to common files.
-	sinceArg := fmt.Sprintf("--since '%dm'", int(since.Minutes()))
-	if osType == "aix" {
-		// AIX commonly logs via syslog; exact location depends on configuration.
-		// Best-effort: try authlog, then syslog.
-		cmd := "sh -lc \"(test -r /var/adm/ras/authlog && tail -n 20000 /var/adm/ras/authlog) || (test -r /var/adm/messages && tail -n 20000 /var/adm/messages) || (test -r /var/log/messages && tail -n 20000 /var/log/messages)\""
-		return s.ssh.Run(ctx, host, cmd)
+	// Prefer journalctl if available; otherwise fall back to the OS's auth log files.
+	cmd, err := s.cmds.Render(osType, remotecmd.SSHDLogs, remotecmd.Params{
+		"since":     fmt.Sprintf("-%dmin", int(since.Minutes())),
+		"max_lines": 20000,
+	})
+	if err != nil {
+		return "", err
 	}
-	cmd := "sh -lc \"(command -v journalctl >/dev/null 2>&1 && journalctl -u ssh -u sshd " + sinceArg + " --no-pager) || (test -r /var/log/secure && tail -n 20000 /var/log/secure) || (test -r /var/log/auth.log && tail -n 20000 /var/log/auth.log)\""
 	return s.ssh.Run(ctx, host, cmd)
 }
 
@@ -0,0 +1,127 @@
+package spider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
+)
+
+// parseSSHDConfig parses `sshd -T` output or a raw sshd_config into lowercase
+// keyword -> value. As in sshd, the first occurrence of a keyword wins; Match
+// blocks are ignored.
+func parseSSHDConfig(text string) (source string, settings map[string]string) {
+	settings = map[string]string{}
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "---SOURCE ") {
+			source = strings.TrimPrefix(line, "---SOURCE ")
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kw, val, _ := strings.Cut(line, " ")
+		if i := strings.IndexAny(kw, "=\t"); i >= 0 {
+			kw, val = kw[:i], kw[i+1:]+" "+val
+		}
+		kw = strings.ToLower(kw)
+		if kw == "match" {
+			break
+		}
+		if _, seen := settings[kw]; !seen {
+			settings[kw] = strings.TrimSpace(strings.TrimLeft(val, "= \t"))
+		}
+	}
+	return source, settings
+}
+
+// sshdConcernTypes are the concern types auditSSHDSettings can raise; any
+// that no longer apply to a host are resolved after each audit.
+var sshdConcernTypes = []string{
+	"SSHD_PERMIT_ROOT_LOGIN",
+	"SSHD_PASSWORD_AUTH",
+	"SSHD_PERMIT_EMPTY_PASSWORDS",
+	"SSHD_AUTHORIZED_KEYS_COMMAND",
+	"SSHD_NONSTANDARD_AUTHORIZED_KEYS_FILE",
+}
+
+type sshdFinding struct {
+	severity string
+	ctype    string
+	details  string
+}
+
+// auditSSHDSettings flags sshd settings that matter for key-based access.
+func auditSSHDSettings(st map[string]string) []sshdFinding {
+	var out []sshdFinding
+	get := func(k string) string { return strings.ToLower(st[k]) }
+
+	if v := get("permitrootlogin"); v == "yes" {
+		out = append(out, sshdFinding{"medium", "SSHD_PERMIT_ROOT_LOGIN", "PermitRootLogin yes (root may log in with a password)"})
+	}
+	if get("passwordauthentication") == "yes" {
+		out = append(out, sshdFinding{"medium", "SSHD_PASSWORD_AUTH", "PasswordAuthentication yes"})
+	}
+	if get("permitemptypasswords") == "yes" {
+		out = append(out, sshdFinding{"high", "SSHD_PERMIT_EMPTY_PASSWORDS", "PermitEmptyPasswords yes"})
+	}
+	if v := st["authorizedkeyscommand"]; v != "" && !strings.EqualFold(v, "none") {
+		out = append(out, sshdFinding{"info", "SSHD_AUTHORIZED_KEYS_COMMAND", "AuthorizedKeysCommand " + v + " (keys may not be visible in authorized_keys files)"})
+	}
+	if v := st["authorizedkeysfile"]; v != "" {
+		for _, f := range strings.Fields(v) {
+			if f != ".ssh/authorized_keys" && f != ".ssh/authorized_keys2" && f != "%h/.ssh/authorized_keys" && f != "%h/.ssh/authorized_keys2" {
+				out = append(out, sshdFinding{"info", "SSHD_NONSTANDARD_AUTHORIZED_KEYS_FILE", "AuthorizedKeysFile " + v + " (not covered by the authorized_keys scan)"})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// auditSSHDConfig collects the sshd configuration of host, stores it and
+// raises one open concern per risky setting, resolving concerns for settings
+// that have since been fixed. It returns the number of new concerns.
+func (s *Spider) auditSSHDConfig(ctx context.Context, hostID int64, host, osType string) (int, error) {
+	cmd, err := s.cmds.Render(osType, remotecmd.SSHDConfig, nil)
+	if err != nil {
+		return 0, err
+	}
+	out, err := s.ssh.Run(ctx, host, cmd)
+	if err != nil {
+		return 0, err
+	}
+	source, settings := parseSSHDConfig(out)
+	if len(settings) == 0 {
+		return 0, fmt.Errorf("no sshd settings found")
+	}
+	if err := s.store.UpsertSSHDConfig(ctx, hostID, source, settings); err != nil {
+		return 0, err
+	}
+
+	raised := 0
+	found := map[string]bool{}
+	for _, f := range auditSSHDSettings(settings) {
+		found[f.ctype] = true
+		_, created, err := s.store.EnsureOpenConcern(ctx, f.severity, f.ctype, &hostID, f.details)
+		if err != nil {
+			return raised, err
+		}
+		if created {
+			raised++
+		}
+	}
+	for _, ct := range sshdConcernTypes {
+		if found[ct] {
+			continue
+		}
+		if _, err := s.store.ResolveConcerns(ctx, ct, hostID); err != nil {
+			return raised, err
+		}
+	}
+	return raised, nil
+}
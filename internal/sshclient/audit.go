@@ -0,0 +1,70 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+type initiatorKey struct{}
+
+// WithInitiator tags ctx with who asked for remote commands (e.g. "scan_job:42",
+// "watcher:host1", "cli:scan"). It is recorded on every ssh_audit row.
+func WithInitiator(ctx context.Context, initiator string) context.Context {
+	return context.WithValue(ctx, initiatorKey{}, initiator)
+}
+
+func initiatorFrom(ctx context.Context) string {
+	if v, ok := ctx.Value(initiatorKey{}).(string); ok && v != "" {
+		return v
+	}
+	return "unknown"
+}
+
+// EnableAudit makes every Run/Stream call append a row to ssh_audit.
+func (c *Client) EnableAudit(st *store.Store, hashChain bool) {
+	c.audit = st
+	c.auditChain = hashChain
+}
+
+func (c *Client) record(ctx context.Context, host, mode, remoteCmd string, started time.Time, bytes int64, runErr error) {
+	if c.audit == nil {
+		return
+	}
+	a := &store.SSHAudit{
+		TS:            started.UTC(),
+		Host:          host,
+		Command:       remoteCmd,
+		Initiator:     initiatorFrom(ctx),
+		Mode:          mode,
+		ExitStatus:    exitStatus(runErr),
+		DurationMS:    time.Since(started).Milliseconds(),
+		BytesReturned: bytes,
+	}
+	if runErr != nil {
+		msg := runErr.Error()
+		a.Error = &msg
+	}
+	// Audit rows must be written even when the caller's context was cancelled.
+	actx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, err := c.audit.InsertSSHAudit(actx, a, c.auditChain); err != nil {
+		log.Printf("ssh audit: %v", err)
+	}
+}
+
+// exitStatus returns the remote exit code, 0 on success and -1 when ssh did not run.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
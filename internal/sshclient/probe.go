@@ -0,0 +1,82 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Reasons a reachability probe failed, recorded in reach_history.reason.
+const (
+	ReasonDNS              = "dns_failure"
+	ReasonTCPRefused       = "tcp_refused"
+	ReasonTimeout          = "timeout"
+	ReasonNetUnreachable   = "network_unreachable"
+	ReasonAuthDenied       = "auth_denied"       // no key was accepted
+	ReasonHostKeyMismatch  = "host_key_mismatch" // unknown or changed host key
+	ReasonPermissionDenied = "permission_denied" // authenticated, but the session was refused
+	ReasonError            = "error"
+)
+
+// ProbeResult is the outcome of one connectivity check.
+type ProbeResult struct {
+	OK       bool
+	Reason   string // empty when OK
+	Detail   string // ssh's own message
+	Duration time.Duration
+}
+
+// Probe runs a no-op command on host and classifies any failure.
+func (c *Client) Probe(ctx context.Context, host string) ProbeResult {
+	ctx2, cancel := context.WithTimeout(ctx, c.cfg.SSH.ConnectTimeout)
+	defer cancel()
+	started := time.Now()
+	_, err := c.Run(ctx2, host, "true")
+	res := ProbeResult{OK: err == nil, Duration: time.Since(started)}
+	if err != nil {
+		res.Detail = err.Error()
+		res.Reason = classifyProbeError(err)
+		if errors.Is(ctx2.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			res.Reason = ReasonTimeout
+		}
+	}
+	return res
+}
+
+// classifyProbeError maps ssh's stderr (carried in err) to a Reason.
+func classifyProbeError(err error) string {
+	msg := strings.ToLower(err.Error())
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("host key verification failed", "remote host identification has changed", "host key for", "host key is known", "no matching host key type"):
+		return ReasonHostKeyMismatch
+	case has("could not resolve hostname", "name or service not known", "temporary failure in name resolution", "nodename nor servname"):
+		return ReasonDNS
+	case has("connection refused"):
+		return ReasonTCPRefused
+	case has("timed out", "timeout"):
+		return ReasonTimeout
+	case has("no route to host", "network is unreachable"):
+		return ReasonNetUnreachable
+	case has("permission denied (", "too many authentication failures", "no supported authentication methods"):
+		return ReasonAuthDenied
+	case has("account is currently not available", "not allowed", "administratively prohibited", "account has expired", "permission denied"):
+		return ReasonPermissionDenied
+	}
+	// ssh exits 255 for its own errors; any other status came from the remote
+	// side after authentication (a nologin shell, a forced command).
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() != 255 && ee.ExitCode() != -1 {
+		return ReasonPermissionDenied
+	}
+	return ReasonError
+}
@@ -4,47 +4,100 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
 
 type Client struct {
 	cfg *config.Config
+
+	audit      *store.Store
+	auditChain bool
+
+	ctlOnce sync.Once
+	ctlDir  string
 }
 
 func New(cfg *config.Config) *Client { return &Client{cfg: cfg} }
 
+// NewAudited returns a client that records every command in ssh_audit when
+// audit.enabled is set.
+func NewAudited(cfg *config.Config, st *store.Store) *Client {
+	c := New(cfg)
+	if cfg.Audit.Enabled {
+		c.EnableAudit(st, cfg.Audit.HashChain)
+	}
+	return c
+}
+
 func (c *Client) CanConnect(ctx context.Context, host string) bool {
 	// Lightweight connectivity check.
-	ctx2, cancel := context.WithTimeout(ctx, c.cfg.SSH.ConnectTimeout)
-	defer cancel()
-	_, err := c.Run(ctx2, host, "true")
-	return err == nil
+	return c.Probe(ctx, host).OK
 }
 
 func (c *Client) Run(ctx context.Context, host string, remoteCmd string) (string, error) {
-	userHost := host
-	if c.cfg.SSH.User != "" && !strings.Contains(host, "@") {
-		userHost = c.cfg.SSH.User + "@" + host
-	}
-
-	args := []string{
-		"-o", "BatchMode=yes",
-		"-o", fmt.Sprintf("ConnectTimeout=%d", c.cfg.SSH.ConnectTimeoutSeconds),
-		userHost,
-		"--",
-		remoteCmd,
-	}
+	userHost, args := c.args(host, remoteCmd)
 
+	started := time.Now()
 	cmd := exec.CommandContext(ctx, "ssh", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	err := cmd.Run()
+	c.record(ctx, host, "run", remoteCmd, started, int64(stdout.Len()), err)
 	if err != nil {
 		return "", fmt.Errorf("ssh %s: %w: %s", userHost, err, strings.TrimSpace(stderr.String()))
 	}
 	return stdout.String(), nil
 }
+
+// args builds the ssh command line. With ssh.control_persist_seconds set,
+// every session to a host rides one master connection (the first one made),
+// so a watcher's stream and the probes of that host share a handshake.
+func (c *Client) args(host, remoteCmd string) (userHost string, args []string) {
+	userHost = host
+	if c.cfg.SSH.User != "" && !strings.Contains(host, "@") {
+		userHost = c.cfg.SSH.User + "@" + host
+	}
+
+	args = []string{
+		"-o", "BatchMode=yes",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", c.cfg.SSH.ConnectTimeoutSeconds),
+	}
+	if dir := c.controlDir(); dir != "" {
+		args = append(args,
+			"-o", "ControlMaster=auto",
+			"-o", "ControlPath="+filepath.Join(dir, "%C"),
+			"-o", fmt.Sprintf("ControlPersist=%d", c.cfg.SSH.ControlPersistSeconds),
+		)
+	}
+	return userHost, append(args, userHost, "--", remoteCmd)
+}
+
+// controlDir returns the directory for control sockets, creating it on first
+// use, or "" when multiplexing is off or the directory is unusable.
+func (c *Client) controlDir() string {
+	if c.cfg.SSH.ControlPersistSeconds <= 0 {
+		return ""
+	}
+	c.ctlOnce.Do(func() {
+		dir := c.cfg.SSH.ControlDir
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "keyspider-ssh")
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			log.Printf("ssh: control dir %s: %v; not multiplexing", dir, err)
+			return
+		}
+		c.ctlDir = dir
+	})
+	return c.ctlDir
+}
@@ -4,27 +4,31 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // Stream runs an SSH command and yields stdout lines to handler.
 // If handler returns false, the stream stops.
 func (c *Client) Stream(ctx context.Context, host string, remoteCmd string, handler func(line string) bool) error {
-	userHost := host
-	if c.cfg.SSH.User != "" && !strings.Contains(host, "@") {
-		userHost = c.cfg.SSH.User + "@" + host
-	}
+	return c.StreamRaw(ctx, host, remoteCmd, func(raw string) bool {
+		if handler == nil {
+			return true
+		}
+		return handler(strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r"))
+	})
+}
 
-	args := []string{
-		"-o", "BatchMode=yes",
-		"-o", fmt.Sprintf("ConnectTimeout=%d", c.cfg.SSH.ConnectTimeoutSeconds),
-		userHost,
-		"--",
-		remoteCmd,
-	}
+// StreamRaw is Stream with each line as sent, line ending included (the last
+// one may have none), for callers that count the bytes they were given.
+func (c *Client) StreamRaw(ctx context.Context, host string, remoteCmd string, handler func(raw string) bool) error {
+	userHost, args := c.args(host, remoteCmd)
 
+	started := time.Now()
 	cmd := exec.CommandContext(ctx, "ssh", args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -34,22 +38,36 @@ func (c *Client) Stream(ctx context.Context, host string, remoteCmd string, hand
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
+		c.record(ctx, host, "stream", remoteCmd, started, 0, err)
 		return fmt.Errorf("ssh start %s: %w: %s", userHost, err, strings.TrimSpace(stderr.String()))
 	}
 
-	s := bufio.NewScanner(stdout)
-	for s.Scan() {
-		if handler != nil {
-			if ok := handler(s.Text()); !ok {
+	var nbytes int64
+	var readErr error
+	br := bufio.NewReaderSize(stdout, bufio.MaxScanTokenSize)
+	for {
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			readErr = bufio.ErrTooLong
+			_ = cmd.Process.Kill()
+			break
+		}
+		nbytes += int64(len(line))
+		if len(line) > 0 && handler != nil {
+			if ok := handler(string(line)); !ok {
 				_ = cmd.Process.Kill()
 				break
 			}
 		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				readErr = err
+			}
+			break
+		}
 	}
 
-	_ = cmd.Wait()
-	if err := s.Err(); err != nil {
-		return err
-	}
-	return nil
+	waitErr := cmd.Wait()
+	c.record(ctx, host, "stream", remoteCmd, started, nbytes, waitErr)
+	return readErr
 }
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HostAddress is one run of sightings of an address as the same host.
+type HostAddress struct {
+	ID        int64     `json:"id"`
+	IP        string    `json:"ip"`
+	HostID    *int64    `json:"host_id"` // nil: reverse DNS gave no usable name
+	Hostname  *string   `json:"hostname"`
+	PTRNames  []string  `json:"ptr_names"`
+	FCrDNS    bool      `json:"fcrdns"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// RecordAddress notes that ip was just seen as hostID. The address's latest
+// row is extended if it names the same host; otherwise a new row starts, so
+// an address handed to another machine never merges the two.
+func (s *Store) RecordAddress(ctx context.Context, ip string, hostID *int64, ptrNames []string, fcrdns bool) error {
+	if ptrNames == nil {
+		ptrNames = []string{}
+	}
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('keyspider_addr:' || $1::text))`, ip); err != nil {
+		return fmt.Errorf("lock address: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+UPDATE host_addresses SET last_seen=now(), ptr_names=$3, fcrdns=$4
+WHERE id = (SELECT id FROM host_addresses WHERE ip=$1::text::inet ORDER BY last_seen DESC, id DESC LIMIT 1)
+  AND host_id IS NOT DISTINCT FROM $2
+`, ip, hostID, ptrNames, fcrdns)
+	if err != nil {
+		return fmt.Errorf("update host address: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO host_addresses(ip, host_id, ptr_names, fcrdns) VALUES ($1::text::inet, $2, $3, $4)
+`, ip, hostID, ptrNames, fcrdns); err != nil {
+			return fmt.Errorf("insert host address: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// ListHostAddresses returns the addresses hostID was seen at, latest first.
+func (s *Store) ListHostAddresses(ctx context.Context, hostID int64, limit int) ([]HostAddress, error) {
+	return s.listHostAddresses(ctx, `a.host_id=$1`, hostID, limit)
+}
+
+// AddressHistory returns the hosts ip belonged to, latest first.
+func (s *Store) AddressHistory(ctx context.Context, ip string, limit int) ([]HostAddress, error) {
+	return s.listHostAddresses(ctx, `a.ip=$1::text::inet`, ip, limit)
+}
+
+func (s *Store) listHostAddresses(ctx context.Context, where string, arg any, limit int) ([]HostAddress, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT a.id, host(a.ip), a.host_id, h.hostname, a.ptr_names, a.fcrdns, a.first_seen, a.last_seen
+FROM host_addresses a
+LEFT JOIN hosts h ON h.id = a.host_id
+WHERE `+where+`
+ORDER BY a.last_seen DESC, a.id DESC
+LIMIT $2
+`, arg, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list host addresses: %w", err)
+	}
+	defer rows.Close()
+	out := []HostAddress{}
+	for rows.Next() {
+		var a HostAddress
+		if err := rows.Scan(&a.ID, &a.IP, &a.HostID, &a.Hostname, &a.PTRNames, &a.FCrDNS, &a.FirstSeen, &a.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
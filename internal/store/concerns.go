@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Concern is a finding raised by a scan, a watcher or a findings refresh.
+type Concern struct {
+	ID            int64      `json:"id"`
+	Severity      string     `json:"severity"`
+	Type          string     `json:"type"`
+	HostID        *int64     `json:"host_id"`
+	Hostname      *string    `json:"hostname"`
+	KeyID         *int64     `json:"key_id"`
+	Fingerprint   *string    `json:"fingerprint_sha256"`
+	Owner         *string    `json:"owner"` // "alice@corp via laptop key", when known
+	AccessEventID *int64     `json:"access_event_id"`
+	Subject       *string    `json:"subject"`
+	Details       *string    `json:"details"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+}
+
+// ConcernFilter selects concerns; empty fields match everything.
+type ConcernFilter struct {
+	Types  []string
+	HostID int64
+	Status string // open (default), resolved or all
+	Limit  int
+}
+
+// ListConcerns returns concerns matching f, newest first.
+func (s *Store) ListConcerns(ctx context.Context, f ConcernFilter) ([]Concern, error) {
+	if f.Types == nil {
+		f.Types = []string{}
+	}
+	if f.Limit <= 0 {
+		f.Limit = 500
+	}
+	switch f.Status {
+	case "":
+		f.Status = "open"
+	case "open", "resolved", "all":
+	default:
+		return nil, fmt.Errorf("concern status must be open, resolved or all, not %q", f.Status)
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT c.id, c.severity, c.type, c.host_id, h.hostname, c.key_id, fp.fingerprint, o.attribution,
+  c.access_event_id, c.subject, c.details, c.created_at, c.resolved_at
+FROM concerns c
+LEFT JOIN hosts h ON h.id = c.host_id
+LEFT JOIN ssh_keys k ON k.id = c.key_id
+CROSS JOIN LATERAL (SELECT COALESCE(c.fingerprint_sha256, k.fingerprint_sha256) AS fingerprint) fp
+LEFT JOIN LATERAL (`+fmt.Sprintf(bestOwnerSQL, "fp.fingerprint")+`) o ON true
+WHERE (cardinality($1::text[]) = 0 OR c.type = ANY($1))
+  AND ($2::bigint = 0 OR c.host_id = $2)
+  AND ($3 = 'all' OR ($3 = 'open') = (c.resolved_at IS NULL))
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT $4
+`, f.Types, f.HostID, f.Status, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list concerns: %w", err)
+	}
+	defer rows.Close()
+	out := []Concern{}
+	for rows.Next() {
+		var c Concern
+		if err := rows.Scan(&c.ID, &c.Severity, &c.Type, &c.HostID, &c.Hostname, &c.KeyID, &c.Fingerprint, &c.Owner,
+			&c.AccessEventID, &c.Subject, &c.Details, &c.CreatedAt, &c.ResolvedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
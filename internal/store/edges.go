@@ -2,22 +2,28 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/jackc/pgx/v5"
 )
 
-func (s *Store) UpsertEdge(ctx context.Context, srcHostID *int64, srcLabel string, destHostID int64, evidenceType string, confidence int) (int64, error) {
+// UpsertEdge records srcLabel -> destHostID. outOfScope marks sources that the
+// scope policy forbids probing; they are recorded but never followed.
+func (s *Store) UpsertEdge(ctx context.Context, srcHostID *int64, srcLabel string, destHostID int64, evidenceType string, confidence int, outOfScope bool) (int64, error) {
 	var id int64
 	err := s.db.Pool.QueryRow(ctx, `
-INSERT INTO edges(src_host_id, src_label, dest_host_id, evidence_type, confidence, first_seen, last_seen)
-VALUES ($1,$2,$3,$4,$5, now(), now())
+INSERT INTO edges(src_host_id, src_label, dest_host_id, evidence_type, confidence, out_of_scope, first_seen, last_seen)
+VALUES ($1,$2,$3,$4,$5,$6, now(), now())
 ON CONFLICT (src_label, dest_host_id)
 DO UPDATE SET
   src_host_id=COALESCE(EXCLUDED.src_host_id, edges.src_host_id),
   evidence_type=EXCLUDED.evidence_type,
   confidence=GREATEST(edges.confidence, EXCLUDED.confidence),
+  out_of_scope=EXCLUDED.out_of_scope,
   last_seen=now()
 RETURNING id;
-`, srcHostID, srcLabel, destHostID, evidenceType, confidence).Scan(&id)
+`, srcHostID, srcLabel, destHostID, evidenceType, confidence, outOfScope).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("upsert edge: %w", err)
 	}
@@ -36,3 +42,53 @@ RETURNING id;
 	}
 	return id, nil
 }
+
+// EnsureOpenConcern raises a concern unless an unresolved one of the same type
+// already exists for the host. It returns the concern id and whether it was
+// created. Concurrent callers (watcher and spider, say) are serialized per
+// type and host so one outage yields one open concern.
+func (s *Store) EnsureOpenConcern(ctx context.Context, severity, ctype string, hostID *int64, details string) (int64, bool, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('keyspider_concern:' || $1 || ':' || COALESCE($2::bigint::text, '')))`, ctype, hostID); err != nil {
+		return 0, false, fmt.Errorf("lock concern: %w", err)
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+SELECT id FROM concerns
+WHERE type=$1 AND host_id IS NOT DISTINCT FROM $2 AND resolved_at IS NULL
+ORDER BY id LIMIT 1
+`, ctype, hostID).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, fmt.Errorf("find open concern: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+INSERT INTO concerns(severity, type, host_id, details)
+VALUES ($1,$2,$3,$4)
+RETURNING id;
+`, severity, ctype, hostID, details).Scan(&id)
+	if err != nil {
+		return 0, false, fmt.Errorf("insert concern: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("commit: %w", err)
+	}
+	return id, true, nil
+}
+
+// ResolveConcerns resolves every open concern of ctype for the host.
+func (s *Store) ResolveConcerns(ctx context.Context, ctype string, hostID int64) (int64, error) {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE concerns SET resolved_at=now() WHERE type=$1 AND host_id=$2 AND resolved_at IS NULL`, ctype, hostID)
+	if err != nil {
+		return 0, fmt.Errorf("resolve concerns: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
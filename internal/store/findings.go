@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Key finding types, raised as concerns by RefreshKeyFindings.
+const (
+	ConcernKeyUnused          = "KEY_UNUSED"           // authorized, but no login used it for unused_days
+	ConcernKeyUnknown         = "KEY_UNKNOWN"          // used in logs, in no scanned authorized_keys for the account
+	ConcernKeyOrphanedPrivate = "KEY_ORPHANED_PRIVATE" // private key whose public half is authorized nowhere
+	ConcernKeySharedPrivate   = "KEY_SHARED_PRIVATE"   // the same private key on more than max_private_hosts hosts
+	ConcernKeyWideAccess      = "KEY_WIDE_ACCESS"      // authorized for more than max_authorized_accounts accounts
+)
+
+// KeyFindingTypes are the concern types RefreshKeyFindings owns.
+var KeyFindingTypes = []string{ConcernKeyUnused, ConcernKeyUnknown, ConcernKeyOrphanedPrivate, ConcernKeySharedPrivate, ConcernKeyWideAccess}
+
+// KeyFindingRules are the thresholds of a refresh (config key_findings).
+// A zero MaxPrivateHosts or MaxAuthorizedAccounts turns that check off.
+type KeyFindingRules struct {
+	UnusedDays            int
+	MaxPrivateHosts       int
+	MaxAuthorizedAccounts int
+}
+
+// KeyFindings summarizes a refresh: findings open per type, and how many
+// concerns were raised and resolved by it.
+type KeyFindings struct {
+	Unused          int `json:"unused"`
+	Unknown         int `json:"unknown"`
+	OrphanedPrivate int `json:"orphaned_private"`
+	SharedPrivate   int `json:"shared_private"`
+	WideAccess      int `json:"wide_access"`
+	Raised          int `json:"raised"`
+	Resolved        int `json:"resolved"`
+}
+
+// currentInstanceSQL holds for key_instances ki still present at the latest
+// collection of its kind (authorized_keys, key hunt) on its host: rows for
+// keys since removed keep their old last_seen.
+const currentInstanceSQL = `ki.last_seen >= (
+    SELECT max(o.last_seen) FROM key_instances o
+    WHERE o.host_id = ki.host_id AND o.instance_type = ki.instance_type) - interval '1 hour'`
+
+// accountSQL identifies the account of an authorized_key instance ki.
+const accountSQL = `ki.host_id || ':' || COALESCE(ki.username, ki.path)`
+
+// keyFindingsSQL fills key_findings_now; $1 is the unused cutoff, $2 and $3
+// max_private_hosts and max_authorized_accounts.
+//
+// An authorized key counts as unused once it has been known for unused_days
+// with no login by it on that account since; only entries present at the
+// host's latest authorized_keys scan count. Unknown keys are those with
+// logins since the cutoff that no authorized_keys entry explains.
+const keyFindingsSQL = `
+INSERT INTO key_findings_now(severity, type, host_id, key_id, fingerprint_sha256, subject, details)
+SELECT 'medium', 'KEY_UNUSED', ki.host_id, ki.key_id, k.fingerprint_sha256, 'key_instance:' || ki.id,
+  format('authorized in %s on %s, %s', ki.path, h.hostname,
+    COALESCE('last used ' || to_char(u.last_used AT TIME ZONE 'UTC', 'YYYY-MM-DD'), 'no login seen'))
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+JOIN hosts h ON h.id = ki.host_id
+LEFT JOIN LATERAL (
+  SELECT max(e.ts) AS last_used FROM access_events e
+  WHERE e.dest_host_id = ki.host_id AND e.key_id = ki.key_id
+    AND (ki.username IS NULL OR e.dest_user = ki.username)) u ON true
+WHERE ki.instance_type = 'authorized_key'
+  AND ki.first_seen <= $1
+  AND (u.last_used IS NULL OR u.last_used < $1)
+  AND ` + currentInstanceSQL + `
+
+UNION ALL
+
+SELECT 'medium', 'KEY_UNKNOWN', e.dest_host_id, max(e.key_id), e.fingerprint_sha256,
+  'host:' || e.dest_host_id || ':' || e.fingerprint_sha256,
+  format('%s login(s) as %s on %s, last %s, granted by no scanned authorized_keys (AuthorizedKeysCommand or an unscanned file?)',
+    count(*), string_agg(DISTINCT e.dest_user, ','), h.hostname, to_char(max(e.ts) AT TIME ZONE 'UTC', 'YYYY-MM-DD'))
+FROM access_events e
+JOIN hosts h ON h.id = e.dest_host_id
+WHERE e.ts >= $1 AND e.fingerprint_sha256 IS NOT NULL AND e.key_instance_id IS NULL
+GROUP BY e.dest_host_id, h.hostname, e.fingerprint_sha256
+
+UNION ALL
+
+SELECT 'low', 'KEY_ORPHANED_PRIVATE', ki.host_id, ki.key_id, k.fingerprint_sha256, 'key_instance:' || ki.id,
+  format('private key %s on %s is authorized in no scanned authorized_keys', ki.path, h.hostname)
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+JOIN hosts h ON h.id = ki.host_id
+WHERE ki.instance_type = 'private' AND ` + currentInstanceSQL + `
+  AND NOT EXISTS (
+    SELECT 1 FROM key_instances a
+    WHERE a.key_id = ki.key_id AND a.instance_type = 'authorized_key'
+      AND a.last_seen >= (SELECT max(o.last_seen) FROM key_instances o
+                          WHERE o.host_id = a.host_id AND o.instance_type = a.instance_type) - interval '1 hour')
+
+UNION ALL
+
+SELECT 'high', 'KEY_SHARED_PRIVATE', NULL, k.id, k.fingerprint_sha256, 'key:' || k.id,
+  format('private key on %s hosts: %s', count(DISTINCT ki.host_id), string_agg(DISTINCT h.hostname, ', '))
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+JOIN hosts h ON h.id = ki.host_id
+WHERE $2 > 0 AND ki.instance_type = 'private' AND ` + currentInstanceSQL + `
+GROUP BY k.id, k.fingerprint_sha256
+HAVING count(DISTINCT ki.host_id) > $2
+
+UNION ALL
+
+SELECT 'medium', 'KEY_WIDE_ACCESS', NULL, k.id, k.fingerprint_sha256, 'key:' || k.id,
+  format('authorized for %s accounts on %s hosts', count(DISTINCT ` + accountSQL + `), count(DISTINCT ki.host_id))
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+WHERE $3 > 0 AND ki.instance_type = 'authorized_key' AND ` + currentInstanceSQL + `
+GROUP BY k.id, k.fingerprint_sha256
+HAVING count(DISTINCT ` + accountSQL + `) > $3
+`
+
+// RefreshKeyFindings recomputes the key findings: it raises a concern for
+// each new finding, refreshes the details of open ones and resolves those
+// that no longer hold (key used again, removed, found, or spread reduced).
+func (s *Store) RefreshKeyFindings(ctx context.Context, rules KeyFindingRules) (*KeyFindings, error) {
+	cutoff := time.Now().AddDate(0, 0, -rules.UnusedDays)
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('keyspider_key_findings'))`); err != nil {
+		return nil, fmt.Errorf("lock key findings: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+CREATE TEMP TABLE key_findings_now (
+  severity text, type text, host_id bigint, key_id bigint, fingerprint_sha256 text, subject text, details text
+) ON COMMIT DROP`); err != nil {
+		return nil, fmt.Errorf("key findings: %w", err)
+	}
+	if _, err := tx.Exec(ctx, keyFindingsSQL, cutoff, rules.MaxPrivateHosts, rules.MaxAuthorizedAccounts); err != nil {
+		return nil, fmt.Errorf("compute key findings: %w", err)
+	}
+
+	out := &KeyFindings{}
+	rows, err := tx.Query(ctx, `SELECT type, count(*) FROM key_findings_now GROUP BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("count key findings: %w", err)
+	}
+	for rows.Next() {
+		var t string
+		var n int
+		if err := rows.Scan(&t, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		switch t {
+		case ConcernKeyUnused:
+			out.Unused = n
+		case ConcernKeyUnknown:
+			out.Unknown = n
+		case ConcernKeyOrphanedPrivate:
+			out.OrphanedPrivate = n
+		case ConcernKeySharedPrivate:
+			out.SharedPrivate = n
+		case ConcernKeyWideAccess:
+			out.WideAccess = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+WITH up AS (
+  INSERT INTO concerns(severity, type, host_id, key_id, fingerprint_sha256, subject, details)
+  SELECT severity, type, host_id, key_id, fingerprint_sha256, subject, details FROM key_findings_now
+  ON CONFLICT (type, subject) WHERE resolved_at IS NULL AND subject IS NOT NULL
+  DO UPDATE SET severity=EXCLUDED.severity, key_id=EXCLUDED.key_id, details=EXCLUDED.details
+  RETURNING (xmax = 0) AS inserted
+)
+SELECT count(*) FILTER (WHERE inserted) FROM up
+`).Scan(&out.Raised)
+	if err != nil {
+		return nil, fmt.Errorf("raise key findings: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+UPDATE concerns c SET resolved_at = now()
+WHERE c.type = ANY($1) AND c.resolved_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM key_findings_now f WHERE f.type = c.type AND f.subject = c.subject)
+`, KeyFindingTypes)
+	if err != nil {
+		return nil, fmt.Errorf("resolve key findings: %w", err)
+	}
+	out.Resolved = int(tag.RowsAffected())
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -14,13 +15,22 @@ type Edge struct {
 	LastSeen    time.Time `json:"last_seen"`
 	Evidence    string    `json:"evidence_type"`
 	Confidence  int       `json:"confidence"`
+	OutOfScope  bool      `json:"out_of_scope"`
+	Identities  []string  `json:"identities"` // owners of the keys used along the edge
 }
 
 func (s *Store) ListEdges(ctx context.Context, limit int) ([]Edge, error) {
 	rows, err := s.db.Pool.Query(ctx, `
-SELECT id, src_host_id, src_label, dest_host_id, first_seen, last_seen, evidence_type, confidence
-FROM edges
-ORDER BY last_seen DESC
+SELECT ed.id, ed.src_host_id, ed.src_label, ed.dest_host_id, ed.first_seen, ed.last_seen, ed.evidence_type, ed.confidence, ed.out_of_scope,
+  ARRAY(
+    SELECT DISTINCT o.attribution
+    FROM (SELECT DISTINCT fingerprint_sha256 FROM access_events e
+          WHERE e.dest_host_id = ed.dest_host_id AND e.fingerprint_sha256 IS NOT NULL
+            AND (e.source_host = ed.src_label OR host(e.source_ip) = ed.src_label)) f
+    JOIN LATERAL (`+fmt.Sprintf(bestOwnerSQL, "f.fingerprint_sha256")+`) o ON true
+    ORDER BY 1)
+FROM edges ed
+ORDER BY ed.last_seen DESC
 LIMIT $1
 `, limit)
 	if err != nil {
@@ -31,7 +41,7 @@ LIMIT $1
 	var out []Edge
 	for rows.Next() {
 		var e Edge
-		if err := rows.Scan(&e.ID, &e.SrcHostID, &e.SrcLabel, &e.DestHostID, &e.FirstSeen, &e.LastSeen, &e.Evidence, &e.Confidence); err != nil {
+		if err := rows.Scan(&e.ID, &e.SrcHostID, &e.SrcLabel, &e.DestHostID, &e.FirstSeen, &e.LastSeen, &e.Evidence, &e.Confidence, &e.OutOfScope, &e.Identities); err != nil {
 			return nil, err
 		}
 		out = append(out, e)
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Identity kinds.
+const (
+	IdentityPerson  = "person"
+	IdentityService = "service"
+	IdentityTeam    = "team"
+)
+
+// Identity is a person, service account or team that keys belong to.
+type Identity struct {
+	ID          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	Name        string    `json:"name"`
+	DisplayName *string   `json:"display_name"`
+	Email       *string   `json:"email"`
+	TeamID      *int64    `json:"team_id"`
+	Team        *string   `json:"team"` // the team's name, when read back
+	Source      string    `json:"source"`
+	ExternalID  *string   `json:"external_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidIdentityKind reports whether kind is person, service or team.
+func ValidIdentityKind(kind string) bool {
+	return kind == IdentityPerson || kind == IdentityService || kind == IdentityTeam
+}
+
+// KeyOwner links a key fingerprint to an identity.
+type KeyOwner struct {
+	Fingerprint  string    `json:"fingerprint_sha256"`
+	IdentityID   int64     `json:"identity_id"`
+	IdentityKind string    `json:"identity_kind"`
+	IdentityName string    `json:"identity_name"`
+	Label        *string   `json:"label"`
+	Source       string    `json:"source"`
+	Confidence   int       `json:"confidence"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Attribution renders the owner as "alice@corp via laptop key".
+func (o KeyOwner) Attribution() string {
+	if o.Label == nil || *o.Label == "" {
+		return o.IdentityName
+	}
+	return o.IdentityName + " via " + *o.Label
+}
+
+// attributionSQL is KeyOwner.Attribution in SQL, for ko (key_owners) joined to i (identities).
+const attributionSQL = `i.name || COALESCE(' via ' || NULLIF(ko.label, ''), '')`
+
+// bestOwnerSQL picks a fingerprint's most confident owner; %s is the
+// fingerprint expression.
+const bestOwnerSQL = `
+SELECT ko.identity_id, ` + attributionSQL + ` AS attribution
+FROM key_owners ko JOIN identities i ON i.id = ko.identity_id
+WHERE ko.fingerprint_sha256 = %s
+ORDER BY ko.confidence DESC, ko.updated_at DESC
+LIMIT 1`
+
+// UpsertIdentity creates the identity (kind, name) or updates it. Empty
+// optional fields keep what is stored; team names a team identity, which is
+// created if needed.
+func (s *Store) UpsertIdentity(ctx context.Context, id *Identity) (int64, error) {
+	if !ValidIdentityKind(id.Kind) {
+		return 0, fmt.Errorf("identity kind must be person, service or team, not %q", id.Kind)
+	}
+	if id.Name == "" {
+		return 0, errors.New("identity name is required")
+	}
+	if id.Source == "" {
+		id.Source = "manual"
+	}
+	if id.Team != nil && *id.Team != "" && id.TeamID == nil {
+		tid, err := s.UpsertIdentity(ctx, &Identity{Kind: IdentityTeam, Name: *id.Team, Source: id.Source})
+		if err != nil {
+			return 0, err
+		}
+		id.TeamID = &tid
+	}
+	err := s.db.Pool.QueryRow(ctx, `
+INSERT INTO identities(kind, name, display_name, email, team_id, source, external_id)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+ON CONFLICT (kind, name) DO UPDATE SET
+  display_name=COALESCE(EXCLUDED.display_name, identities.display_name),
+  email=COALESCE(EXCLUDED.email, identities.email),
+  team_id=COALESCE(EXCLUDED.team_id, identities.team_id),
+  external_id=COALESCE(EXCLUDED.external_id, identities.external_id),
+  -- A comment-derived guess never relabels an identity someone curated.
+  source=CASE WHEN EXCLUDED.source = 'comment' THEN identities.source ELSE EXCLUDED.source END,
+  updated_at=now()
+RETURNING id
+`, id.Kind, id.Name, id.DisplayName, id.Email, id.TeamID, id.Source, id.ExternalID).Scan(&id.ID)
+	if err != nil {
+		return 0, fmt.Errorf("upsert identity: %w", err)
+	}
+	return id.ID, nil
+}
+
+const identityCols = `i.id, i.kind, i.name, i.display_name, i.email, i.team_id, t.name, i.source, i.external_id, i.created_at, i.updated_at`
+
+func scanIdentity(row pgx.Row, id *Identity) error {
+	return row.Scan(&id.ID, &id.Kind, &id.Name, &id.DisplayName, &id.Email, &id.TeamID, &id.Team, &id.Source, &id.ExternalID, &id.CreatedAt, &id.UpdatedAt)
+}
+
+// GetIdentity returns the identity, or nil if there is none.
+func (s *Store) GetIdentity(ctx context.Context, id int64) (*Identity, error) {
+	var out Identity
+	err := scanIdentity(s.db.Pool.QueryRow(ctx, `
+SELECT `+identityCols+` FROM identities i LEFT JOIN identities t ON t.id = i.team_id WHERE i.id=$1`, id), &out)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get identity: %w", err)
+	}
+	return &out, nil
+}
+
+// FindIdentity looks an identity up by kind and name; nil if there is none.
+func (s *Store) FindIdentity(ctx context.Context, kind, name string) (*Identity, error) {
+	var out Identity
+	err := scanIdentity(s.db.Pool.QueryRow(ctx, `
+SELECT `+identityCols+` FROM identities i LEFT JOIN identities t ON t.id = i.team_id WHERE i.kind=$1 AND i.name=$2`, kind, name), &out)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find identity: %w", err)
+	}
+	return &out, nil
+}
+
+// ListIdentities returns identities of kind (empty: all), by name.
+func (s *Store) ListIdentities(ctx context.Context, kind string, limit int) ([]Identity, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+identityCols+` FROM identities i LEFT JOIN identities t ON t.id = i.team_id
+WHERE $1 = '' OR i.kind = $1
+ORDER BY i.kind, i.name
+LIMIT $2
+`, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %w", err)
+	}
+	defer rows.Close()
+	out := []Identity{}
+	for rows.Next() {
+		var id Identity
+		if err := scanIdentity(rows, &id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// DeleteIdentity removes an identity and its key links; false if there was none.
+func (s *Store) DeleteIdentity(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM identities WHERE id=$1`, id)
+	if err != nil {
+		return false, fmt.Errorf("delete identity: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LinkKey records that fingerprint belongs to identityID. An existing link
+// is only replaced by one from the same source or with at least its
+// confidence, so derived guesses never override curated owners.
+func (s *Store) LinkKey(ctx context.Context, fingerprint string, identityID int64, label *string, source string, confidence int) error {
+	_, err := s.db.Pool.Exec(ctx, `
+INSERT INTO key_owners(fingerprint_sha256, identity_id, label, source, confidence)
+VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (fingerprint_sha256, identity_id) DO UPDATE SET
+  label=COALESCE(EXCLUDED.label, key_owners.label), source=EXCLUDED.source, confidence=EXCLUDED.confidence, updated_at=now()
+WHERE key_owners.source = EXCLUDED.source OR EXCLUDED.confidence >= key_owners.confidence
+`, fingerprint, identityID, label, source, confidence)
+	if err != nil {
+		return fmt.Errorf("link key: %w", err)
+	}
+	return nil
+}
+
+// UnlinkKey removes the link between fingerprint and identityID; false if
+// there was none.
+func (s *Store) UnlinkKey(ctx context.Context, fingerprint string, identityID int64) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM key_owners WHERE fingerprint_sha256=$1 AND identity_id=$2`, fingerprint, identityID)
+	if err != nil {
+		return false, fmt.Errorf("unlink key: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListKeyOwners returns the owners of fingerprint (empty: every link of
+// identityID, when non-zero), most confident first.
+func (s *Store) ListKeyOwners(ctx context.Context, fingerprint string, identityID int64) ([]KeyOwner, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT ko.fingerprint_sha256, ko.identity_id, i.kind, i.name, ko.label, ko.source, ko.confidence, ko.created_at, ko.updated_at
+FROM key_owners ko JOIN identities i ON i.id = ko.identity_id
+WHERE ($1 = '' OR ko.fingerprint_sha256 = $1) AND ($2::bigint = 0 OR ko.identity_id = $2)
+ORDER BY ko.confidence DESC, ko.updated_at DESC
+LIMIT 1000
+`, fingerprint, identityID)
+	if err != nil {
+		return nil, fmt.Errorf("list key owners: %w", err)
+	}
+	defer rows.Close()
+	out := []KeyOwner{}
+	for rows.Next() {
+		var o KeyOwner
+		if err := rows.Scan(&o.Fingerprint, &o.IdentityID, &o.IdentityKind, &o.IdentityName, &o.Label, &o.Source, &o.Confidence, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// KeyAttribution returns "alice@corp via laptop key" for fingerprint's most
+// confident owner, or "" when the key has none.
+func (s *Store) KeyAttribution(ctx context.Context, fingerprint string) (string, error) {
+	var id int64
+	var attr string
+	err := s.db.Pool.QueryRow(ctx, fmt.Sprintf(bestOwnerSQL, "$1"), fingerprint).Scan(&id, &attr)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("key attribution: %w", err)
+	}
+	return attr, nil
+}
+
+// CommentedKey is a key with its comment, for deriving owners.
+type CommentedKey struct {
+	Fingerprint string
+	Comment     string
+}
+
+// ListCommentedKeys returns every ssh_keys row with a comment.
+func (s *Store) ListCommentedKeys(ctx context.Context) ([]CommentedKey, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT fingerprint_sha256, comment FROM ssh_keys WHERE COALESCE(comment, '') <> '' ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list commented keys: %w", err)
+	}
+	defer rows.Close()
+	var out []CommentedKey
+	for rows.Next() {
+		var k CommentedKey
+		if err := rows.Scan(&k.Fingerprint, &k.Comment); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// InventoryHost is one host as an inventory/CMDB describes it.
+type InventoryHost struct {
+	Hostname    string   `json:"hostname"` // hosts.hostname: the FQDN when the inventory has one
+	FQDN        string   `json:"fqdn"`
+	OSType      string   `json:"os_type"` // empty, or once keyspider has run uname on the host, keeps the known one
+	Environment string   `json:"environment"`
+	OwnerTeam   string   `json:"owner_team"`
+	Tags        []string `json:"tags"`
+	IPs         []string `json:"ip_addresses"`
+}
+
+// InventoryImport counts what ImportInventory changed.
+type InventoryImport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Pruned  int `json:"pruned"` // hosts of the source no longer listed
+}
+
+// ImportInventory upserts hosts from one inventory source, replacing their
+// environment, owner team and addresses, and the tags source gave them
+// before (tags from other sources stay). With prune, hosts last
+// imported from source that it no longer lists drop out of the inventory
+// (they keep their metadata).
+func (s *Store) ImportInventory(ctx context.Context, source string, hosts []InventoryHost, prune bool) (*InventoryImport, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res := &InventoryImport{}
+	names := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		tags, ips := h.Tags, h.IPs
+		if tags == nil {
+			tags = []string{}
+		}
+		if ips == nil {
+			ips = []string{}
+		}
+		var created bool
+		err := tx.QueryRow(ctx, `
+INSERT INTO hosts(hostname, fqdn, os_type, environment, owner_team, tags, inventory_tags, ip_addresses, in_inventory, inventory_source, inventory_seen_at)
+VALUES ($1, NULLIF($2,''), COALESCE(NULLIF($3,''), 'linux'), NULLIF($4,''), NULLIF($5,''), $6, jsonb_build_object($8::text, to_jsonb($6::text[])), $7, true, $8, now())
+ON CONFLICT (hostname) DO UPDATE SET
+  fqdn=COALESCE(EXCLUDED.fqdn, hosts.fqdn),
+  os_type=CASE WHEN $3 = '' OR hosts.os_checked_at IS NOT NULL THEN hosts.os_type ELSE EXCLUDED.os_type END,
+  environment=EXCLUDED.environment, owner_team=EXCLUDED.owner_team, ip_addresses=EXCLUDED.ip_addresses,
+  tags=ARRAY(
+    SELECT t.tag FROM unnest(hosts.tags) t(tag)
+    WHERE NOT EXISTS (SELECT 1 FROM jsonb_each(hosts.inventory_tags) it, jsonb_array_elements_text(it.value) v(tag) WHERE v.tag = t.tag)
+    UNION
+    SELECT v.tag FROM jsonb_each(hosts.inventory_tags || EXCLUDED.inventory_tags) it, jsonb_array_elements_text(it.value) v(tag)
+    ORDER BY 1),
+  inventory_tags=hosts.inventory_tags || EXCLUDED.inventory_tags,
+  in_inventory=true, inventory_source=EXCLUDED.inventory_source, inventory_seen_at=now()
+RETURNING (xmax = 0)
+`, h.Hostname, h.FQDN, h.OSType, h.Environment, h.OwnerTeam, tags, ips, source).Scan(&created)
+		if err != nil {
+			return nil, fmt.Errorf("import host %s: %w", h.Hostname, err)
+		}
+		if created {
+			res.Created++
+		} else {
+			res.Updated++
+		}
+		names = append(names, h.Hostname)
+	}
+
+	if prune {
+		tag, err := tx.Exec(ctx, `
+UPDATE hosts SET in_inventory=false
+WHERE in_inventory AND inventory_source=$1 AND hostname <> ALL($2)
+`, source, names)
+		if err != nil {
+			return nil, fmt.Errorf("prune inventory: %w", err)
+		}
+		res.Pruned = int(tag.RowsAffected())
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+// InventoryGaps compares the inventory with what keyspider has seen.
+type InventoryGaps struct {
+	NeverScanned   []Host `json:"never_scanned"`    // in inventory, never scanned or watched
+	NotInInventory []Host `json:"not_in_inventory"` // scanned, watched or with edges, but not in inventory
+}
+
+// ListInventoryGaps returns up to limit hosts of each kind. A host counts as
+// scanned once the spider collected its logs or keys (last_scanned_at) or a
+// watcher or push receiver recorded events for it. Hosts only named in
+// passing (a scope check, a DNS answer) are in neither list.
+func (s *Store) ListInventoryGaps(ctx context.Context, limit int) (*InventoryGaps, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+hostCols+` FROM hosts h
+WHERE in_inventory AND last_scanned_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM access_events e WHERE e.dest_host_id = h.id)
+ORDER BY hostname
+LIMIT $1
+`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list never scanned: %w", err)
+	}
+	gaps := &InventoryGaps{}
+	if gaps.NeverScanned, err = scanHosts(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Pool.Query(ctx, `
+SELECT `+hostCols+` FROM hosts h
+WHERE NOT in_inventory
+  AND (last_scanned_at IS NOT NULL
+    OR EXISTS (SELECT 1 FROM watchers w WHERE w.host_id = h.id)
+    OR EXISTS (SELECT 1 FROM edges ed WHERE ed.src_host_id = h.id OR ed.dest_host_id = h.id))
+ORDER BY hostname
+LIMIT $1
+`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list not in inventory: %w", err)
+	}
+	if gaps.NotInInventory, err = scanHosts(rows); err != nil {
+		return nil, err
+	}
+	return gaps, nil
+}
@@ -31,8 +31,11 @@ type KeyInstance struct {
 	LastSeen     time.Time  `json:"last_seen"`
 }
 
+// UpsertSSHKey records a key by fingerprint. A newly discovered key is
+// linked to the access events that already used it.
 func (s *Store) UpsertSSHKey(ctx context.Context, keyType string, publicKey *string, fp256 string, comment *string) (int64, error) {
 	var id int64
+	var inserted bool
 	err := s.db.Pool.QueryRow(ctx, `
 INSERT INTO ssh_keys(key_type, public_key, fingerprint_sha256, comment)
 VALUES ($1,$2,$3,$4)
@@ -40,21 +43,39 @@ ON CONFLICT (fingerprint_sha256) DO UPDATE
 SET key_type=EXCLUDED.key_type,
     public_key=COALESCE(EXCLUDED.public_key, ssh_keys.public_key),
     comment=COALESCE(EXCLUDED.comment, ssh_keys.comment)
-RETURNING id;
-`, keyType, publicKey, fp256, comment).Scan(&id)
+RETURNING id, (xmax = 0);
+`, keyType, publicKey, fp256, comment).Scan(&id, &inserted)
 	if err != nil {
 		return 0, fmt.Errorf("upsert ssh_key: %w", err)
 	}
+	if inserted {
+		if _, err := s.db.Pool.Exec(ctx, `UPDATE access_events SET key_id=$1 WHERE fingerprint_sha256=$2 AND key_id IS NULL`, id, fp256); err != nil {
+			return id, fmt.Errorf("link access_events to key: %w", err)
+		}
+	}
 	return id, nil
 }
 
+// UpsertKeyInstance records where a key lives. authorized_key instances are
+// one row per key in a file (KeyID is required); other types one row per
+// path. A new authorized_key instance is linked to the access events it
+// already granted.
 func (s *Store) UpsertKeyInstance(ctx context.Context, ki *KeyInstance) (int64, error) {
+	conflict := `(host_id, path, instance_type) WHERE instance_type <> 'authorized_key'`
+	if ki.InstanceType == "authorized_key" {
+		if ki.KeyID == nil {
+			return 0, fmt.Errorf("upsert key_instance: authorized_key %s without a key", ki.Path)
+		}
+		conflict = `(host_id, path, key_id) WHERE instance_type = 'authorized_key'`
+	}
 	var id int64
+	var inserted bool
 	err := s.db.Pool.QueryRow(ctx, `
 INSERT INTO key_instances(host_id, username, path, key_id, instance_type, owner, "group", perm, size_bytes, mtime, first_seen, last_seen)
 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, COALESCE($11, now()), now())
-ON CONFLICT (host_id, path, instance_type)
+ON CONFLICT `+conflict+`
 DO UPDATE SET
+  username=COALESCE(EXCLUDED.username, key_instances.username),
   key_id=COALESCE(EXCLUDED.key_id, key_instances.key_id),
   owner=COALESCE(EXCLUDED.owner, key_instances.owner),
   "group"=COALESCE(EXCLUDED."group", key_instances."group"),
@@ -62,11 +83,36 @@ DO UPDATE SET
   size_bytes=COALESCE(EXCLUDED.size_bytes, key_instances.size_bytes),
   mtime=COALESCE(EXCLUDED.mtime, key_instances.mtime),
   last_seen=now()
-RETURNING id;
-`, ki.HostID, ki.Username, ki.Path, ki.KeyID, ki.InstanceType, ki.Owner, ki.Group, ki.Perm, ki.SizeBytes, ki.Mtime, ki.FirstSeen).Scan(&id)
+RETURNING id, (xmax = 0);
+`, ki.HostID, ki.Username, ki.Path, ki.KeyID, ki.InstanceType, ki.Owner, ki.Group, ki.Perm, ki.SizeBytes, ki.Mtime, ki.FirstSeen).Scan(&id, &inserted)
 	if err != nil {
-		// On conflict requires unique constraint; we will add it in migration 003.
+		// On conflict requires the unique indexes from migration 024.
 		return 0, fmt.Errorf("upsert key_instance: %w", err)
 	}
+	if inserted && ki.InstanceType == "authorized_key" {
+		if err := s.linkEventsToInstance(ctx, id); err != nil {
+			return id, err
+		}
+	}
 	return id, nil
 }
+
+// linkEventsToInstance points the unlinked access events that the
+// authorized_keys entry id granted (same host, key and account) at it.
+func (s *Store) linkEventsToInstance(ctx context.Context, id int64) error {
+	_, err := s.db.Pool.Exec(ctx, `
+UPDATE access_events e
+SET key_id = ki.key_id, key_instance_id = ki.id
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+WHERE ki.id = $1
+  AND e.dest_host_id = ki.host_id
+  AND e.fingerprint_sha256 = k.fingerprint_sha256
+  AND e.key_instance_id IS NULL
+  AND (ki.username IS NULL OR e.dest_user = ki.username)
+`, id)
+	if err != nil {
+		return fmt.Errorf("link access_events to key_instance: %w", err)
+	}
+	return nil
+}
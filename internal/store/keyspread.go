@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// KeySpread is how far one key has spread, counting only the key_instances
+// present at their host's latest collection.
+type KeySpread struct {
+	KeyID              int64      `json:"key_id"`
+	Fingerprint        string     `json:"fingerprint_sha256"`
+	KeyType            string     `json:"key_type"`
+	Comment            *string    `json:"comment"`
+	Owner              *string    `json:"owner"` // "alice@corp via laptop key", when known
+	AuthorizedHosts    int        `json:"authorized_hosts"`
+	AuthorizedUsers    int        `json:"authorized_users"`    // distinct usernames
+	AuthorizedAccounts int        `json:"authorized_accounts"` // distinct host:user pairs
+	PrivateCopies      int        `json:"private_copies"`
+	PrivateHosts       int        `json:"private_hosts"`
+	LoginCount         int        `json:"login_count"`
+	LastLogin          *time.Time `json:"last_login"`
+}
+
+// KeySpreadFilter selects keys by spread; zero fields match everything.
+type KeySpreadFilter struct {
+	MinAccounts     int
+	MinPrivateHosts int
+	Limit           int
+}
+
+// KeyLocation is a place a key was found: an authorized_keys entry, or a
+// public or private key file found by key hunt.
+type KeyLocation struct {
+	InstanceID   int64     `json:"instance_id"`
+	HostID       int64     `json:"host_id"`
+	Hostname     string    `json:"hostname"`
+	Username     *string   `json:"username"`
+	Path         string    `json:"path"`
+	InstanceType string    `json:"instance_type"`
+	Current      bool      `json:"current"` // present at the host's latest collection
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// KeyDetail is everything known about one key.
+type KeyDetail struct {
+	KeySpread
+	PublicKey *string       `json:"public_key"`
+	CreatedAt time.Time     `json:"created_at"`
+	Locations []KeyLocation `json:"locations"`
+	Logins    []AccessEvent `json:"logins"` // newest first
+}
+
+// keySpreadSQL aggregates the current instances and logins of each key; $1
+// restricts it to one fingerprint unless empty, $2 and $3 are the minimum
+// accounts and private hosts, $4 the limit.
+const keySpreadSQL = `
+SELECT k.id, k.fingerprint_sha256, k.key_type, k.comment, o.attribution,
+  count(DISTINCT ki.host_id) FILTER (WHERE ki.instance_type = 'authorized_key'),
+  count(DISTINCT ki.username) FILTER (WHERE ki.instance_type = 'authorized_key'),
+  count(DISTINCT ` + accountSQL + `) FILTER (WHERE ki.instance_type = 'authorized_key'),
+  count(ki.id) FILTER (WHERE ki.instance_type = 'private'),
+  count(DISTINCT ki.host_id) FILTER (WHERE ki.instance_type = 'private'),
+  l.logins, l.last_login
+FROM ssh_keys k
+LEFT JOIN key_instances ki ON ki.key_id = k.id AND ` + currentInstanceSQL + `
+LEFT JOIN LATERAL (SELECT count(*) AS logins, max(e.ts) AS last_login FROM access_events e WHERE e.key_id = k.id) l ON true
+LEFT JOIN LATERAL (` + bestOwnerSQL + `) o ON true
+WHERE ($1 = '' OR k.fingerprint_sha256 = $1)
+GROUP BY k.id, o.attribution, l.logins, l.last_login
+HAVING count(DISTINCT ` + accountSQL + `) FILTER (WHERE ki.instance_type = 'authorized_key') >= $2
+   AND count(DISTINCT ki.host_id) FILTER (WHERE ki.instance_type = 'private') >= $3
+ORDER BY 8 DESC, 10 DESC, k.id
+LIMIT $4
+`
+
+func scanKeySpread(row pgx.Row, ks *KeySpread) error {
+	return row.Scan(&ks.KeyID, &ks.Fingerprint, &ks.KeyType, &ks.Comment, &ks.Owner, &ks.AuthorizedHosts, &ks.AuthorizedUsers,
+		&ks.AuthorizedAccounts, &ks.PrivateCopies, &ks.PrivateHosts, &ks.LoginCount, &ks.LastLogin)
+}
+
+// ListKeySpread returns the spread of keys matching f, widest first.
+func (s *Store) ListKeySpread(ctx context.Context, f KeySpreadFilter) ([]KeySpread, error) {
+	if f.Limit <= 0 {
+		f.Limit = 200
+	}
+	rows, err := s.db.Pool.Query(ctx, fmt.Sprintf(keySpreadSQL, "k.fingerprint_sha256"), "", f.MinAccounts, f.MinPrivateHosts, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list key spread: %w", err)
+	}
+	defer rows.Close()
+	out := []KeySpread{}
+	for rows.Next() {
+		var ks KeySpread
+		if err := scanKeySpread(rows, &ks); err != nil {
+			return nil, err
+		}
+		out = append(out, ks)
+	}
+	return out, rows.Err()
+}
+
+// GetKeyDetail returns the key with this fingerprint, its spread, every
+// location it was found at and its latest logins, or nil if it is unknown.
+// A key seen only in logs (no scan found it, see KEY_UNKNOWN) has its logins
+// and no locations.
+func (s *Store) GetKeyDetail(ctx context.Context, fingerprint string, loginLimit int) (*KeyDetail, error) {
+	if fingerprint == "" {
+		return nil, nil
+	}
+	if loginLimit <= 0 {
+		loginLimit = 500
+	}
+	d := &KeyDetail{Locations: []KeyLocation{}}
+	err := scanKeySpread(s.db.Pool.QueryRow(ctx, fmt.Sprintf(keySpreadSQL, "k.fingerprint_sha256"), fingerprint, 0, 0, 1), &d.KeySpread)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.loggedKeyDetail(ctx, fingerprint, loginLimit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+	if err := s.db.Pool.QueryRow(ctx, `SELECT public_key, created_at FROM ssh_keys WHERE id=$1`, d.KeyID).Scan(&d.PublicKey, &d.CreatedAt); err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT ki.id, ki.host_id, h.hostname, ki.username, ki.path, ki.instance_type, `+currentInstanceSQL+`, ki.first_seen, ki.last_seen
+FROM key_instances ki
+JOIN hosts h ON h.id = ki.host_id
+WHERE ki.key_id = $1
+ORDER BY h.hostname, ki.instance_type, ki.path
+`, d.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("list key locations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l KeyLocation
+		if err := rows.Scan(&l.InstanceID, &l.HostID, &l.Hostname, &l.Username, &l.Path, &l.InstanceType, &l.Current, &l.FirstSeen, &l.LastSeen); err != nil {
+			return nil, err
+		}
+		d.Locations = append(d.Locations, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if d.Logins, err = s.ListKeyLogins(ctx, fingerprint, loginLimit); err != nil {
+		return nil, fmt.Errorf("list key logins: %w", err)
+	}
+	if d.Logins == nil {
+		d.Logins = []AccessEvent{}
+	}
+	return d, nil
+}
+
+// loggedKeyDetail is GetKeyDetail for a fingerprint with no ssh_keys row,
+// from its logins alone (created_at is the first login); nil if it has none.
+func (s *Store) loggedKeyDetail(ctx context.Context, fingerprint string, loginLimit int) (*KeyDetail, error) {
+	d := &KeyDetail{KeySpread: KeySpread{Fingerprint: fingerprint}, Locations: []KeyLocation{}}
+	var first *time.Time
+	err := s.db.Pool.QueryRow(ctx, `
+SELECT count(*), max(ts), min(ts) FROM access_events WHERE fingerprint_sha256 = $1
+`, fingerprint).Scan(&d.LoginCount, &d.LastLogin, &first)
+	if err != nil {
+		return nil, fmt.Errorf("get logged key: %w", err)
+	}
+	if d.LoginCount == 0 {
+		return nil, nil
+	}
+	d.CreatedAt = *first
+	owner, err := s.KeyAttribution(ctx, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if owner != "" {
+		d.Owner = &owner
+	}
+	if d.Logins, err = s.ListKeyLogins(ctx, fingerprint, loginLimit); err != nil {
+		return nil, fmt.Errorf("list key logins: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LeaderStatus is the last recorded holder of a leader role. The advisory
+// lock decides leadership; this row only reports it.
+type LeaderStatus struct {
+	Role        string    `json:"role"`
+	Holder      string    `json:"holder"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
+
+func (s *Store) SetLeader(ctx context.Context, role, holder string) error {
+	_, err := s.db.Pool.Exec(ctx, `
+INSERT INTO leader_status(role, holder, acquired_at, heartbeat_at)
+VALUES ($1,$2, now(), now())
+ON CONFLICT (role) DO UPDATE SET holder=EXCLUDED.holder, acquired_at=now(), heartbeat_at=now()
+`, role, holder)
+	if err != nil {
+		return fmt.Errorf("set leader: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) HeartbeatLeader(ctx context.Context, role, holder string) error {
+	_, err := s.db.Pool.Exec(ctx, `UPDATE leader_status SET heartbeat_at=now() WHERE role=$1 AND holder=$2`, role, holder)
+	if err != nil {
+		return fmt.Errorf("heartbeat leader: %w", err)
+	}
+	return nil
+}
+
+// ClearLeader removes the row on a clean step-down, unless another holder already replaced it.
+func (s *Store) ClearLeader(ctx context.Context, role, holder string) error {
+	_, err := s.db.Pool.Exec(ctx, `DELETE FROM leader_status WHERE role=$1 AND holder=$2`, role, holder)
+	if err != nil {
+		return fmt.Errorf("clear leader: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ListLeaders(ctx context.Context) ([]LeaderStatus, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT role, holder, acquired_at, heartbeat_at FROM leader_status ORDER BY role`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LeaderStatus
+	for rows.Next() {
+		var l LeaderStatus
+		if err := rows.Scan(&l.Role, &l.Holder, &l.AcquiredAt, &l.HeartbeatAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
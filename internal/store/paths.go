@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Evidence of a GraphEdge.
+const (
+	EvidenceLog           = "log"            // logins seen in the destination's logs (edges table)
+	EvidenceKey           = "key"            // a private key on the source is authorized on the destination
+	EvidenceAuthorizedKey = "authorized_key" // the key itself is authorized on the destination (key reach)
+)
+
+// GraphEdge is one "Src can log into Dest" hop for path queries. Src is the
+// source's hostname, or its label (an address) when it is not a known host;
+// key edges name the key that grants the access.
+type GraphEdge struct {
+	Src         string    `json:"src"`
+	SrcHostID   *int64    `json:"src_host_id"`
+	Dest        string    `json:"dest"`
+	DestHostID  int64     `json:"dest_host_id"`
+	Evidence    string    `json:"evidence_type"`
+	Confidence  int       `json:"confidence"`
+	LastSeen    time.Time `json:"last_seen"`
+	Fingerprint *string   `json:"fingerprint_sha256,omitempty"`
+}
+
+// GraphEdgeFilter selects the edges path queries may use; zero fields match
+// everything.
+type GraphEdgeFilter struct {
+	Evidence      []string // log, key
+	MinConfidence int
+	Since         time.Time // last seen at or after
+}
+
+// keyEdgeConfidence rates key edges below logins: a private key on a host
+// proves access only if it is usable there (no passphrase, readable by
+// someone on the host).
+const keyEdgeConfidence = 60
+
+// ListGraphEdges returns the edges matching f: the login edges, and key
+// edges from every host holding a private key to every host that key is
+// authorized on. Key edges use only instances present at their host's
+// latest collection. A login source recorded by address is named after the
+// host that address belongs to, when its latest host_addresses row is
+// FCrDNS-verified, so paths continue through it.
+func (s *Store) ListGraphEdges(ctx context.Context, f GraphEdgeFilter) ([]GraphEdge, error) {
+	if f.Evidence == nil {
+		f.Evidence = []string{}
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+WITH addr_hosts AS (
+  SELECT host(a.ip) AS label, a.host_id, h.hostname
+  FROM (SELECT DISTINCT ON (ip) ip, host_id, fcrdns FROM host_addresses ORDER BY ip, last_seen DESC, id DESC) a
+  JOIN hosts h ON h.id = a.host_id
+  WHERE a.fcrdns
+)
+SELECT * FROM (
+  SELECT COALESCE(sh.hostname, ah.hostname, ed.src_label), COALESCE(ed.src_host_id, ah.host_id), dh.hostname, ed.dest_host_id,
+    ed.evidence_type, ed.confidence, ed.last_seen, NULL::text
+  FROM edges ed
+  JOIN hosts dh ON dh.id = ed.dest_host_id
+  LEFT JOIN hosts sh ON sh.id = ed.src_host_id
+  LEFT JOIN addr_hosts ah ON ed.src_host_id IS NULL AND ah.label = ed.src_label
+
+  UNION ALL
+
+  SELECT sh.hostname, ki.host_id, dh.hostname, a.host_id, 'key', $4::int, max(LEAST(ki.last_seen, a.last_seen)), k.fingerprint_sha256
+  FROM key_instances ki
+  JOIN key_instances a ON a.key_id = ki.key_id AND a.instance_type = 'authorized_key' AND a.host_id <> ki.host_id
+  JOIN ssh_keys k ON k.id = ki.key_id
+  JOIN hosts sh ON sh.id = ki.host_id
+  JOIN hosts dh ON dh.id = a.host_id
+  WHERE ki.instance_type = 'private' AND `+currentInstanceSQL+`
+    AND a.last_seen >= (SELECT max(o.last_seen) FROM key_instances o
+                        WHERE o.host_id = a.host_id AND o.instance_type = a.instance_type) - interval '1 hour'
+  GROUP BY sh.hostname, ki.host_id, dh.hostname, a.host_id, k.fingerprint_sha256
+) g(src, src_host_id, dest, dest_host_id, evidence_type, confidence, last_seen, fingerprint_sha256)
+WHERE (cardinality($1::text[]) = 0 OR evidence_type = ANY($1))
+  AND confidence >= $2
+  AND ($3::timestamptz IS NULL OR last_seen >= $3)
+ORDER BY src, confidence DESC, dest
+`, f.Evidence, f.MinConfidence, nullTime(f.Since), keyEdgeConfidence)
+	if err != nil {
+		return nil, fmt.Errorf("list graph edges: %w", err)
+	}
+	defer rows.Close()
+	var out []GraphEdge
+	for rows.Next() {
+		var e GraphEdge
+		if err := rows.Scan(&e.Src, &e.SrcHostID, &e.Dest, &e.DestHostID, &e.Evidence, &e.Confidence, &e.LastSeen, &e.Fingerprint); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ListKeyAccessEdges returns an edge from the key (named by its
+// fingerprint) to each host it is currently authorized on, the first hop of
+// what a holder of the key can reach. Only since filters them: the evidence
+// and confidence filters are for the hops after it.
+func (s *Store) ListKeyAccessEdges(ctx context.Context, fingerprint string, since time.Time) ([]GraphEdge, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT h.hostname, ki.host_id, max(ki.last_seen)
+FROM key_instances ki
+JOIN ssh_keys k ON k.id = ki.key_id
+JOIN hosts h ON h.id = ki.host_id
+WHERE k.fingerprint_sha256 = $1 AND ki.instance_type = 'authorized_key' AND `+currentInstanceSQL+`
+GROUP BY h.hostname, ki.host_id
+HAVING $2::timestamptz IS NULL OR max(ki.last_seen) >= $2
+ORDER BY h.hostname
+`, fingerprint, nullTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("list key access edges: %w", err)
+	}
+	defer rows.Close()
+	var out []GraphEdge
+	for rows.Next() {
+		e := GraphEdge{Src: fingerprint, Evidence: EvidenceAuthorizedKey, Confidence: 100, Fingerprint: &fingerprint}
+		if err := rows.Scan(&e.Dest, &e.DestHostID, &e.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// nullTime is t, or NULL when t is zero.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
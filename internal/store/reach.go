@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReachProbe is one reachability probe of a host.
+type ReachProbe struct {
+	ID         int64     `json:"id"`
+	HostID     int64     `json:"host_id"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Reachable  bool      `json:"reachable"`
+	Reason     *string   `json:"reason"` // see migration 020; nil when reachable
+	Detail     *string   `json:"detail"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// RecordReachability stores a probe of hostname: the latest result on hosts
+// and a reach_history row. It creates the host if needed and returns its id.
+func (s *Store) RecordReachability(ctx context.Context, hostname string, p ReachProbe) (int64, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+INSERT INTO hosts(hostname, reachable_from_jump, reach_checked_at, reach_reason) VALUES ($1, $2, now(), $3)
+ON CONFLICT (hostname) DO UPDATE SET reachable_from_jump=EXCLUDED.reachable_from_jump, reach_checked_at=now(), reach_reason=EXCLUDED.reach_reason
+RETURNING id
+`, hostname, p.Reachable, p.Reason).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("record reachability: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+INSERT INTO reach_history(host_id, reachable, reason, detail, duration_ms) VALUES ($1,$2,$3,$4,$5)
+`, id, p.Reachable, p.Reason, p.Detail, p.DurationMS); err != nil {
+		return 0, fmt.Errorf("insert reach history: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return id, nil
+}
+
+// HostReachability returns hostname's last probe; CheckedAt is the zero time
+// if it was never probed (or the host is unknown).
+func (s *Store) HostReachability(ctx context.Context, hostname string) (*ReachProbe, error) {
+	var p ReachProbe
+	var checkedAt *time.Time
+	err := s.db.Pool.QueryRow(ctx, `SELECT id, reachable_from_jump, reach_checked_at, reach_reason FROM hosts WHERE hostname=$1`, hostname).
+		Scan(&p.HostID, &p.Reachable, &checkedAt, &p.Reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("host reachability: %w", err)
+	}
+	if checkedAt != nil {
+		p.CheckedAt = *checkedAt
+	}
+	return &p, nil
+}
+
+// ListReachHistory returns hostID's probes since from (zero: all), newest first.
+func (s *Store) ListReachHistory(ctx context.Context, hostID int64, from time.Time, limit int) ([]ReachProbe, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT id, host_id, checked_at, reachable, reason, detail, COALESCE(duration_ms, 0)
+FROM reach_history
+WHERE host_id=$1 AND checked_at >= $2
+ORDER BY checked_at DESC, id DESC
+LIMIT $3
+`, hostID, from, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list reach history: %w", err)
+	}
+	defer rows.Close()
+	out := []ReachProbe{}
+	for rows.Next() {
+		var p ReachProbe
+		if err := rows.Scan(&p.ID, &p.HostID, &p.CheckedAt, &p.Reachable, &p.Reason, &p.Detail, &p.DurationMS); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ReachChange is a host whose reachability flipped.
+type ReachChange struct {
+	HostID       int64     `json:"host_id"`
+	Hostname     string    `json:"hostname"`
+	ChangedAt    time.Time `json:"changed_at"`
+	Reachable    bool      `json:"reachable"`     // the state it changed to
+	WasReachable *bool     `json:"was_reachable"` // nil: first probe of the host
+	Reason       *string   `json:"reason"`
+	Detail       *string   `json:"detail"`
+	ReachableNow bool      `json:"reachable_now"`
+	ConcernID    *int64    `json:"open_concern_id"` // open UNREACHABLE_SOURCE/UNREACHABLE_HOST concern
+}
+
+// ReachChangeFilter selects ReachChanges. SourcesOnly keeps hosts that appear
+// as the source of an edge.
+type ReachChangeFilter struct {
+	From        time.Time
+	To          time.Time
+	Reachable   bool // changes to this state
+	SourcesOnly bool
+	Limit       int
+}
+
+// ListReachChanges returns, per host, the latest probe in [From, To) whose
+// result differs from the probe before it (or that was the host's first
+// probe), limited to changes to f.Reachable.
+func (s *Store) ListReachChanges(ctx context.Context, f ReachChangeFilter) ([]ReachChange, error) {
+	if f.Limit <= 0 {
+		f.Limit = 500
+	}
+	if f.To.IsZero() {
+		f.To = time.Now().Add(time.Minute)
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT * FROM (
+  SELECT DISTINCT ON (r.host_id)
+    r.host_id, h.hostname, r.checked_at, r.reachable, prev.reachable, r.reason, r.detail, h.reachable_from_jump,
+    (SELECT c.id FROM concerns c
+     WHERE c.host_id = r.host_id AND c.type IN ('UNREACHABLE_SOURCE','UNREACHABLE_HOST') AND c.resolved_at IS NULL
+     ORDER BY c.id LIMIT 1)
+  FROM reach_history r
+  JOIN hosts h ON h.id = r.host_id
+  LEFT JOIN LATERAL (
+    SELECT p.reachable FROM reach_history p
+    WHERE p.host_id = r.host_id AND (p.checked_at, p.id) < (r.checked_at, r.id)
+    ORDER BY p.checked_at DESC, p.id DESC LIMIT 1
+  ) prev ON true
+  WHERE r.checked_at >= $1 AND r.checked_at < $2 AND r.reachable = $3
+    AND prev.reachable IS DISTINCT FROM r.reachable
+    AND (NOT $4 OR EXISTS (SELECT 1 FROM edges e WHERE e.src_host_id = r.host_id))
+  ORDER BY r.host_id, r.checked_at DESC, r.id DESC
+) x
+ORDER BY x.checked_at DESC
+LIMIT $5
+`, f.From, f.To, f.Reachable, f.SourcesOnly, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list reach changes: %w", err)
+	}
+	defer rows.Close()
+	out := []ReachChange{}
+	for rows.Next() {
+		var c ReachChange
+		if err := rows.Scan(&c.HostID, &c.Hostname, &c.ChangedAt, &c.Reachable, &c.WasReachable, &c.Reason, &c.Detail, &c.ReachableNow, &c.ConcernID); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Scan host phases, in pipeline order. Terminal phases end processing of a host.
+const (
+	PhaseQueued         = "queued"
+	PhaseLogs           = "logs"
+	PhaseAuthorizedKeys = "authorized_keys"
+	PhaseKeyHunt        = "keyhunt"
+	PhaseConfigAudit    = "config_audit"
+	PhaseDone           = "done"
+	PhaseUnreachable    = "unreachable"
+	PhaseOutOfScope     = "out_of_scope"
+)
+
+// ScanJobHost is the checkpoint and progress of one host within a scan job.
+type ScanJobHost struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	Host      string    `json:"host"`
+	Parent    *string   `json:"parent"` // host whose logs led here (nil for the target)
+	Depth     int       `json:"depth"`
+	Phase     string    `json:"phase"`
+	OSType    *string   `json:"os_type"`
+	Sources   []string  `json:"sources"`
+	Events    int       `json:"events"`
+	Keys      int       `json:"keys"`
+	Edges     int       `json:"edges"`
+	Concerns  int       `json:"concerns"`
+	Errors    []string  `json:"errors"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScanJobTotals sums per-host progress over a whole job, across resumes.
+// It is what scan_jobs.result holds.
+type ScanJobTotals struct {
+	EventsInserted int `json:"events_inserted"`
+	KeysSeen       int `json:"keys_seen"`
+	HostsVisited   int `json:"hosts_visited"`
+	EdgesUpserted  int `json:"edges_upserted"`
+	ConcernsRaised int `json:"concerns_raised"`
+}
+
+const scanJobHostCols = `id, job_id, host, parent, depth, phase, os_type, sources, events, keys, edges, concerns, errors, created_at, updated_at`
+
+func scanScanJobHost(row pgx.Row) (*ScanJobHost, error) {
+	var h ScanJobHost
+	if err := row.Scan(&h.ID, &h.JobID, &h.Host, &h.Parent, &h.Depth, &h.Phase, &h.OSType, &h.Sources,
+		&h.Events, &h.Keys, &h.Edges, &h.Concerns, &h.Errors, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// AddScanJobHost adds host to the job's frontier. A host already reached by the
+// job (at any depth) is left alone, which doubles as the BFS visited set.
+func (s *Store) AddScanJobHost(ctx context.Context, jobID int64, host string, depth int, parent *string) error {
+	_, err := s.db.Pool.Exec(ctx, `
+INSERT INTO scan_job_hosts(job_id, host, depth, parent)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (job_id, host) DO NOTHING
+`, jobID, host, depth, parent)
+	if err != nil {
+		return fmt.Errorf("add scan_job_host: %w", err)
+	}
+	return nil
+}
+
+// NextScanJobHost returns the oldest host of the job that has not reached a
+// terminal phase (including one interrupted mid-pipeline), or nil if none.
+func (s *Store) NextScanJobHost(ctx context.Context, jobID int64) (*ScanJobHost, error) {
+	h, err := scanScanJobHost(s.db.Pool.QueryRow(ctx, `
+SELECT `+scanJobHostCols+`
+FROM scan_job_hosts
+WHERE job_id=$1 AND phase NOT IN ('done','unreachable','out_of_scope')
+ORDER BY id ASC
+LIMIT 1
+`, jobID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("next scan_job_host: %w", err)
+	}
+	return h, nil
+}
+
+// SaveScanJobHost checkpoints the phase, sources and progress counters of h.
+func (s *Store) SaveScanJobHost(ctx context.Context, h *ScanJobHost) error {
+	_, err := s.db.Pool.Exec(ctx, `
+UPDATE scan_job_hosts
+SET phase=$2, os_type=$3, sources=$4, events=$5, keys=$6, edges=$7, concerns=$8, errors=$9, updated_at=now()
+WHERE id=$1
+`, h.ID, h.Phase, h.OSType, h.Sources, h.Events, h.Keys, h.Edges, h.Concerns, h.Errors)
+	if err != nil {
+		return fmt.Errorf("save scan_job_host: %w", err)
+	}
+	return nil
+}
+
+// ListScanJobHosts returns the job's hosts in BFS order. With a non-zero since
+// only rows updated at or after it are returned, in (updated_at, id) order,
+// for streaming progress. updated_at is the saving transaction's start, so a
+// row can commit after later ones: streamers re-read a window before their
+// cursor and skip the versions they already sent.
+func (s *Store) ListScanJobHosts(ctx context.Context, jobID int64, since time.Time) ([]ScanJobHost, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+scanJobHostCols+`
+FROM scan_job_hosts
+WHERE job_id=$1 AND ($2::timestamptz IS NULL OR updated_at >= $2)
+ORDER BY CASE WHEN $2::timestamptz IS NULL THEN NULL ELSE updated_at END, id
+`, jobID, nullTime(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScanJobHost
+	for rows.Next() {
+		h, err := scanScanJobHost(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *h)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) ScanJobTotals(ctx context.Context, jobID int64) (*ScanJobTotals, error) {
+	var t ScanJobTotals
+	err := s.db.Pool.QueryRow(ctx, `
+SELECT count(*) FILTER (WHERE phase NOT IN ('queued','out_of_scope')),
+       COALESCE(sum(events),0), COALESCE(sum(keys),0), COALESCE(sum(edges),0), COALESCE(sum(concerns),0)
+FROM scan_job_hosts WHERE job_id=$1
+`, jobID).Scan(&t.HostsVisited, &t.EventsInserted, &t.KeysSeen, &t.EdgesUpserted, &t.ConcernsRaised)
+	if err != nil {
+		return nil, fmt.Errorf("scan job totals: %w", err)
+	}
+	return &t, nil
+}
@@ -2,82 +2,405 @@ package store
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type ScanJob struct {
-	ID          int64      `json:"id"`
-	Kind        string     `json:"kind"`
-	TargetHost  string     `json:"target_host"`
-	SinceSec    int        `json:"since_interval_seconds"`
-	SpiderDepth int        `json:"spider_depth"`
-	Status      string     `json:"status"`
-	Error       *string    `json:"error"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at"`
-	FinishedAt  *time.Time `json:"finished_at"`
-}
-
-func (s *Store) EnqueueScanJob(ctx context.Context, host string, since time.Duration, depth int) (int64, error) {
+	ID             int64           `json:"id"`
+	Kind           string          `json:"kind"`
+	TargetHost     string          `json:"target_host"`
+	SinceSec       int             `json:"since_interval_seconds"`
+	SpiderDepth    int             `json:"spider_depth"`
+	Status         string          `json:"status"`
+	Error          *string         `json:"error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	StartedAt      *time.Time      `json:"started_at"`
+	FinishedAt     *time.Time      `json:"finished_at"`
+	WorkerID       *string         `json:"worker_id"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at"`
+	HeartbeatAt    *time.Time      `json:"heartbeat_at"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	Priority       int             `json:"priority"`
+	CancelRequest  *time.Time      `json:"cancel_requested_at"`
+	Result         json.RawMessage `json:"result"`
+	Params         json.RawMessage `json:"params"`
+	ParentJobID    *int64          `json:"parent_job_id"`
+}
+
+// Job kinds. scan targets one host; rescan_all and key_findings take no
+// hosts; the rest work on an explicit host list in params.
+const (
+	JobKindScan           = "scan"
+	JobKindAuthorizedKeys = "authorized_keys"
+	JobKindKeyHunt        = "keyhunt"
+	JobKindConfigAudit    = "config_audit"
+	JobKindRescanAll      = "rescan_all"
+	JobKindKeyFindings    = "key_findings"
+)
+
+var JobKinds = []string{JobKindScan, JobKindAuthorizedKeys, JobKindKeyHunt, JobKindConfigAudit, JobKindRescanAll, JobKindKeyFindings}
+
+// JobParams is the JSON stored in scan_jobs.params. Which fields apply depends on the kind.
+type JobParams struct {
+	Hosts []string `json:"hosts,omitempty"` // authorized_keys, keyhunt, config_audit
+	// rescan_all: kind of the child job enqueued per reachable host (default scan).
+	ChildKind string `json:"child_kind,omitempty"`
+}
+
+// JobSpec describes a job to enqueue.
+type JobSpec struct {
+	Kind        string
+	TargetHost  string
+	Since       time.Duration
+	SpiderDepth int
+	Priority    int
+	Params      JobParams
+	ParentJobID *int64
+}
+
+type ScanJobFilter struct {
+	Status string
+	Host   string
+	Limit  int
+}
+
+// ErrLeaseLost is returned when a worker no longer owns the job it is running
+// (the lease expired and the reaper requeued or failed it).
+var ErrLeaseLost = errors.New("scan job lease lost")
+
+// ErrScanJobNotFound is returned for a scan job id that does not exist.
+var ErrScanJobNotFound = errors.New("scan job not found")
+
+const scanJobCols = `id, kind, target_host, since_interval_seconds, spider_depth, status, error, created_at, started_at, finished_at,
+  worker_id, lease_expires_at, heartbeat_at, attempts, max_attempts, priority, cancel_requested_at, result, params, parent_job_id`
+
+func scanScanJob(row pgx.Row) (*ScanJob, error) {
+	var j ScanJob
+	if err := row.Scan(&j.ID, &j.Kind, &j.TargetHost, &j.SinceSec, &j.SpiderDepth, &j.Status, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt,
+		&j.WorkerID, &j.LeaseExpiresAt, &j.HeartbeatAt, &j.Attempts, &j.MaxAttempts, &j.Priority, &j.CancelRequest, &j.Result, &j.Params, &j.ParentJobID); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// EnqueueScanJob queues a scan. Jobs with a higher priority are claimed first.
+func (s *Store) EnqueueScanJob(ctx context.Context, host string, since time.Duration, depth int, priority int) (int64, error) {
+	return s.EnqueueJob(ctx, JobSpec{Kind: JobKindScan, TargetHost: host, Since: since, SpiderDepth: depth, Priority: priority})
+}
+
+// Validate checks that spec names a known kind with the inputs it needs.
+func (spec *JobSpec) Validate() error {
+	switch spec.Kind {
+	case JobKindScan:
+		if spec.TargetHost == "" {
+			return errors.New("scan job requires a host")
+		}
+	case JobKindAuthorizedKeys, JobKindKeyHunt, JobKindConfigAudit:
+		if len(spec.Params.Hosts) == 0 {
+			return fmt.Errorf("%s job requires at least one host", spec.Kind)
+		}
+	case JobKindRescanAll:
+		switch spec.Params.ChildKind {
+		case "", JobKindScan, JobKindAuthorizedKeys, JobKindKeyHunt, JobKindConfigAudit:
+		default:
+			return fmt.Errorf("rescan_all: bad child_kind %q", spec.Params.ChildKind)
+		}
+	case JobKindKeyFindings:
+	default:
+		return fmt.Errorf("unknown job kind %q", spec.Kind)
+	}
+	return nil
+}
+
+// EnqueueJob queues a job of any kind. For host-list kinds target_host is set
+// to the first host, so job listings and the host filter stay useful.
+func (s *Store) EnqueueJob(ctx context.Context, spec JobSpec) (int64, error) {
+	if err := spec.Validate(); err != nil {
+		return 0, err
+	}
+	if spec.TargetHost == "" {
+		switch {
+		case spec.Kind == JobKindRescanAll, spec.Kind == JobKindKeyFindings:
+			spec.TargetHost = "*"
+		case len(spec.Params.Hosts) > 0:
+			spec.TargetHost = spec.Params.Hosts[0]
+		}
+	}
 	var id int64
-	sinceSec := int(since.Seconds())
+	sinceSec := int(spec.Since.Seconds())
 	if sinceSec <= 0 {
 		sinceSec = 3600
 	}
-	err := s.db.Pool.QueryRow(ctx, `
-INSERT INTO scan_jobs(kind, target_host, since_interval_seconds, spider_depth, status)
-VALUES ('scan', $1, $2, $3, 'queued')
+	params, err := json.Marshal(spec.Params)
+	if err != nil {
+		return 0, err
+	}
+	err = s.db.Pool.QueryRow(ctx, `
+INSERT INTO scan_jobs(kind, target_host, since_interval_seconds, spider_depth, status, priority, params, parent_job_id)
+VALUES ($1, $2, $3, $4, 'queued', $5, $6, $7)
 RETURNING id;
-`, host, sinceSec, depth).Scan(&id)
+`, spec.Kind, spec.TargetHost, sinceSec, spec.SpiderDepth, spec.Priority, params, spec.ParentJobID).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("enqueue scan job: %w", err)
+		return 0, fmt.Errorf("enqueue %s job: %w", spec.Kind, err)
 	}
 	return id, nil
 }
 
-// ClaimNextScanJob atomically claims a queued job.
-func (s *Store) ClaimNextScanJob(ctx context.Context) (*ScanJob, error) {
+// ChildJobHosts returns the target hosts of jobs already enqueued by parentID,
+// so a resumed fan-out does not enqueue them twice.
+func (s *Store) ChildJobHosts(ctx context.Context, parentID int64) (map[string]bool, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT target_host FROM scan_jobs WHERE parent_job_id=$1`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("list child jobs: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		out[h] = true
+	}
+	return out, rows.Err()
+}
+
+// JobParams decodes j.Params.
+func (j *ScanJob) JobParams() (JobParams, error) {
+	var p JobParams
+	if len(j.Params) == 0 {
+		return p, nil
+	}
+	if err := json.Unmarshal(j.Params, &p); err != nil {
+		return p, fmt.Errorf("job %d params: %w", j.ID, err)
+	}
+	return p, nil
+}
+
+// ClaimNextScanJob atomically claims a queued job for workerID and takes a lease on it.
+func (s *Store) ClaimNextScanJob(ctx context.Context, workerID string, lease time.Duration) (*ScanJob, error) {
 	row := s.db.Pool.QueryRow(ctx, `
 WITH next AS (
   SELECT id FROM scan_jobs
   WHERE status='queued'
-  ORDER BY created_at ASC
+  ORDER BY priority DESC, created_at ASC
   LIMIT 1
   FOR UPDATE SKIP LOCKED
 )
 UPDATE scan_jobs j
-SET status='running', started_at=now()
+SET status='running', started_at=now(), worker_id=$1,
+    lease_expires_at=now() + make_interval(secs => $2), heartbeat_at=now(),
+    attempts=j.attempts+1
 FROM next
 WHERE j.id=next.id
-RETURNING j.id, j.kind, j.target_host, j.since_interval_seconds, j.spider_depth, j.status, j.error, j.created_at, j.started_at, j.finished_at;
-`)
+RETURNING `+prefixCols("j.", scanJobCols)+`;
+`, workerID, lease.Seconds())
+	return scanScanJob(row)
+}
 
-	var j ScanJob
-	if err := row.Scan(&j.ID, &j.Kind, &j.TargetHost, &j.SinceSec, &j.SpiderDepth, &j.Status, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt); err != nil {
-		return nil, err
+// HeartbeatScanJob extends the lease of a running job. It returns ErrLeaseLost
+// if workerID no longer owns the job.
+func (s *Store) HeartbeatScanJob(ctx context.Context, id int64, workerID string, lease time.Duration) error {
+	tag, err := s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs SET heartbeat_at=now(), lease_expires_at=now() + make_interval(secs => $3)
+WHERE id=$1 AND worker_id=$2 AND status='running'
+`, id, workerID, lease.Seconds())
+	if err != nil {
+		return fmt.Errorf("heartbeat scan job: %w", err)
 	}
-	return &j, nil
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
 
-func (s *Store) FinishScanJob(ctx context.Context, id int64, jobErr error) error {
+// FinishScanJob records the outcome of a job still owned by workerID.
+func (s *Store) FinishScanJob(ctx context.Context, id int64, workerID string, jobErr error) error {
+	var tag pgconn.CommandTag
+	var err error
 	if jobErr == nil {
-		_, err := s.db.Pool.Exec(ctx, `UPDATE scan_jobs SET status='done', finished_at=now() WHERE id=$1`, id)
+		tag, err = s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs SET status='done', finished_at=now(), lease_expires_at=NULL
+WHERE id=$1 AND worker_id=$2 AND status='running'`, id, workerID)
+	} else {
+		// A job stopped by a cancel request ends as cancelled, not error.
+		msg := jobErr.Error()
+		tag, err = s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs
+SET status=CASE WHEN cancel_requested_at IS NOT NULL THEN 'cancelled' ELSE 'error' END,
+    error=$3, finished_at=now(), lease_expires_at=NULL
+WHERE id=$1 AND worker_id=$2 AND status='running'`, id, workerID, msg)
+	}
+	if err != nil {
 		return err
 	}
-	msg := jobErr.Error()
-	_, err := s.db.Pool.Exec(ctx, `UPDATE scan_jobs SET status='error', error=$2, finished_at=now() WHERE id=$1`, id, msg)
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// SetScanJobResult stores the job's ScanResult (any JSON-encodable value).
+func (s *Store) SetScanJobResult(ctx context.Context, id int64, result any) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Pool.Exec(ctx, `UPDATE scan_jobs SET result=$2 WHERE id=$1`, id, b)
 	return err
 }
 
+// RequeueScanJob hands a job back to the queue after a graceful shutdown. The
+// attempt is not counted, and its scan_job_hosts checkpoint is kept so the
+// next worker resumes it.
+func (s *Store) RequeueScanJob(ctx context.Context, id int64, workerID string) error {
+	_, err := s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs SET status='queued', worker_id=NULL, lease_expires_at=NULL, attempts=GREATEST(attempts-1, 0)
+WHERE id=$1 AND worker_id=$2 AND status='running'`, id, workerID)
+	return err
+}
+
+// ReapExpiredScanJobs requeues running jobs whose lease expired, or fails them
+// once they have used max_attempts. Safe to run from several processes.
+func (s *Store) ReapExpiredScanJobs(ctx context.Context) (requeued, failed int64, err error) {
+	_, err = s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs
+SET status='cancelled', error='cancelled (worker lease expired)', finished_at=now(), lease_expires_at=NULL
+WHERE status='running' AND lease_expires_at < now() AND cancel_requested_at IS NOT NULL`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cancel expired scan jobs: %w", err)
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs
+SET status='error', error='lease expired after ' || attempts || ' attempt(s) (last worker ' || COALESCE(worker_id, '?') || ')',
+    finished_at=now(), lease_expires_at=NULL
+WHERE status='running' AND lease_expires_at < now() AND attempts >= max_attempts`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail expired scan jobs: %w", err)
+	}
+	failed = tag.RowsAffected()
+
+	tag, err = s.db.Pool.Exec(ctx, `
+UPDATE scan_jobs
+SET status='queued', worker_id=NULL, lease_expires_at=NULL
+WHERE status='running' AND lease_expires_at < now() AND attempts < max_attempts`)
+	if err != nil {
+		return 0, failed, fmt.Errorf("requeue expired scan jobs: %w", err)
+	}
+	return tag.RowsAffected(), failed, nil
+}
+
 func (s *Store) GetScanJob(ctx context.Context, id int64) (*ScanJob, error) {
-	row := s.db.Pool.QueryRow(ctx, `
-SELECT id, kind, target_host, since_interval_seconds, spider_depth, status, error, created_at, started_at, finished_at
-FROM scan_jobs WHERE id=$1
-`, id)
-	var j ScanJob
-	if err := row.Scan(&j.ID, &j.Kind, &j.TargetHost, &j.SinceSec, &j.SpiderDepth, &j.Status, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt); err != nil {
+	row := s.db.Pool.QueryRow(ctx, `SELECT `+scanJobCols+` FROM scan_jobs WHERE id=$1`, id)
+	return scanScanJob(row)
+}
+
+func (s *Store) ListScanJobs(ctx context.Context, f ScanJobFilter) ([]ScanJob, error) {
+	if f.Limit <= 0 {
+		f.Limit = 200
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+scanJobCols+`
+FROM scan_jobs
+WHERE ($1 = '' OR status = $1)
+  AND ($2 = '' OR target_host = $2)
+ORDER BY created_at DESC
+LIMIT $3
+`, f.Status, f.Host, f.Limit)
+	if err != nil {
 		return nil, err
 	}
-	return &j, nil
+	defer rows.Close()
+	var out []ScanJob
+	for rows.Next() {
+		j, err := scanScanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *j)
+	}
+	return out, rows.Err()
+}
+
+// CancelScanJob cancels a queued job immediately, or flags a running job so
+// its worker stops it at the next cancel check. It returns the resulting status.
+func (s *Store) CancelScanJob(ctx context.Context, id int64) (string, error) {
+	var status string
+	err := s.db.Pool.QueryRow(ctx, `
+UPDATE scan_jobs
+SET status=CASE WHEN status='queued' THEN 'cancelled' ELSE status END,
+    finished_at=CASE WHEN status='queued' THEN now() ELSE finished_at END,
+    cancel_requested_at=COALESCE(cancel_requested_at, now())
+WHERE id=$1 AND status IN ('queued','running')
+RETURNING status
+`, id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("scan job %d is not queued or running", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cancel scan job: %w", err)
+	}
+	return status, nil
+}
+
+// ScanJobCancelRequested reports whether a cancel was requested for the job.
+func (s *Store) ScanJobCancelRequested(ctx context.Context, id int64) (bool, error) {
+	var req bool
+	err := s.db.Pool.QueryRow(ctx, `SELECT cancel_requested_at IS NOT NULL FROM scan_jobs WHERE id=$1`, id).Scan(&req)
+	return req, err
+}
+
+// RetryScanJob requeues a finished (error/cancelled/done) job with a fresh
+// attempt budget. An error or cancelled job resumes from its checkpoint, with
+// the hosts it had not finished started over (their counts and sources
+// cleared, so the rerun is not counted twice); a done job, or any job with
+// fresh set, starts the scan over.
+func (s *Store) RetryScanJob(ctx context.Context, id int64, fresh bool) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var prev string
+	err = tx.QueryRow(ctx, `SELECT status FROM scan_jobs WHERE id=$1 FOR UPDATE`, id).Scan(&prev)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("scan job %d: %w", id, ErrScanJobNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("retry scan job: %w", err)
+	}
+	if prev != "error" && prev != "cancelled" && prev != "done" {
+		return fmt.Errorf("scan job %d is not finished", id)
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE scan_jobs
+SET status='queued', error=NULL, started_at=NULL, finished_at=NULL, worker_id=NULL,
+    lease_expires_at=NULL, heartbeat_at=NULL, attempts=0, cancel_requested_at=NULL
+WHERE id=$1
+`, id); err != nil {
+		return fmt.Errorf("retry scan job: %w", err)
+	}
+	if fresh || prev == "done" {
+		if _, err := tx.Exec(ctx, `DELETE FROM scan_job_hosts WHERE job_id=$1`, id); err != nil {
+			return fmt.Errorf("clear checkpoint: %w", err)
+		}
+	} else {
+		if _, err := tx.Exec(ctx, `
+UPDATE scan_job_hosts
+SET phase='queued', errors='{}', sources='{}', events=0, keys=0, edges=0, concerns=0, updated_at=now()
+WHERE job_id=$1 AND phase NOT IN ('done','unreachable','out_of_scope')
+`, id); err != nil {
+			return fmt.Errorf("reset checkpoint: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
 }
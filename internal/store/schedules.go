@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Schedule actions.
+const (
+	ScheduleActionJob    = "job"
+	ScheduleActionExport = "export"
+)
+
+// Misfire policies: what to do when a schedule is found more than the grace
+// period past its fire time (daemon down, no leader). Missed runs are never
+// replayed one by one.
+const (
+	MisfireRunOnce = "run_once" // fire once now, then resume the normal cadence
+	MisfireSkip    = "skip"     // drop the missed run and wait for the next one
+)
+
+// ScheduleSpec is the JSON stored in schedules.spec. Job fields apply to
+// action=job, Format and Limit to action=export.
+type ScheduleSpec struct {
+	Kind         string   `json:"kind,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	Hosts        []string `json:"hosts,omitempty"`
+	ChildKind    string   `json:"child_kind,omitempty"`
+	SinceSeconds int      `json:"since_seconds,omitempty"`
+	SpiderDepth  int      `json:"spider_depth,omitempty"`
+	Priority     int      `json:"priority,omitempty"`
+
+	Format string `json:"format,omitempty"` // json|csv|graphml
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// JobSpec converts a job schedule to the job it enqueues.
+func (sp ScheduleSpec) JobSpec() JobSpec {
+	since := sp.SinceSeconds
+	if since <= 0 {
+		since = 168 * 3600
+	}
+	js := JobSpec{
+		Kind:        sp.Kind,
+		Since:       time.Duration(since) * time.Second,
+		SpiderDepth: sp.SpiderDepth,
+		Priority:    sp.Priority,
+		Params:      JobParams{Hosts: sp.Hosts, ChildKind: sp.ChildKind},
+	}
+	if js.Kind == "" {
+		js.Kind = JobKindScan
+	}
+	if js.Kind == JobKindScan {
+		js.TargetHost = sp.Host
+	} else if sp.Host != "" {
+		js.Params.Hosts = append([]string{sp.Host}, js.Params.Hosts...)
+	}
+	return js
+}
+
+type Schedule struct {
+	ID            int64        `json:"id"`
+	Name          string       `json:"name"`
+	Cron          string       `json:"cron"`
+	Timezone      string       `json:"timezone"`
+	Action        string       `json:"action"`
+	Spec          ScheduleSpec `json:"spec"`
+	MisfirePolicy string       `json:"misfire_policy"`
+	Enabled       bool         `json:"enabled"`
+	NextRunAt     time.Time    `json:"next_run_at"`
+	LastRunAt     *time.Time   `json:"last_run_at"`
+	LastStatus    *string      `json:"last_status"`
+	LastError     *string      `json:"last_error"`
+	LastJobID     *int64       `json:"last_job_id"`
+	LastOutput    *string      `json:"last_output"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+const scheduleCols = `id, name, cron, timezone, action, spec, misfire_policy, enabled, next_run_at,
+  last_run_at, last_status, last_error, last_job_id, last_output, created_at, updated_at`
+
+func scanSchedule(row pgx.Row) (*Schedule, error) {
+	var sc Schedule
+	if err := row.Scan(&sc.ID, &sc.Name, &sc.Cron, &sc.Timezone, &sc.Action, &sc.Spec, &sc.MisfirePolicy, &sc.Enabled, &sc.NextRunAt,
+		&sc.LastRunAt, &sc.LastStatus, &sc.LastError, &sc.LastJobID, &sc.LastOutput, &sc.CreatedAt, &sc.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// CreateSchedule inserts sc (NextRunAt must already be computed) and returns its id.
+func (s *Store) CreateSchedule(ctx context.Context, sc *Schedule) (int64, error) {
+	var id int64
+	err := s.db.Pool.QueryRow(ctx, `
+INSERT INTO schedules(name, cron, timezone, action, spec, misfire_policy, enabled, next_run_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+RETURNING id
+`, sc.Name, sc.Cron, sc.Timezone, sc.Action, sc.Spec, sc.MisfirePolicy, sc.Enabled, sc.NextRunAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create schedule: %w", err)
+	}
+	return id, nil
+}
+
+func (s *Store) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+scheduleCols+` FROM schedules ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Schedule
+	for rows.Next() {
+		sc, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sc)
+	}
+	return out, rows.Err()
+}
+
+// GetSchedule looks a schedule up by name. It returns nil if there is none.
+func (s *Store) GetSchedule(ctx context.Context, name string) (*Schedule, error) {
+	sc, err := scanSchedule(s.db.Pool.QueryRow(ctx, `SELECT `+scheduleCols+` FROM schedules WHERE name=$1`, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return sc, err
+}
+
+// DeleteSchedule removes a schedule; it reports whether one existed.
+func (s *Store) DeleteSchedule(ctx context.Context, name string) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM schedules WHERE name=$1`, name)
+	if err != nil {
+		return false, fmt.Errorf("delete schedule: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetScheduleEnabled pauses or resumes a schedule. Resuming sets next_run_at
+// (computed by the caller from now) so runs missed while paused are not misfires.
+func (s *Store) SetScheduleEnabled(ctx context.Context, name string, enabled bool, next time.Time) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `
+UPDATE schedules
+SET enabled=$2, next_run_at=CASE WHEN $2 THEN $3 ELSE next_run_at END, updated_at=now()
+WHERE name=$1`, name, enabled, next)
+	if err != nil {
+		return false, fmt.Errorf("set schedule enabled: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// TriggerSchedule makes an enabled schedule due now.
+func (s *Store) TriggerSchedule(ctx context.Context, name string) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `UPDATE schedules SET next_run_at=now(), updated_at=now() WHERE name=$1 AND enabled`, name)
+	if err != nil {
+		return false, fmt.Errorf("trigger schedule: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ScheduleDecision is what the scheduler decides for a due schedule.
+type ScheduleDecision struct {
+	Next time.Time // new next_run_at
+	Fire bool      // false: the run is skipped (misfire policy skip, or an invalid schedule)
+	Note string    // recorded as last_error when not firing
+}
+
+// ClaimDueSchedules advances every enabled schedule whose next_run_at has
+// passed, using decide to compute the next run, and returns the schedules
+// that should fire (with NextRunAt still set to the run being fired).
+//
+// Only one process claims at a time: the claim runs under a transaction-level
+// advisory lock, and a caller that cannot take it claims nothing. Because
+// next_run_at is advanced in the same transaction, each run fires once even
+// with several keyspiderd replicas.
+func (s *Store) ClaimDueSchedules(ctx context.Context, decide func(*Schedule) ScheduleDecision) ([]Schedule, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('keyspider_scheduler'))`).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("scheduler lock: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+scheduleCols+` FROM schedules WHERE enabled AND next_run_at <= now() ORDER BY next_run_at FOR UPDATE`)
+	if err != nil {
+		return nil, fmt.Errorf("list due schedules: %w", err)
+	}
+	var due []Schedule
+	for rows.Next() {
+		sc, err := scanSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, *sc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var fire []Schedule
+	for i := range due {
+		sc := &due[i]
+		d := decide(sc)
+		if d.Fire {
+			_, err = tx.Exec(ctx, `UPDATE schedules SET next_run_at=$2, last_run_at=now(), updated_at=now() WHERE id=$1`, sc.ID, d.Next)
+			fire = append(fire, *sc)
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE schedules SET next_run_at=$2, last_status='skipped', last_error=$3, updated_at=now() WHERE id=$1`, sc.ID, d.Next, d.Note)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("advance schedule %s: %w", sc.Name, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return fire, nil
+}
+
+// FinishScheduleRun records the outcome of a fired schedule.
+func (s *Store) FinishScheduleRun(ctx context.Context, id int64, runErr error, jobID *int64, output *string) error {
+	status := "ok"
+	var msg *string
+	if runErr != nil {
+		status = "error"
+		m := runErr.Error()
+		msg = &m
+	}
+	_, err := s.db.Pool.Exec(ctx, `
+UPDATE schedules SET last_status=$2, last_error=$3, last_job_id=COALESCE($4, last_job_id), last_output=COALESCE($5, last_output), updated_at=now()
+WHERE id=$1`, id, status, msg, jobID, output)
+	if err != nil {
+		return fmt.Errorf("finish schedule run: %w", err)
+	}
+	return nil
+}
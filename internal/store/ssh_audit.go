@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SSHAudit struct {
+	ID            int64     `json:"id"`
+	TS            time.Time `json:"ts"`
+	Host          string    `json:"host"`
+	CommandSHA256 string    `json:"command_sha256"`
+	Command       string    `json:"command"`
+	Initiator     string    `json:"initiator"`
+	Mode          string    `json:"mode"`
+	ExitStatus    int       `json:"exit_status"`
+	DurationMS    int64     `json:"duration_ms"`
+	BytesReturned int64     `json:"bytes_returned"`
+	Error         *string   `json:"error"`
+	PrevHash      *string   `json:"prev_hash"`
+	RowHash       *string   `json:"row_hash"`
+	HashVersion   int       `json:"hash_version"`
+}
+
+type SSHAuditFilter struct {
+	Host  string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// auditHashVersion is the row hash new rows are chained with. Version 1 left
+// out the error column; version 2 covers it.
+const auditHashVersion = 2
+
+// auditRowHash chains a row to its predecessor: sha256(prev || fields).
+func auditRowHash(prev string, a *SSHAudit) string {
+	fields := []string{
+		prev,
+		a.TS.UTC().Format(time.RFC3339Nano),
+		a.Host,
+		a.CommandSHA256,
+		a.Initiator,
+		a.Mode,
+		strconv.Itoa(a.ExitStatus),
+		strconv.FormatInt(a.DurationMS, 10),
+		strconv.FormatInt(a.BytesReturned, 10),
+	}
+	if a.HashVersion >= 2 {
+		// "-" for no error, so a NULL error differs from an empty one.
+		errField := "-"
+		if a.Error != nil {
+			errField = "+" + *a.Error
+		}
+		fields = append(fields, strconv.Itoa(a.HashVersion), errField)
+	}
+	h := sha256.New()
+	for _, f := range fields {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// InsertSSHAudit appends an audit row. With chain=true the row is linked to the
+// previous chained row under an advisory lock so concurrent writers serialize.
+func (s *Store) InsertSSHAudit(ctx context.Context, a *SSHAudit, chain bool) (int64, error) {
+	if a.CommandSHA256 == "" {
+		sum := sha256.Sum256([]byte(a.Command))
+		a.CommandSHA256 = hex.EncodeToString(sum[:])
+	}
+	if a.TS.IsZero() {
+		a.TS = time.Now().UTC()
+	}
+	// Postgres stores microseconds; truncate so the chain verifies after a round trip.
+	a.TS = a.TS.Truncate(time.Microsecond)
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if chain {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('ssh_audit_chain'))`); err != nil {
+			return 0, fmt.Errorf("lock audit chain: %w", err)
+		}
+		var prev string
+		err := tx.QueryRow(ctx, `SELECT row_hash FROM ssh_audit WHERE row_hash IS NOT NULL ORDER BY id DESC LIMIT 1`).Scan(&prev)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("read audit chain head: %w", err)
+		}
+		a.HashVersion = auditHashVersion
+		rh := auditRowHash(prev, a)
+		a.PrevHash = &prev
+		a.RowHash = &rh
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+INSERT INTO ssh_audit(ts, host, command_sha256, command, initiator, mode, exit_status, duration_ms, bytes_returned, error, prev_hash, row_hash, hash_version)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+RETURNING id;
+`, a.TS, a.Host, a.CommandSHA256, a.Command, a.Initiator, a.Mode, a.ExitStatus, a.DurationMS, a.BytesReturned, a.Error, a.PrevHash, a.RowHash, max(a.HashVersion, 1)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert ssh_audit: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit ssh_audit: %w", err)
+	}
+	return id, nil
+}
+
+func (s *Store) ListSSHAudit(ctx context.Context, f SSHAuditFilter) ([]SSHAudit, error) {
+	if f.Limit <= 0 {
+		f.Limit = 500
+	}
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT id, ts, host, command_sha256, command, initiator, mode, exit_status, duration_ms, bytes_returned, error, prev_hash, row_hash, hash_version
+FROM ssh_audit
+WHERE ($1 = '' OR host = $1)
+  AND ($2::timestamptz IS NULL OR ts >= $2)
+  AND ($3::timestamptz IS NULL OR ts < $3)
+ORDER BY ts DESC, id DESC
+LIMIT $4
+`, f.Host, from, to, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SSHAudit
+	for rows.Next() {
+		var a SSHAudit
+		if err := rows.Scan(&a.ID, &a.TS, &a.Host, &a.CommandSHA256, &a.Command, &a.Initiator, &a.Mode, &a.ExitStatus, &a.DurationMS, &a.BytesReturned, &a.Error, &a.PrevHash, &a.RowHash, &a.HashVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// VerifySSHAuditChain walks every chained row in id order and returns the id of
+// the first row whose hash does not match (0 if the chain is intact) and the
+// number of rows checked.
+func (s *Store) VerifySSHAuditChain(ctx context.Context) (badID int64, checked int, err error) {
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT id, ts, host, command_sha256, command, initiator, mode, exit_status, duration_ms, bytes_returned, error, prev_hash, row_hash, hash_version
+FROM ssh_audit
+WHERE row_hash IS NOT NULL
+ORDER BY id ASC
+`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	prev := ""
+	for rows.Next() {
+		var a SSHAudit
+		if err := rows.Scan(&a.ID, &a.TS, &a.Host, &a.CommandSHA256, &a.Command, &a.Initiator, &a.Mode, &a.ExitStatus, &a.DurationMS, &a.BytesReturned, &a.Error, &a.PrevHash, &a.RowHash, &a.HashVersion); err != nil {
+			return 0, checked, err
+		}
+		checked++
+		sum := sha256.Sum256([]byte(a.Command))
+		if hex.EncodeToString(sum[:]) != a.CommandSHA256 {
+			return a.ID, checked, nil
+		}
+		if a.PrevHash == nil || *a.PrevHash != prev || *a.RowHash != auditRowHash(prev, &a) {
+			return a.ID, checked, nil
+		}
+		prev = *a.RowHash
+	}
+	return 0, checked, rows.Err()
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type SSHDConfig struct {
+	HostID      int64             `json:"host_id"`
+	Source      string            `json:"source"`
+	Settings    map[string]string `json:"settings"`
+	CollectedAt time.Time         `json:"collected_at"`
+}
+
+// UpsertSSHDConfig stores the latest sshd settings collected from a host.
+func (s *Store) UpsertSSHDConfig(ctx context.Context, hostID int64, source string, settings map[string]string) error {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Pool.Exec(ctx, `
+INSERT INTO sshd_configs(host_id, source, settings, collected_at)
+VALUES ($1,$2,$3, now())
+ON CONFLICT (host_id) DO UPDATE SET source=EXCLUDED.source, settings=EXCLUDED.settings, collected_at=now()
+`, hostID, source, b)
+	if err != nil {
+		return fmt.Errorf("upsert sshd_config: %w", err)
+	}
+	return nil
+}
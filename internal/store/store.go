@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
 )
 
@@ -18,30 +21,47 @@ type Host struct {
 	FQDN              *string    `json:"fqdn"`
 	OSType            string     `json:"os_type"`
 	ReachableFromJump bool       `json:"reachable_from_jump"`
+	ReachReason       *string    `json:"reach_reason"` // why the last probe failed
+	Tags              []string   `json:"tags"`
+	Environment       *string    `json:"environment"`
+	OwnerTeam         *string    `json:"owner_team"`
+	IPAddresses       []string   `json:"ip_addresses"` // from inventory
+	InInventory       bool       `json:"in_inventory"`
 	CreatedAt         time.Time  `json:"created_at"`
 	LastSeen          *time.Time `json:"last_seen"`
+	LastScannedAt     *time.Time `json:"last_scanned_at"`
 }
 
 type AccessEvent struct {
-	ID          int64     `json:"id"`
-	TS          time.Time `json:"ts"`
-	DestHostID  int64     `json:"dest_host_id"`
-	DestUser    *string   `json:"dest_user"`
-	SourceHost  *string   `json:"source_host"`
-	SourceIP    *string   `json:"source_ip"`
-	SourcePort  *int      `json:"source_port"`
-	Fingerprint *string   `json:"fingerprint_sha256"`
-	AuthMethod  *string   `json:"auth_method"`
-	Result      *string   `json:"result"`
-	RawLine     string    `json:"raw_line"`
+	ID           int64     `json:"id"`
+	TS           time.Time `json:"ts"`
+	DestHostID   int64     `json:"dest_host_id"`
+	DestHostname string    `json:"dest_hostname"`
+	DestUser     *string   `json:"dest_user"`
+	SourceHost   *string   `json:"source_host"`
+	SourceIP     *string   `json:"source_ip"`
+	SourcePort   *int      `json:"source_port"`
+	Fingerprint  *string   `json:"fingerprint_sha256"`
+	AuthMethod   *string   `json:"auth_method"`
+	Result       *string   `json:"result"`
+	RawLine      string    `json:"raw_line"`
+	SSHDPID      *int      `json:"sshd_pid"` // from the journal, when known
+	BootID       *string   `json:"boot_id"`
+	KeyID        *int64    `json:"key_id"`          // ssh_keys row of the fingerprint
+	KeyInstance  *int64    `json:"key_instance_id"` // the authorized_keys entry that granted it
+	KeyPath      *string   `json:"key_path"`        // that entry's file
+	IdentityID   *int64    `json:"identity_id"`     // the key's owner, when known
+	Attribution  *string   `json:"attribution"`     // "alice@corp via laptop key"
 }
 
+// UpsertHost records hostname with its reachability; an empty osType keeps
+// the one already known.
 func (s *Store) UpsertHost(ctx context.Context, hostname string, fqdn *string, osType string, reachable bool) (int64, error) {
 	var id int64
 	err := s.db.Pool.QueryRow(ctx, `
 INSERT INTO hosts(hostname,fqdn,os_type,reachable_from_jump,last_seen)
-VALUES ($1,$2,$3,$4, now())
-ON CONFLICT (hostname) DO UPDATE SET fqdn=COALESCE(EXCLUDED.fqdn, hosts.fqdn), os_type=EXCLUDED.os_type, reachable_from_jump=EXCLUDED.reachable_from_jump, last_seen=now()
+VALUES ($1,$2,COALESCE(NULLIF($3,''), 'linux'),$4, now())
+ON CONFLICT (hostname) DO UPDATE SET fqdn=COALESCE(EXCLUDED.fqdn, hosts.fqdn), os_type=CASE WHEN $3 = '' THEN hosts.os_type ELSE EXCLUDED.os_type END, reachable_from_jump=EXCLUDED.reachable_from_jump, last_seen=now()
 RETURNING id;
 `, hostname, fqdn, osType, reachable).Scan(&id)
 	if err != nil {
@@ -50,13 +70,103 @@ RETURNING id;
 	return id, nil
 }
 
+// HostOSType returns hostname's os_type and when it was last read from the
+// host; checkedAt is zero when it never was (unknown host, or an inventory
+// value).
+func (s *Store) HostOSType(ctx context.Context, hostname string) (osType string, checkedAt time.Time, err error) {
+	var at *time.Time
+	err = s.db.Pool.QueryRow(ctx, `SELECT os_type, os_checked_at FROM hosts WHERE hostname=$1`, hostname).Scan(&osType, &at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("host os type: %w", err)
+	}
+	if at != nil {
+		checkedAt = *at
+	}
+	return osType, checkedAt, nil
+}
+
+// SetHostOSType records the os_type just read from hostname.
+func (s *Store) SetHostOSType(ctx context.Context, hostname, osType string) error {
+	_, err := s.db.Pool.Exec(ctx, `
+INSERT INTO hosts(hostname, os_type, os_checked_at, last_seen) VALUES ($1, $2, now(), now())
+ON CONFLICT (hostname) DO UPDATE SET os_type=EXCLUDED.os_type, os_checked_at=now()
+`, hostname, osType)
+	if err != nil {
+		return fmt.Errorf("set host os type: %w", err)
+	}
+	return nil
+}
+
+// TouchHost returns the id of hostname, creating it if needed, and bumps
+// last_seen without changing what is known about its reachability.
+func (s *Store) TouchHost(ctx context.Context, hostname string) (int64, error) {
+	var id int64
+	err := s.db.Pool.QueryRow(ctx, `
+INSERT INTO hosts(hostname, last_seen) VALUES ($1, now())
+ON CONFLICT (hostname) DO UPDATE SET last_seen=now()
+RETURNING id
+`, hostname).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("touch host: %w", err)
+	}
+	return id, nil
+}
+
+// MarkHostScanned records that hostID's logs or keys were just collected.
+func (s *Store) MarkHostScanned(ctx context.Context, hostID int64) error {
+	if _, err := s.db.Pool.Exec(ctx, `UPDATE hosts SET last_scanned_at=now() WHERE id=$1`, hostID); err != nil {
+		return fmt.Errorf("mark host scanned: %w", err)
+	}
+	return nil
+}
+
+// HostIPs returns the inventory addresses of hostname, or nil if the host is unknown.
+func (s *Store) HostIPs(ctx context.Context, hostname string) ([]string, error) {
+	var ips []string
+	err := s.db.Pool.QueryRow(ctx, `SELECT ip_addresses FROM hosts WHERE hostname=$1`, hostname).Scan(&ips)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("host ips: %w", err)
+	}
+	return ips, nil
+}
+
+// HostTags returns the inventory tags for hostname, or nil if the host is unknown.
+func (s *Store) HostTags(ctx context.Context, hostname string) ([]string, error) {
+	var tags []string
+	err := s.db.Pool.QueryRow(ctx, `SELECT tags FROM hosts WHERE hostname=$1`, hostname).Scan(&tags)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("host tags: %w", err)
+	}
+	return tags, nil
+}
+
+// InsertAccessEvent stores ev, linked to the key with its fingerprint and to
+// the authorized_keys entry for that key on the destination account, when
+// they are known. Keys found later are linked by UpsertSSHKey and
+// UpsertKeyInstance.
 func (s *Store) InsertAccessEvent(ctx context.Context, ev *AccessEvent) (int64, error) {
 	var id int64
 	err := s.db.Pool.QueryRow(ctx, `
-INSERT INTO access_events(ts, dest_host_id, dest_user, source_host, source_ip, source_port, fingerprint_sha256, auth_method, result, raw_line)
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
-RETURNING id;
-`, ev.TS, ev.DestHostID, ev.DestUser, ev.SourceHost, ev.SourceIP, ev.SourcePort, ev.Fingerprint, ev.AuthMethod, ev.Result, ev.RawLine).Scan(&id)
+INSERT INTO access_events(ts, dest_host_id, dest_user, source_host, source_ip, source_port, fingerprint_sha256, auth_method, result, raw_line, sshd_pid, boot_id,
+  key_id, key_instance_id)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,
+  (SELECT id FROM ssh_keys WHERE fingerprint_sha256 = $7),
+  (SELECT ki.id FROM key_instances ki JOIN ssh_keys k ON k.id = ki.key_id
+   WHERE k.fingerprint_sha256 = $7 AND ki.host_id = $2 AND ki.instance_type = 'authorized_key'
+     AND (ki.username IS NULL OR ki.username = $3)
+   ORDER BY (ki.username = $3) DESC NULLS LAST, ki.last_seen DESC
+   LIMIT 1))
+RETURNING id, key_id, key_instance_id;
+`, ev.TS, ev.DestHostID, ev.DestUser, ev.SourceHost, ev.SourceIP, ev.SourcePort, ev.Fingerprint, ev.AuthMethod, ev.Result, ev.RawLine, ev.SSHDPID, ev.BootID).Scan(&id, &ev.KeyID, &ev.KeyInstance)
 	if err != nil {
 		return 0, fmt.Errorf("insert access_event: %w", err)
 	}
@@ -64,15 +174,69 @@ RETURNING id;
 }
 
 func (s *Store) ListHosts(ctx context.Context, limit int) ([]Host, error) {
-	rows, err := s.db.Pool.Query(ctx, `SELECT id, hostname, fqdn, os_type, reachable_from_jump, created_at, last_seen FROM hosts ORDER BY hostname LIMIT $1`, limit)
+	return s.FindHosts(ctx, HostFilter{Limit: limit})
+}
+
+// HostFilter selects hosts; empty fields match everything.
+type HostFilter struct {
+	Tags        []string // hosts carrying all of these
+	Environment string
+	OwnerTeam   string
+	InInventory *bool
+	Limit       int
+}
+
+// FindHosts returns hosts matching f, by hostname.
+func (s *Store) FindHosts(ctx context.Context, f HostFilter) ([]Host, error) {
+	if f.Tags == nil {
+		f.Tags = []string{}
+	}
+	if f.Limit <= 0 {
+		f.Limit = 200
+	}
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+hostCols+`
+FROM hosts
+WHERE tags @> $1
+  AND ($2 = '' OR environment = $2)
+  AND ($3 = '' OR owner_team = $3)
+  AND ($4::boolean IS NULL OR in_inventory = $4)
+ORDER BY hostname
+LIMIT $5
+`, f.Tags, f.Environment, f.OwnerTeam, f.InInventory, f.Limit)
 	if err != nil {
 		return nil, err
 	}
+	return scanHosts(rows)
+}
+
+const hostCols = `id, hostname, fqdn, os_type, reachable_from_jump, reach_reason, tags, environment, owner_team, ip_addresses, in_inventory, created_at, last_seen, last_scanned_at`
+
+func scanHosts(rows pgx.Rows) ([]Host, error) {
 	defer rows.Close()
 	var out []Host
 	for rows.Next() {
 		var h Host
-		if err := rows.Scan(&h.ID, &h.Hostname, &h.FQDN, &h.OSType, &h.ReachableFromJump, &h.CreatedAt, &h.LastSeen); err != nil {
+		if err := rows.Scan(&h.ID, &h.Hostname, &h.FQDN, &h.OSType, &h.ReachableFromJump, &h.ReachReason, &h.Tags,
+			&h.Environment, &h.OwnerTeam, &h.IPAddresses, &h.InInventory, &h.CreatedAt, &h.LastSeen, &h.LastScannedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// ListReachableHostnames returns every known host last seen reachable from the jump server.
+func (s *Store) ListReachableHostnames(ctx context.Context) ([]string, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT hostname FROM hosts WHERE reachable_from_jump ORDER BY hostname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
 			return nil, err
 		}
 		out = append(out, h)
@@ -81,13 +245,27 @@ func (s *Store) ListHosts(ctx context.Context, limit int) ([]Host, error) {
 }
 
 func (s *Store) ListAccessEvents(ctx context.Context, hostID int64, limit int) ([]AccessEvent, error) {
+	return s.listAccessEvents(ctx, `e.dest_host_id=$1`, hostID, limit)
+}
+
+// ListKeyLogins returns the logins made with a key, newest first.
+func (s *Store) ListKeyLogins(ctx context.Context, fingerprint string, limit int) ([]AccessEvent, error) {
+	return s.listAccessEvents(ctx, `e.fingerprint_sha256=$1`, fingerprint, limit)
+}
+
+// listAccessEvents returns events matching where (on e, with $1 = arg), newest first.
+func (s *Store) listAccessEvents(ctx context.Context, where string, arg any, limit int) ([]AccessEvent, error) {
 	rows, err := s.db.Pool.Query(ctx, `
-SELECT id, ts, dest_host_id, dest_user, source_host, source_ip::text, source_port, fingerprint_sha256, auth_method, result, raw_line
-FROM access_events
-WHERE dest_host_id=$1
-ORDER BY ts DESC
+SELECT e.id, e.ts, e.dest_host_id, h.hostname, e.dest_user, e.source_host, e.source_ip::text, e.source_port, e.fingerprint_sha256, e.auth_method, e.result, e.raw_line, e.sshd_pid, e.boot_id,
+  e.key_id, e.key_instance_id, ki.path, o.identity_id, o.attribution
+FROM access_events e
+JOIN hosts h ON h.id = e.dest_host_id
+LEFT JOIN key_instances ki ON ki.id = e.key_instance_id
+LEFT JOIN LATERAL (`+fmt.Sprintf(bestOwnerSQL, "e.fingerprint_sha256")+`) o ON true
+WHERE `+where+`
+ORDER BY e.ts DESC
 LIMIT $2
-`, hostID, limit)
+`, arg, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -95,10 +273,19 @@ LIMIT $2
 	var out []AccessEvent
 	for rows.Next() {
 		var ev AccessEvent
-		if err := rows.Scan(&ev.ID, &ev.TS, &ev.DestHostID, &ev.DestUser, &ev.SourceHost, &ev.SourceIP, &ev.SourcePort, &ev.Fingerprint, &ev.AuthMethod, &ev.Result, &ev.RawLine); err != nil {
+		if err := rows.Scan(&ev.ID, &ev.TS, &ev.DestHostID, &ev.DestHostname, &ev.DestUser, &ev.SourceHost, &ev.SourceIP, &ev.SourcePort, &ev.Fingerprint, &ev.AuthMethod, &ev.Result, &ev.RawLine, &ev.SSHDPID, &ev.BootID, &ev.KeyID, &ev.KeyInstance, &ev.KeyPath, &ev.IdentityID, &ev.Attribution); err != nil {
 			return nil, err
 		}
 		out = append(out, ev)
 	}
 	return out, rows.Err()
 }
+
+// prefixCols qualifies a comma-separated column list with prefix (e.g. "j.").
+func prefixCols(prefix, cols string) string {
+	parts := strings.Split(cols, ",")
+	for i, p := range parts {
+		parts[i] = prefix + strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -4,8 +4,165 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// Watcher modes.
+var WatcherModes = []string{"auto", "journal", "tail"}
+
+// Watcher is a row of the watchers table with its host name.
+type Watcher struct {
+	HostID        int64      `json:"host_id"`
+	Host          string     `json:"host"`
+	Enabled       bool       `json:"enabled"`
+	Mode          string     `json:"mode"`
+	Source        string     `json:"source"` // config|api
+	Cursor        *string    `json:"cursor"`
+	LastHeartbeat *time.Time `json:"last_heartbeat"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	WatcherHealth
+}
+
+// WatcherHealth is the live state a running watcher reports every few
+// seconds (see last_heartbeat).
+type WatcherHealth struct {
+	Status      *string    `json:"status"`      // connecting|connected|backing_off|failed|stopped
+	ActiveMode  *string    `json:"active_mode"` // journal|tail
+	LastLineAt  *time.Time `json:"last_line_at"`
+	ConnectedAt *time.Time `json:"connected_at"`
+	Reconnects  int        `json:"reconnects"`
+	LastError   *string    `json:"last_error"`
+	LinesPerMin float64    `json:"lines_per_min"`
+	// Backoff and circuit breaker: the breaker opens after
+	// watcher.breaker_threshold consecutive failures.
+	BreakerState        *string    `json:"breaker_state"` // closed|open|half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	NextRetryAt         *time.Time `json:"next_retry_at"`
+}
+
+const watcherCols = `w.host_id, h.hostname, w.enabled, w.mode, w.source, w.cursor, w.last_heartbeat, w.created_at, w.updated_at,
+  w.status, w.active_mode, w.last_line_at, w.connected_at, w.reconnects, w.last_error, w.lines_per_min,
+  w.breaker_state, w.consecutive_failures, w.next_retry_at`
+
+func scanWatcher(row pgx.Row) (*Watcher, error) {
+	var w Watcher
+	if err := row.Scan(&w.HostID, &w.Host, &w.Enabled, &w.Mode, &w.Source, &w.Cursor, &w.LastHeartbeat, &w.CreatedAt, &w.UpdatedAt,
+		&w.Status, &w.ActiveMode, &w.LastLineAt, &w.ConnectedAt, &w.Reconnects, &w.LastError, &w.LinesPerMin,
+		&w.BreakerState, &w.ConsecutiveFailures, &w.NextRetryAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// UpdateWatcherHealth records a running watcher's state and heartbeat.
+func (s *Store) UpdateWatcherHealth(ctx context.Context, hostID int64, h WatcherHealth) error {
+	_, err := s.db.Pool.Exec(ctx, `
+UPDATE watchers
+SET status=$2, active_mode=$3, last_line_at=$4, connected_at=$5, reconnects=$6, last_error=$7, lines_per_min=$8,
+    breaker_state=$9, consecutive_failures=$10, next_retry_at=$11, last_heartbeat=now()
+WHERE host_id=$1`, hostID, h.Status, h.ActiveMode, h.LastLineAt, h.ConnectedAt, h.Reconnects, h.LastError, h.LinesPerMin,
+		h.BreakerState, h.ConsecutiveFailures, h.NextRetryAt)
+	if err != nil {
+		return fmt.Errorf("update watcher health: %w", err)
+	}
+	return nil
+}
+
+// ListWatchers returns every watcher; with enabledOnly, just the ones that should run.
+func (s *Store) ListWatchers(ctx context.Context, enabledOnly bool) ([]Watcher, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+SELECT `+watcherCols+`
+FROM watchers w JOIN hosts h ON h.id = w.host_id
+WHERE (NOT $1 OR w.enabled)
+ORDER BY h.hostname
+`, enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("list watchers: %w", err)
+	}
+	defer rows.Close()
+	var out []Watcher
+	for rows.Next() {
+		w, err := scanWatcher(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *w)
+	}
+	return out, rows.Err()
+}
+
+// ensureHostID returns the id of hostname, creating a bare hosts row if needed
+// without touching reachability of an existing one.
+func (s *Store) ensureHostID(ctx context.Context, hostname string) (int64, error) {
+	var id int64
+	err := s.db.Pool.QueryRow(ctx, `
+INSERT INTO hosts(hostname) VALUES ($1)
+ON CONFLICT (hostname) DO UPDATE SET hostname=EXCLUDED.hostname
+RETURNING id
+`, hostname).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ensure host: %w", err)
+	}
+	return id, nil
+}
+
+// SeedWatcher adds a watcher from config unless the host already has one, so
+// changes made through the API win over the config file.
+func (s *Store) SeedWatcher(ctx context.Context, hostname, mode string) error {
+	hid, err := s.ensureHostID(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Pool.Exec(ctx, `
+INSERT INTO watchers(host_id, enabled, mode, source)
+VALUES ($1, true, $2, 'config')
+ON CONFLICT (host_id) DO NOTHING
+`, hid, mode)
+	if err != nil {
+		return fmt.Errorf("seed watcher: %w", err)
+	}
+	return nil
+}
+
+// PutWatcher creates or updates the watcher of hostname. A nil enabled or an
+// empty mode leaves that field unchanged (new watchers default to enabled, auto).
+func (s *Store) PutWatcher(ctx context.Context, hostname string, enabled *bool, mode string) (*Watcher, error) {
+	hid, err := s.ensureHostID(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	var m *string
+	if mode != "" {
+		m = &mode
+	}
+	_, err = s.db.Pool.Exec(ctx, `
+INSERT INTO watchers(host_id, enabled, mode, source)
+VALUES ($1, COALESCE($2, true), COALESCE($3, 'auto'), 'api')
+ON CONFLICT (host_id) DO UPDATE
+SET enabled=COALESCE($2, watchers.enabled), mode=COALESCE($3, watchers.mode), updated_at=now()
+`, hid, enabled, m)
+	if err != nil {
+		return nil, fmt.Errorf("put watcher: %w", err)
+	}
+	w, err := scanWatcher(s.db.Pool.QueryRow(ctx, `SELECT `+watcherCols+` FROM watchers w JOIN hosts h ON h.id = w.host_id WHERE w.host_id=$1`, hid))
+	if err != nil {
+		return nil, fmt.Errorf("get watcher: %w", err)
+	}
+	return w, nil
+}
+
+// DeleteWatcher removes the watcher of hostname; it reports whether one existed.
+func (s *Store) DeleteWatcher(ctx context.Context, hostname string) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx, `DELETE FROM watchers w USING hosts h WHERE h.id = w.host_id AND h.hostname=$1`, hostname)
+	if err != nil {
+		return false, fmt.Errorf("delete watcher: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 type WatcherState struct {
 	HostID          int64
 	Mode            string
@@ -13,15 +170,6 @@ type WatcherState struct {
 	LastEventSHA256 *string
 }
 
-func (s *Store) EnsureWatcher(ctx context.Context, hostID int64, mode string) error {
-	_, err := s.db.Pool.Exec(ctx, `
-INSERT INTO watchers(host_id, enabled, mode)
-VALUES ($1, true, $2)
-ON CONFLICT (host_id) DO UPDATE SET enabled=true, mode=EXCLUDED.mode;
-`, hostID, mode)
-	return err
-}
-
 func (s *Store) GetWatcherState(ctx context.Context, hostID int64) (*WatcherState, error) {
 	var mode string
 	var cursor sql.NullString
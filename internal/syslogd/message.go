@@ -0,0 +1,200 @@
+package syslogd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is one syslog message, RFC3164 or RFC5424.
+type Message struct {
+	Facility int
+	Severity int
+	Time     time.Time
+	Hostname string // "" when the sender left it out
+	App      string
+	ProcID   string
+	Text     string
+}
+
+// Line renders m the way journalctl --output=short-iso does, which is what the
+// sshd parsers read.
+func (m *Message) Line() string {
+	tag := m.App
+	if tag == "" {
+		tag = "-"
+	}
+	if m.ProcID != "" {
+		tag += "[" + m.ProcID + "]"
+	}
+	host := m.Hostname
+	if host == "" {
+		host = "-"
+	}
+	return fmt.Sprintf("%s %s %s: %s", m.Time.UTC().Format(time.RFC3339), host, tag, m.Text)
+}
+
+// ParseMessage parses an RFC5424 message ("<PRI>1 ...") or, failing that, a
+// BSD RFC3164 one. Fields a sender left out are empty; a missing timestamp
+// becomes now.
+func ParseMessage(b []byte, now time.Time) (*Message, error) {
+	s := strings.TrimRight(string(b), "\r\n\x00")
+	pri, rest, err := parsePRI(s)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Facility: pri / 8, Severity: pri % 8}
+	if strings.HasPrefix(rest, "1 ") {
+		err = parse5424(m, rest[2:], now)
+	} else {
+		parse3164(m, rest, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func parsePRI(s string) (int, string, error) {
+	if !strings.HasPrefix(s, "<") {
+		return 0, "", errors.New("syslog: missing <PRI>")
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errors.New("syslog: bad <PRI>")
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", fmt.Errorf("syslog: bad PRI %q", s[1:end])
+	}
+	return pri, s[end+1:], nil
+}
+
+// parse5424 reads "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]".
+func parse5424(m *Message, s string, now time.Time) error {
+	fields := make([]string, 0, 5)
+	for len(fields) < 5 {
+		i := strings.IndexByte(s, ' ')
+		if i < 0 {
+			return errors.New("syslog: truncated RFC5424 header")
+		}
+		fields = append(fields, s[:i])
+		s = s[i+1:]
+	}
+	nilable := func(v string) string {
+		if v == "-" {
+			return ""
+		}
+		return v
+	}
+
+	m.Time = now
+	if ts := fields[0]; ts != "-" {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return fmt.Errorf("syslog: bad timestamp %q", ts)
+		}
+		m.Time = t
+	}
+	m.Hostname = nilable(fields[1])
+	m.App = nilable(fields[2])
+	m.ProcID = nilable(fields[3])
+
+	rest, err := skipStructuredData(s)
+	if err != nil {
+		return err
+	}
+	m.Text = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return nil
+}
+
+// skipStructuredData returns what follows the SD field: "-" or one or more
+// [id param="value"...] elements, where values may contain escaped \" \] \\.
+func skipStructuredData(s string) (string, error) {
+	if strings.HasPrefix(s, "-") {
+		return s[1:], nil
+	}
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		inQuote := false
+		for i++; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && inQuote {
+				i++
+				continue
+			}
+			if c == '"' {
+				inQuote = !inQuote
+				continue
+			}
+			if c == ']' && !inQuote {
+				break
+			}
+		}
+		if i >= len(s) {
+			return "", errors.New("syslog: unterminated structured data")
+		}
+		i++
+	}
+	if i == 0 {
+		return "", errors.New("syslog: bad structured data")
+	}
+	return s[i:], nil
+}
+
+// parse3164 is lenient, as BSD syslog is in practice: "Mmm dd hh:mm:ss" or an
+// RFC3339 timestamp, an optional HOSTNAME, then "TAG[PID]: MSG".
+func parse3164(m *Message, s string, now time.Time) {
+	m.Time = now
+	stamped := false
+	if len(s) >= 15 {
+		if t, ok := parseBSDTime(s[:15], now); ok {
+			m.Time, stamped = t, true
+			s = strings.TrimLeft(s[15:], " ")
+		} else if i := strings.IndexByte(s, ' '); i > 0 {
+			if t, err := time.Parse(time.RFC3339Nano, s[:i]); err == nil {
+				m.Time, stamped = t, true
+				s = s[i+1:]
+			}
+		}
+	}
+
+	// Without a timestamp there is no HOSTNAME either (RFC 3164 4.3.2). A tag
+	// ends in ':' or carries "[pid]"; anything else first is a hostname.
+	if i := strings.IndexByte(s, ' '); stamped && i > 0 && !isTag(s[:i]) {
+		m.Hostname = s[:i]
+		s = s[i+1:]
+	}
+
+	if i := strings.Index(s, ": "); i > 0 && !strings.ContainsRune(s[:i], ' ') {
+		tag := s[:i]
+		s = s[i+2:]
+		if j := strings.IndexByte(tag, '['); j > 0 && strings.HasSuffix(tag, "]") {
+			m.ProcID = tag[j+1 : len(tag)-1]
+			tag = tag[:j]
+		}
+		m.App = tag
+	}
+	m.Text = s
+}
+
+func isTag(tok string) bool {
+	return strings.HasSuffix(tok, ":") || strings.Contains(tok, "[")
+}
+
+// parseBSDTime reads "Mmm dd hh:mm:ss" (day may be space-padded) in local
+// time, assuming the year that puts it nearest before now.
+func parseBSDTime(s string, now time.Time) (time.Time, bool) {
+	t, err := time.ParseInLocation("Jan _2 15:04:05", s, now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	// A timestamp more than a day ahead is from last year (year roll-over).
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
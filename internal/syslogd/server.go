@@ -0,0 +1,316 @@
+package syslogd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/pushauth"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+	"github.com/jsherman999/openclaw_keyspider/internal/watcher"
+	"github.com/jsherman999/openclaw_keyspider/internal/watchhub"
+)
+
+// Push-mode ingestion: hosts (or relays) forward sshd logs to keyspiderd
+// instead of being polled over SSH. Each message is attributed to a host by
+// its HOSTNAME field or sender address and then handled exactly like a line
+// from a watcher stream.
+
+// statsInterval is how often receive counts are logged.
+const statsInterval = 5 * time.Minute
+
+// UDP messages are handled by udpWorkers goroutines off a queue, so store and
+// DNS work never blocks the socket; when the queue is full, messages are
+// dropped and counted.
+const (
+	udpWorkers   = 8
+	udpQueueSize = 4096
+)
+
+type packet struct {
+	msg []byte
+	ip  net.IP
+}
+
+type Server struct {
+	cfg   *config.Config
+	st    *store.Store
+	w     *watcher.Watcher
+	allow pushauth.AllowList
+	sem   chan struct{} // TCP/TLS connection slots
+	dns   *resolver.Resolver
+
+	received  atomic.Int64
+	malformed atomic.Int64
+	dropped   atomic.Int64
+}
+
+func New(cfg *config.Config, dbc *db.DB, hub *watchhub.Hub, rp *reach.Prober) *Server {
+	s := &Server{
+		cfg:   cfg,
+		st:    store.New(dbc),
+		w:     watcher.New(cfg, dbc, hub, rp),
+		sem:   make(chan struct{}, max(cfg.Syslog.MaxConnections, 1)),
+		dns:   resolver.New(cfg),
+		allow: pushauth.ParseAllowList(cfg.Syslog.AllowCIDRs),
+	}
+	return s
+}
+
+// Run listens on the configured transports until ctx ends.
+func (s *Server) Run(ctx context.Context) {
+	if !s.cfg.Syslog.Enabled {
+		return
+	}
+	sl := s.cfg.Syslog
+	var wg sync.WaitGroup
+	start := func(name string, f func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("syslog(%s): %v", name, err)
+			}
+		}()
+	}
+	if sl.UDPListen != "" {
+		start("udp", s.serveUDP)
+	}
+	if sl.TCPListen != "" {
+		start("tcp", func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", sl.TCPListen)
+			if err != nil {
+				return err
+			}
+			return s.serveStream(ctx, "tcp", ln)
+		})
+	}
+	if sl.TLSListen != "" {
+		start("tls", func(ctx context.Context) error {
+			tc, err := pushauth.TLSConfig(sl.TLSCertFile, sl.TLSKeyFile, sl.TLSClientCAFile)
+			if err != nil {
+				return err
+			}
+			ln, err := tls.Listen("tcp", sl.TLSListen, tc)
+			if err != nil {
+				return err
+			}
+			return s.serveStream(ctx, "tls", ln)
+		})
+	}
+	start("stats", s.logStats)
+	wg.Wait()
+}
+
+func (s *Server) serveUDP(ctx context.Context) error {
+	pc, err := net.ListenPacket("udp", s.cfg.Syslog.UDPListen)
+	if err != nil {
+		return err
+	}
+	log.Printf("syslog: udp listening on %s", pc.LocalAddr())
+	stop := context.AfterFunc(ctx, func() { _ = pc.Close() })
+	defer stop()
+
+	queue := make(chan packet, udpQueueSize)
+	var wg sync.WaitGroup
+	for range udpWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				s.handle(ctx, p.msg, p.ip)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(queue)
+
+	buf := make([]byte, s.cfg.Syslog.MaxMessageBytes)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		ip := addrIP(addr)
+		if !s.allow.Allows(ip) {
+			continue
+		}
+		select {
+		case queue <- packet{msg: append([]byte(nil), buf[:n]...), ip: ip}:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// serveStream accepts TCP or TLS senders; each connection carries
+// octet-counted or newline-delimited messages (RFC 6587).
+func (s *Server) serveStream(ctx context.Context, name string, ln net.Listener) error {
+	log.Printf("syslog: %s listening on %s", name, ln.Addr())
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		ip := addrIP(c.RemoteAddr())
+		if !s.allow.Allows(ip) {
+			_ = c.Close()
+			continue
+		}
+		select {
+		case s.sem <- struct{}{}:
+		default:
+			log.Printf("syslog(%s): too many connections, refusing %s", name, c.RemoteAddr())
+			_ = c.Close()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-s.sem }()
+			if err := s.serveConn(ctx, c, ip); err != nil && ctx.Err() == nil {
+				log.Printf("syslog(%s): %s: %v", name, c.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, c net.Conn, ip net.IP) error {
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+
+	br := bufio.NewReaderSize(c, s.cfg.Syslog.MaxMessageBytes)
+	for {
+		msg, err := readFrame(br, s.cfg.Syslog.MaxMessageBytes)
+		if len(msg) > 0 {
+			s.handle(ctx, msg, ip)
+		}
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readFrame reads one message: "LEN SP MSG" when the frame starts with a
+// digit (octet counting), otherwise up to the next LF.
+func readFrame(br *bufio.Reader, maxBytes int) ([]byte, error) {
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		n := 0
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if c == ' ' {
+				break
+			}
+			if c < '0' || c > '9' || n > maxBytes {
+				return nil, errors.New("bad octet count")
+			}
+			n = n*10 + int(c-'0')
+		}
+		if n > maxBytes {
+			return nil, fmt.Errorf("message of %d bytes exceeds max_message_bytes", n)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+	line, err := br.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, errors.New("message exceeds max_message_bytes")
+	}
+	return line, err
+}
+
+func (s *Server) handle(ctx context.Context, raw []byte, ip net.IP) {
+	s.received.Add(1)
+	m, err := ParseMessage(raw, time.Now())
+	if err != nil {
+		s.malformed.Add(1)
+		return
+	}
+	host := pushauth.Attribute(ctx, s.dns, s.cfg.Syslog.HostFrom, m.Hostname, ip)
+	if host == "" {
+		s.malformed.Add(1)
+		return
+	}
+	hid, err := s.w.PushHostID(ctx, host)
+	if err != nil {
+		log.Printf("syslog: %v", err)
+		return
+	}
+	if m.Hostname == "" {
+		m.Hostname = host
+	}
+	s.w.Ingest(ctx, hid, host, m.Line())
+}
+
+func (s *Server) logStats(ctx context.Context) error {
+	t := time.NewTicker(statsInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		if n := s.received.Swap(0); n > 0 {
+			log.Printf("syslog: %d messages received, %d malformed", n, s.malformed.Swap(0))
+		}
+		if n := s.dropped.Swap(0); n > 0 {
+			log.Printf("syslog: %d udp messages dropped (queue full)", n)
+		}
+	}
+}
+
+func addrIP(a net.Addr) net.IP {
+	switch v := a.(type) {
+	case *net.UDPAddr:
+		return v.IP
+	case *net.TCPAddr:
+		return v.IP
+	}
+	host, _, err := net.SplitHostPort(a.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,99 @@
+package watcher
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+)
+
+// Circuit breaker states reported in watchers.breaker_state.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// healthyStream is how long a stream must stay up (if it delivered no line)
+// to count as a success that resets the backoff.
+const healthyStream = time.Minute
+
+// breaker decides how long a watcher waits before reconnecting. Failures back
+// off exponentially with equal jitter up to max; after threshold consecutive
+// failures the breaker opens for cooldown, then allows one trial (half_open)
+// that either closes it or opens it again. It is owned by one watchHost
+// goroutine and needs no locking.
+type breaker struct {
+	base, max, cooldown time.Duration
+	threshold           int
+
+	state     string
+	failures  int // consecutive
+	openUntil time.Time
+	rnd       *rand.Rand
+}
+
+func newBreaker(cfg *config.Config) *breaker {
+	b := &breaker{
+		base:      time.Duration(cfg.Watcher.BackoffBaseSeconds) * time.Second,
+		max:       time.Duration(cfg.Watcher.BackoffMaxSeconds) * time.Second,
+		cooldown:  time.Duration(cfg.Watcher.BreakerCooldownSeconds) * time.Second,
+		threshold: cfg.Watcher.BreakerThreshold,
+		state:     breakerClosed,
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if b.base <= 0 {
+		b.base = 2 * time.Second
+	}
+	if b.max < b.base {
+		b.max = b.base
+	}
+	if b.cooldown < b.max {
+		b.cooldown = b.max
+	}
+	return b
+}
+
+// allow is called before each connect attempt. It returns how long to wait
+// first; an open breaker whose cooldown has passed moves to half_open.
+func (b *breaker) allow(now time.Time) time.Duration {
+	if b.state != breakerOpen {
+		return 0
+	}
+	if wait := b.openUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	b.state = breakerHalfOpen
+	return 0
+}
+
+// failure records a failed attempt and returns the delay before the next one.
+func (b *breaker) failure(now time.Time) time.Duration {
+	b.failures++
+	if b.state == breakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = breakerOpen
+		b.openUntil = now.Add(b.cooldown)
+		return b.cooldown
+	}
+	return b.backoff()
+}
+
+// success closes the breaker and resets the backoff.
+func (b *breaker) success() {
+	b.state = breakerClosed
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// backoff is base*2^(failures-1), capped at max, with equal jitter: drawn from [d/2, d].
+func (b *breaker) backoff() time.Duration {
+	d := b.base
+	for i := 1; i < b.failures && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	half := d / 2
+	return half + time.Duration(b.rnd.Int63n(int64(d-half)+1))
+}
@@ -0,0 +1,199 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Watcher statuses reported in watchers.status.
+const (
+	statusConnecting = "connecting"
+	statusConnected  = "connected"
+	statusBackingOff = "backing_off"
+	statusFailed     = "failed" // breaker open
+	statusStopped    = "stopped"
+)
+
+// healthReportInterval is how often a watcher writes its health (and heartbeat).
+const healthReportInterval = 15 * time.Second
+
+// health tracks one host's stream. watchHost updates it; report flushes it
+// to the watchers table.
+type health struct {
+	mu          sync.Mutex
+	hostID      int64
+	status      string
+	mode        string
+	lastLine    time.Time
+	connectedAt time.Time
+	connects    int
+	lastErr     string
+	lines       int // since the last report
+	lastReport  time.Time
+	linesPerMin float64
+
+	// Copied from the host's breaker after each attempt.
+	breakerState string
+	failures     int // consecutive
+	nextRetry    time.Time
+}
+
+func newHealth() *health {
+	return &health{status: statusConnecting, breakerState: breakerClosed, lastReport: time.Now()}
+}
+
+func (h *health) setHostID(id int64) {
+	h.mu.Lock()
+	h.hostID = id
+	h.mu.Unlock()
+}
+
+func (h *health) connecting() {
+	h.mu.Lock()
+	h.status = statusConnecting
+	h.mu.Unlock()
+}
+
+// connected marks a stream as up in mode (journal|tail).
+func (h *health) connected(mode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status = statusConnected
+	h.mode = mode
+	h.connectedAt = time.Now()
+	h.connects++
+}
+
+func (h *health) line() {
+	h.mu.Lock()
+	h.lastLine = time.Now()
+	h.lines++
+	h.mu.Unlock()
+}
+
+// fail records a dropped or refused connection; the caller backs off next.
+func (h *health) fail(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.lastErr = err.Error()
+	}
+	h.status = statusBackingOff
+}
+
+// retry records the breaker's state and when the next attempt is due; an
+// open breaker marks the watcher failed.
+func (h *health) retry(b *breaker, next time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.breakerState = b.state
+	h.failures = b.failures
+	h.nextRetry = next
+	if b.state == breakerOpen {
+		h.status = statusFailed
+	}
+}
+
+// lineSince reports whether a line arrived after t.
+func (h *health) lineSince(t time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastLine.After(t)
+}
+
+func (h *health) snapshot() (int64, store.WatcherHealth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if el := now.Sub(h.lastReport); el > 0 {
+		h.linesPerMin = float64(h.lines) / el.Minutes()
+	}
+	h.lines = 0
+	h.lastReport = now
+
+	reconnects := h.connects - 1
+	if reconnects < 0 {
+		reconnects = 0
+	}
+	wh := store.WatcherHealth{
+		Status:      ptr(h.status),
+		ActiveMode:  ptr(h.mode),
+		LastLineAt:  timePtr(h.lastLine),
+		ConnectedAt: timePtr(h.connectedAt),
+		Reconnects:  reconnects,
+		LastError:   ptr(h.lastErr),
+		LinesPerMin: h.linesPerMin,
+
+		BreakerState:        ptr(h.breakerState),
+		ConsecutiveFailures: h.failures,
+		NextRetryAt:         timePtr(h.nextRetry),
+	}
+	return h.hostID, wh
+}
+
+// report flushes h every healthReportInterval until ctx ends, then records
+// the watcher as stopped. It also raises a WATCHER_SILENT concern when a
+// connected watcher has seen no line for watcher.silent_after_seconds, and
+// resolves it once lines flow again.
+func (w *Watcher) report(ctx context.Context, host string, h *health) {
+	silentAfter := time.Duration(w.cfg.Watcher.SilentAfterSeconds) * time.Second
+	t := time.NewTicker(healthReportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.mu.Lock()
+			h.status = statusStopped
+			h.mu.Unlock()
+			w.flushHealth(context.WithoutCancel(ctx), h)
+			return
+		case <-t.C:
+		}
+
+		hostID, wh := w.flushHealth(ctx, h)
+		if hostID == 0 || silentAfter <= 0 {
+			continue
+		}
+		// Silence is measured from the last line, or from the connect if none arrived yet.
+		since := wh.LastLineAt
+		if since == nil || (wh.ConnectedAt != nil && since.Before(*wh.ConnectedAt)) {
+			since = wh.ConnectedAt
+		}
+		if since == nil || *wh.Status != statusConnected {
+			continue
+		}
+		if quiet := time.Since(*since); quiet > silentAfter {
+			_, created, err := w.st.EnsureOpenConcern(ctx, "medium", "WATCHER_SILENT", &hostID,
+				fmt.Sprintf("watcher connected (%s) but no log lines for %s", *wh.ActiveMode, quiet.Round(time.Minute)))
+			if err == nil && created {
+				log.Printf("watcher(%s): silent for %s", host, quiet.Round(time.Second))
+			}
+		} else if wh.LastLineAt != nil && time.Since(*wh.LastLineAt) < healthReportInterval {
+			_, _ = w.st.ResolveConcerns(ctx, "WATCHER_SILENT", hostID)
+		}
+	}
+}
+
+func (w *Watcher) flushHealth(ctx context.Context, h *health) (int64, store.WatcherHealth) {
+	hostID, wh := h.snapshot()
+	if hostID == 0 {
+		return 0, wh
+	}
+	if err := w.st.UpdateWatcherHealth(ctx, hostID, wh); err != nil && ctx.Err() == nil {
+		log.Printf("watcher: %v", err)
+	}
+	return hostID, wh
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,45 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Owners of the keys seen in streamed logins are cached for ownerTTL, so the
+// live stream names them without a query per line; at most maxOwners
+// fingerprints are kept.
+const (
+	ownerTTL  = 5 * time.Minute
+	maxOwners = 10000
+)
+
+type cachedOwner struct {
+	who    string
+	looked time.Time
+}
+
+// keyOwner returns the attribution of fingerprint fp ("" when it has no
+// owner), from the cache while it is fresh.
+func (w *Watcher) keyOwner(ctx context.Context, fp string) string {
+	w.mu.Lock()
+	o, ok := w.owners[fp]
+	w.mu.Unlock()
+	if ok && time.Since(o.looked) < ownerTTL {
+		return o.who
+	}
+	who, err := w.st.KeyAttribution(ctx, fp)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("watcher: key owner %s: %v", fp, err)
+		}
+		return o.who
+	}
+	w.mu.Lock()
+	if len(w.owners) >= maxOwners {
+		w.owners = map[string]cachedOwner{}
+	}
+	w.owners[fp] = cachedOwner{who: who, looked: time.Now()}
+	w.mu.Unlock()
+	return who
+}
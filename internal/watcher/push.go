@@ -0,0 +1,56 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/parsers"
+)
+
+// Push receivers (syslog, journal-remote) hand lines to a Watcher instead of
+// it pulling them over SSH; they share its parse, dedupe, edge and publish
+// pipeline.
+
+// pushTouchInterval limits how often a push sender's hosts.last_seen is bumped.
+const pushTouchInterval = time.Minute
+
+type pushedHost struct {
+	id      int64
+	touched time.Time
+}
+
+// PushHostID returns the id of a host that pushed logs, creating the host and
+// bumping its last_seen at most once per pushTouchInterval.
+func (w *Watcher) PushHostID(ctx context.Context, host string) (int64, error) {
+	w.mu.Lock()
+	ph, ok := w.pushed[host]
+	w.mu.Unlock()
+	if ok && time.Since(ph.touched) < pushTouchInterval {
+		return ph.id, nil
+	}
+	id, err := w.st.TouchHost(ctx, host)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	w.pushed[host] = pushedHost{id: id, touched: time.Now()}
+	w.mu.Unlock()
+	return id, nil
+}
+
+// Ingest handles one text log line pushed for hostID.
+func (w *Watcher) Ingest(ctx context.Context, hostID int64, host, line string) {
+	w.handleLogLine(ctx, hostID, host, line, lineMeta{})
+}
+
+// IngestJournal handles one pushed journal entry.
+func (w *Watcher) IngestJournal(ctx context.Context, hostID int64, host string, e parsers.JournalEntry) {
+	w.handleJournalEntry(ctx, hostID, host, e)
+}
+
+// handleJournalEntry keeps the entry's exact timestamp, PID and boot id
+// alongside the short-iso rendering the parsers read.
+func (w *Watcher) handleJournalEntry(ctx context.Context, hostID int64, host string, e parsers.JournalEntry) {
+	ts, _ := e.Time()
+	w.handleLogLine(ctx, hostID, host, e.Line(), lineMeta{ts: ts, pid: e.PID(), bootID: e["_BOOT_ID"]})
+}
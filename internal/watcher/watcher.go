@@ -5,14 +5,22 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/osdetect"
 	"github.com/jsherman999/openclaw_keyspider/internal/parsers"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
+	"github.com/jsherman999/openclaw_keyspider/internal/remotecmd"
+	"github.com/jsherman999/openclaw_keyspider/internal/resolver"
+	"github.com/jsherman999/openclaw_keyspider/internal/scope"
 	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 	"github.com/jsherman999/openclaw_keyspider/internal/watchhub"
@@ -30,132 +38,395 @@ type Watcher struct {
 	ssh   *sshclient.Client
 	hub   *watchhub.Hub
 	parse *parsers.LinuxSSHDParser
+	scope *scope.Policy
+	cmds  *remotecmd.Catalog
+	reach *reach.Prober
+	osd   *osdetect.Detector
 
 	// in-memory dedupe: per-host ring of recent hashes
 	mu      sync.Mutex
 	recent  map[int64][]string
 	recentI map[int64]int
+
+	// host ids of push senders (see PushHostID)
+	pushed map[string]pushedHost
+
+	// key owners named in the live stream (see keyOwner)
+	owners map[string]cachedOwner
+
+	dns   *resolver.Resolver
+	addrs map[string]time.Time // source ip -> DNS answer last recorded in host_addresses
 }
 
-func New(cfg *config.Config, dbc *db.DB, hub *watchhub.Hub) *Watcher {
+// New returns a watcher probing hosts through rp, which the daemon shares
+// with the spider so both use one reachability cache.
+func New(cfg *config.Config, dbc *db.DB, hub *watchhub.Hub, rp *reach.Prober) *Watcher {
+	st := store.New(dbc)
+	ssh := sshclient.NewAudited(cfg, st)
+	cmds := remotecmd.MustNew(cfg.Commands.Overrides)
 	return &Watcher{
 		cfg:     cfg,
 		db:      dbc,
-		st:      store.New(dbc),
-		ssh:     sshclient.New(cfg),
+		st:      st,
+		ssh:     ssh,
+		reach:   rp,
+		osd:     osdetect.New(st, ssh, cmds),
 		hub:     hub,
 		parse:   parsers.NewLinuxSSHDParser(time.Now),
+		scope:   scope.New(cfg),
+		cmds:    cmds,
 		recent:  map[int64][]string{},
 		recentI: map[int64]int{},
+		pushed:  map[string]pushedHost{},
+		owners:  map[string]cachedOwner{},
+		dns:     resolver.New(cfg),
+		addrs:   map[string]time.Time{},
 	}
 }
 
+// running is a watchHost goroutine and the mode it was started with.
+type running struct {
+	mode   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run keeps one stream per enabled row of the watchers table, reconciling
+// every watcher.reconcile_seconds: watchers added or enabled through the API
+// are started, removed or disabled ones stopped, and a mode change restarts
+// the stream. watcher.hosts only seeds the table.
 func (w *Watcher) Run(ctx context.Context) {
 	if !w.cfg.Watcher.Enabled {
 		return
 	}
-	if len(w.cfg.Watcher.Hosts) == 0 {
-		log.Printf("watcher enabled but watcher.hosts empty")
-		return
-	}
+	w.seed(ctx)
 
-	for _, host := range w.cfg.Watcher.Hosts {
-		h := host
-		go w.watchHost(ctx, h)
+	iv := time.Duration(w.cfg.Watcher.ReconcileSeconds) * time.Second
+	if iv <= 0 {
+		iv = 15 * time.Second
 	}
+	active := map[string]*running{}
+	stop := func(host string) {
+		r := active[host]
+		r.cancel()
+		<-r.done
+		delete(active, host)
+	}
+	// Wait for every host stream to stop, so a new leader never overlaps with us.
+	defer func() {
+		for host := range active {
+			stop(host)
+		}
+	}()
 
-	<-ctx.Done()
-}
-
-func (w *Watcher) watchHost(ctx context.Context, host string) {
+	t := time.NewTicker(iv)
+	defer t.Stop()
 	for {
+		if ws, err := w.st.ListWatchers(ctx, true); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("watcher: list watchers: %v", err)
+			}
+		} else {
+			want := map[string]string{}
+			for _, x := range ws {
+				want[x.Host] = x.Mode
+			}
+			for host, r := range active {
+				if mode, ok := want[host]; !ok || mode != r.mode {
+					log.Printf("watcher(%s): stopping (removed, disabled or mode changed)", host)
+					stop(host)
+				}
+			}
+			for host, mode := range want {
+				if _, ok := active[host]; ok {
+					continue
+				}
+				hctx, cancel := context.WithCancel(sshclient.WithInitiator(ctx, "watcher:"+host))
+				r := &running{mode: mode, cancel: cancel, done: make(chan struct{})}
+				active[host] = r
+				go func(host string) {
+					defer close(r.done)
+					w.watchHost(hctx, host, mode)
+				}(host)
+				log.Printf("watcher(%s): started mode=%s", host, mode)
+			}
+		}
+
 		select {
 		case <-ctx.Done():
 			return
-		default:
-		}
-
-		if !w.ssh.CanConnect(ctx, host) {
-			// Backoff; also mark host unreachable.
-			hid, _ := w.st.UpsertHost(ctx, host, &host, "linux", false)
-			_, _ = w.st.InsertConcern(ctx, "high", "UNREACHABLE_HOST", &hid, nil, nil, "watcher cannot ssh to host")
-			time.Sleep(10 * time.Second)
-			continue
+		case <-t.C:
 		}
+	}
+}
 
-		hid, _ := w.st.UpsertHost(ctx, host, &host, "linux", true)
-		_ = w.st.EnsureWatcher(ctx, hid, "auto")
-
-		state, _ := w.st.GetWatcherState(ctx, hid)
+// seed adds watcher.hosts to the watchers table. Hosts that already have a
+// watcher row keep their API-managed settings.
+func (w *Watcher) seed(ctx context.Context) {
+	for _, host := range w.cfg.Watcher.Hosts {
 		mode := w.cfg.Watcher.DefaultMode
+		if m, ok := w.cfg.Watcher.HostModes[host]; ok && m != "" {
+			mode = m
+		}
 		if mode == "" {
 			mode = "auto"
 		}
-		if w.cfg.Watcher.HostModes != nil {
-			if m, ok := w.cfg.Watcher.HostModes[host]; ok && m != "" {
-				mode = m
+		if err := w.st.SeedWatcher(ctx, host, mode); err != nil {
+			log.Printf("watcher(%s): seed: %v", host, err)
+		}
+	}
+}
+
+func (w *Watcher) watchHost(ctx context.Context, host, mode string) {
+	h := newHealth()
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		w.report(ctx, host, h)
+	}()
+	defer func() { <-reported }()
+
+	b := newBreaker(w.cfg)
+	for {
+		if wait := b.allow(time.Now()); wait > 0 {
+			sleepCtx(ctx, wait)
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		h.retry(b, time.Time{})
+
+		h.connecting()
+		pr := w.ssh.Probe(ctx, host)
+		w.reach.Record(ctx, host, pr)
+		if !pr.OK {
+			// One open concern per outage; it is resolved when the host is back.
+			hid, _ := w.st.UpsertHost(ctx, host, &host, "", false)
+			h.setHostID(hid)
+			if _, created, err := w.st.EnsureOpenConcern(ctx, "high", "UNREACHABLE_HOST", &hid, "watcher cannot ssh to host ("+pr.Reason+")"); err == nil && created {
+				log.Printf("watcher(%s): host unreachable: %s", host, pr.Reason)
 			}
+			w.backoff(ctx, host, h, b, fmt.Errorf("jump server cannot ssh to host: %s", pr.Reason))
+			continue
 		}
-		if state != nil && state.Mode != "" {
-			// DB overrides config.
-			mode = state.Mode
+
+		hid, _ := w.st.UpsertHost(ctx, host, &host, "", true)
+		h.setHostID(hid)
+		if n, err := w.st.ResolveConcerns(ctx, "UNREACHABLE_HOST", hid); err == nil && n > 0 {
+			log.Printf("watcher(%s): host reachable again", host)
 		}
+		state, _ := w.st.GetWatcherState(ctx, hid)
+
+		osType := w.osd.OSType(ctx, host)
 
 		// Decide command.
-		useJournal := mode == "journal" || mode == "auto"
-		if useJournal {
-			err := w.streamJournal(ctx, host, hid, state)
-			if err == nil {
-				continue
+		started := time.Now()
+		var err error
+		if mode == "journal" || mode == "auto" {
+			err = w.streamJournal(ctx, host, hid, osType, state, h)
+			if err != nil {
+				h.fail(err)
+				log.Printf("watcher(%s): journal stream failed, falling back to tail: %v", host, err)
+				err = w.streamTail(ctx, host, hid, osType, state, h)
 			}
-			log.Printf("watcher(%s): journal stream failed, falling back to tail: %v", host, err)
+		} else {
+			err = w.streamTail(ctx, host, hid, osType, state, h)
 		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("stream ended")
+		}
+
+		// A stream that delivered lines or stayed up a while counts as a
+		// success: reconnect promptly with the backoff reset.
+		if h.lineSince(started) || time.Since(started) >= healthyStream {
+			b.success()
+			h.fail(err)
+			h.retry(b, time.Now().Add(b.base))
+			sleepCtx(ctx, b.base)
+			continue
+		}
+		w.backoff(ctx, host, h, b, err)
+	}
+}
 
-		_ = w.streamTail(ctx, host, hid)
-		// If stream ends, retry.
-		time.Sleep(2 * time.Second)
+// backoff records a failed attempt and waits out the breaker's delay.
+func (w *Watcher) backoff(ctx context.Context, host string, h *health, b *breaker, err error) {
+	h.fail(err)
+	d := b.failure(time.Now())
+	h.retry(b, time.Now().Add(d))
+	if b.state == breakerOpen {
+		log.Printf("watcher(%s): %d consecutive failures, circuit open for %s: %v", host, b.failures, d, err)
 	}
+	sleepCtx(ctx, d)
 }
 
-func (w *Watcher) streamJournal(ctx context.Context, host string, hostID int64, state *store.WatcherState) error {
-	// Use journalctl short-iso and show cursor, so we can resume.
-	cursorArg := ""
-	if state != nil && state.Cursor != nil && *state.Cursor != "" {
-		cursorArg = "--after-cursor '" + *state.Cursor + "'"
-	} else {
-		cursorArg = "--since '2 minutes ago'"
+// sleepCtx sleeps for d or until ctx ends.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
 	}
+}
 
-	cmd := "sh -lc \"command -v journalctl >/dev/null 2>&1 || exit 2; journalctl -f -u ssh -u sshd --no-pager --output=short-iso --show-cursor " + cursorArg + "\""
+func (w *Watcher) streamJournal(ctx context.Context, host string, hostID int64, osType string, state *store.WatcherState, h *health) error {
+	// journalctl --output=json: one record per line, carrying its own cursor.
+	p := remotecmd.Params{"since": "2 minutes ago"}
+	if state != nil && state.Cursor != nil && *state.Cursor != "" && !isTailCursor(*state.Cursor) {
+		p["cursor"] = *state.Cursor
+	}
+	cmd, err := w.cmds.Render(osType, remotecmd.WatchJournal, p)
+	if err != nil {
+		return err
+	}
 
-	var lastCursor string
-	err := w.ssh.Stream(ctx, host, cmd, func(line string) bool {
-		// journalctl cursor lines look like: "-- cursor: s=..."
-		if strings.HasPrefix(line, "-- cursor:") {
-			lastCursor = strings.TrimSpace(strings.TrimPrefix(line, "-- cursor:"))
-			_ = w.st.UpdateWatcherCursor(ctx, hostID, lastCursor)
+	// As in tail mode, the cursor is saved every couple of seconds.
+	var cursor string
+	lastSave := time.Now()
+	save := func(ctx context.Context) {
+		if cursor != "" {
+			_ = w.st.UpdateWatcherCursor(ctx, hostID, cursor)
+		}
+		lastSave = time.Now()
+	}
+	defer func() { save(context.WithoutCancel(ctx)) }()
+
+	h.connected("journal")
+	return w.ssh.Stream(ctx, host, cmd, func(line string) bool {
+		e, err := parsers.ParseJournalJSON(line)
+		if err != nil {
+			// An override template may still emit text (short-iso, --show-cursor).
+			if c, ok := strings.CutPrefix(line, "-- cursor:"); ok {
+				cursor = strings.TrimSpace(c)
+			} else if line != "" {
+				h.line()
+				w.handleLogLine(ctx, hostID, host, line, lineMeta{})
+			}
 			return true
 		}
-		w.handleLogLine(ctx, hostID, host, line)
+		h.line()
+		w.handleJournalEntry(ctx, hostID, host, e)
+		if c := e["__CURSOR"]; c != "" {
+			cursor = c
+		}
+		if time.Since(lastSave) > 2*time.Second {
+			save(ctx)
+		}
 		return true
 	})
-	return err
 }
 
-func (w *Watcher) streamTail(ctx context.Context, host string, hostID int64) error {
-	// Tail common log files.
-	cmd := `sh -lc '
-if [ -r /var/log/secure ]; then tail -n 0 -F /var/log/secure; exit $?; fi
-if [ -r /var/log/auth.log ]; then tail -n 0 -F /var/log/auth.log; exit $?; fi
-exit 2
-'`
-	return w.ssh.Stream(ctx, host, cmd, func(line string) bool {
-		w.handleLogLine(ctx, hostID, host, line)
+func (w *Watcher) streamTail(ctx context.Context, host string, hostID int64, osType string, state *store.WatcherState, h *health) error {
+	// Follow the OS's auth log file from the saved (inode, offset), if any.
+	p := remotecmd.Params{}
+	if state != nil && state.Cursor != nil {
+		if ino, off, ok := parseTailCursor(*state.Cursor); ok {
+			p["inode"] = ino
+			p["offset"] = int(off)
+		}
+	}
+	cmd, err := w.cmds.Render(osType, remotecmd.WatchTail, p)
+	if err != nil {
+		return err
+	}
+
+	// The script announces which file/inode/offset the following bytes start
+	// at; from there we count the bytes received, line endings as sent (CRLF,
+	// or none on a last partial line). The cursor is saved every couple of
+	// seconds: lines replayed after a crash are dropped by the dedupe.
+	var ino string
+	var off int64
+	tracking := false
+	// Markers are preceded by a newline of their own; an empty line is only
+	// counted once the next line shows it was not that separator.
+	var blank int64
+	lastSave := time.Now()
+	save := func(ctx context.Context) {
+		if tracking {
+			_ = w.st.UpdateWatcherCursor(ctx, hostID, formatTailCursor(ino, off))
+		}
+		lastSave = time.Now()
+	}
+	defer func() { save(context.WithoutCancel(ctx)) }()
+
+	h.connected("tail")
+	return w.ssh.StreamRaw(ctx, host, cmd, func(raw string) bool {
+		line := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+		if strings.HasPrefix(line, tailMarker) {
+			blank = 0
+			f := strings.Fields(strings.TrimPrefix(line, tailMarker))
+			switch {
+			case len(f) == 4 && f[0] == "FILE":
+				if n, err := strconv.ParseInt(f[3], 10, 64); err == nil {
+					ino, off, tracking = f[2], n, true
+					save(ctx)
+				}
+			case len(f) > 0 && f[0] == "GAP":
+				log.Printf("watcher(%s): log rotated while disconnected and the old file is gone; lines may be missing", host)
+			case len(f) > 0:
+				log.Printf("watcher(%s): %s", host, strings.Join(f, " "))
+			}
+			return true
+		}
+		if line == "" && blank == 0 {
+			blank = int64(len(raw))
+			return true
+		}
+		if tracking {
+			off += blank + int64(len(raw))
+		}
+		blank = 0
+		if line != "" {
+			h.line()
+			w.handleLogLine(ctx, hostID, host, line, lineMeta{})
+		}
+		if time.Since(lastSave) > 2*time.Second {
+			save(ctx)
+		}
 		return true
 	})
 }
 
-func (w *Watcher) handleLogLine(ctx context.Context, hostID int64, host string, line string) {
+// tailMarker starts the control lines of the watch_tail script.
+const tailMarker = "---KS "
+
+// Tail-mode cursors are stored in watchers.cursor as "tail:<inode>:<offset>",
+// next to journald cursors, which never start with "tail:".
+func formatTailCursor(ino string, off int64) string {
+	return fmt.Sprintf("tail:%s:%d", ino, off)
+}
+
+func isTailCursor(c string) bool { return strings.HasPrefix(c, "tail:") }
+
+func parseTailCursor(c string) (ino string, off int64, ok bool) {
+	rest, found := strings.CutPrefix(c, "tail:")
+	if !found {
+		return "", 0, false
+	}
+	ino, offStr, found := strings.Cut(rest, ":")
+	if !found || ino == "" {
+		return "", 0, false
+	}
+	off, err := strconv.ParseInt(offStr, 10, 64)
+	if err != nil || off < 0 {
+		return "", 0, false
+	}
+	return ino, off, true
+}
+
+// lineMeta carries what a structured source knows beyond the text line.
+type lineMeta struct {
+	ts     time.Time // overrides the parsed timestamp when set
+	pid    int
+	bootID string
+}
+
+func (w *Watcher) handleLogLine(ctx context.Context, hostID int64, host string, line string, meta lineMeta) {
 	// Dedupe by hash of raw line + host_id.
 	h := sha256.Sum256([]byte(host + "\n" + line))
 	sha := hex.EncodeToString(h[:])
@@ -183,6 +454,11 @@ func (w *Watcher) handleLogLine(ctx context.Context, hostID int64, host string,
 		AuthMethod: ptr(ev.AuthMethod),
 		Result:     ptr(ev.Result),
 		RawLine:    line,
+		SSHDPID:    ptrInt(meta.pid),
+		BootID:     ptr(meta.bootID),
+	}
+	if !meta.ts.IsZero() {
+		storeEv.TS = meta.ts
 	}
 
 	// DB-level last-hash dedupe (helps across restarts).
@@ -192,41 +468,93 @@ func (w *Watcher) handleLogLine(ctx context.Context, hostID int64, host string,
 		}
 	}
 
+	// Label the source by its reverse DNS name when there is a usable one.
+	srcLabel := ev.SourceIP
+	var names *resolver.Names
+	if w.cfg.Discovery.DNS.Enabled && ev.SourceIP != "" {
+		n := w.dns.Reverse(ctx, ev.SourceIP)
+		names = &n
+		if name := w.dns.Name(n); name != "" {
+			srcLabel = name
+			storeEv.SourceHost = &name
+		}
+	}
+
 	id, err := w.st.InsertAccessEvent(ctx, storeEv)
 	if err != nil {
 		return
 	}
 	_ = w.st.UpdateWatcherLastHash(ctx, hostID, sha)
 
-	// minimal edge update; label is IP until DNS enrichment via spider scan.
-	srcLabel := ev.SourceIP
 	var srcHostID *int64
-	if strings.Contains(srcLabel, ".") {
-		reach := w.ssh.CanConnect(ctx, srcLabel)
-		hid, _ := w.st.UpsertHost(ctx, srcLabel, &srcLabel, "linux", reach)
+	tags, _ := w.st.HostTags(ctx, srcLabel)
+	outOfScope := !w.scope.Check(ctx, scope.Target{Host: srcLabel, IPs: []string{ev.SourceIP}, Tags: tags}).InScope
+	if !outOfScope && strings.Contains(srcLabel, ".") {
+		hid, _ := w.st.TouchHost(ctx, srcLabel)
 		srcHostID = &hid
-		if !reach {
-			_, _ = w.st.InsertConcern(ctx, "high", "UNREACHABLE_SOURCE", &hid, nil, &id, "source seen by watcher but not reachable from jump")
-		}
+		// Probe in the background: the stream never waits on a handshake.
+		w.reach.Async(srcLabel, func(r reach.Result) { w.sourceReached(hid, r) })
+	}
+	_, _ = w.st.UpsertEdge(ctx, srcHostID, srcLabel, hostID, "log", 80, outOfScope)
+	if names != nil {
+		w.recordAddress(ctx, *names, srcLabel != ev.SourceIP, srcHostID)
 	}
-	_, _ = w.st.UpsertEdge(ctx, srcHostID, srcLabel, hostID, "log", 80)
 
 	// Publish SSE payload
 	payload := map[string]any{
 		"access_event_id": id,
 		"dest_host":       host,
-		"ts":              ev.TS,
+		"ts":              storeEv.TS,
 		"dest_user":       ev.DestUser,
 		"source_ip":       ev.SourceIP,
 		"source_port":     ev.SourcePort,
 		"fingerprint":     ev.FingerprintSHA256,
 		"raw":             line,
 	}
+	if ev.FingerprintSHA256 != "" {
+		if who := w.keyOwner(ctx, ev.FingerprintSHA256); who != "" {
+			payload["identity"] = who
+		}
+	}
 	if b, err := json.Marshal(payload); err == nil {
 		w.hub.Publish(b)
 	}
 }
 
+// recordAddress keeps host_addresses current, once per DNS answer rather
+// than once per line.
+func (w *Watcher) recordAddress(ctx context.Context, n resolver.Names, named bool, hostID *int64) {
+	w.mu.Lock()
+	if w.addrs[n.IP].Equal(n.ResolvedAt) {
+		w.mu.Unlock()
+		return
+	}
+	if len(w.addrs) >= max(w.cfg.Discovery.DNS.MaxEntries, 1) {
+		w.addrs = map[string]time.Time{}
+	}
+	w.addrs[n.IP] = n.ResolvedAt
+	w.mu.Unlock()
+	if !named {
+		hostID = nil
+	}
+	_ = w.st.RecordAddress(ctx, n.IP, hostID, n.PTR, n.FCrDNS())
+}
+
+// sourceReached raises one UNREACHABLE_SOURCE concern while a source seen in
+// the logs cannot be reached from the jump server, and resolves it once it can.
+func (w *Watcher) sourceReached(hostID int64, r reach.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if r.Reachable {
+		_, _ = w.st.ResolveConcerns(ctx, "UNREACHABLE_SOURCE", hostID)
+		return
+	}
+	if r.CheckedAt.IsZero() {
+		return
+	}
+	_, _, _ = w.st.EnsureOpenConcern(ctx, "high", "UNREACHABLE_SOURCE", &hostID, "source seen by watcher but not reachable from jump: "+r.Summary())
+}
+
 func ptr(s string) *string {
 	if s == "" {
 		return nil
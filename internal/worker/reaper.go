@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jsherman999/openclaw_keyspider/internal/config"
+	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/store"
+)
+
+// Reaper requeues scan jobs whose worker stopped heartbeating (crash, network
+// partition) and fails them once they run out of attempts. Requeued jobs resume
+// from their scan_job_hosts checkpoint.
+type Reaper struct {
+	st       *store.Store
+	interval time.Duration
+}
+
+func NewReaper(cfg *config.Config, dbc *db.DB) *Reaper {
+	iv := time.Duration(cfg.Worker.ReaperIntervalSeconds) * time.Second
+	if iv <= 0 {
+		iv = 30 * time.Second
+	}
+	return &Reaper{st: store.New(dbc), interval: iv}
+}
+
+func (r *Reaper) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		r.reapOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (r *Reaper) reapOnce(ctx context.Context) {
+	requeued, failed, err := r.st.ReapExpiredScanJobs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("reaper: %v", err)
+		}
+		return
+	}
+	if requeued > 0 || failed > 0 {
+		log.Printf("reaper: expired scan job leases: requeued=%d failed=%d", requeued, failed)
+	}
+}
@@ -3,12 +3,16 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/jsherman999/openclaw_keyspider/internal/config"
 	"github.com/jsherman999/openclaw_keyspider/internal/db"
+	"github.com/jsherman999/openclaw_keyspider/internal/reach"
 	"github.com/jsherman999/openclaw_keyspider/internal/spider"
+	"github.com/jsherman999/openclaw_keyspider/internal/sshclient"
 	"github.com/jsherman999/openclaw_keyspider/internal/store"
 )
 
@@ -18,13 +22,46 @@ type ScanWorker struct {
 	st    *store.Store
 	sp    *spider.Spider
 	poll  time.Duration
+	id    string
+	lease time.Duration
+	beat  time.Duration
 }
 
-func NewScanWorker(cfg *config.Config, dbc *db.DB) *ScanWorker {
-	return &ScanWorker{cfg: cfg, db: dbc, st: store.New(dbc), sp: spider.New(cfg, dbc), poll: 2 * time.Second}
+func NewScanWorker(cfg *config.Config, dbc *db.DB, rp *reach.Prober) *ScanWorker {
+	lease := time.Duration(cfg.Worker.LeaseSeconds) * time.Second
+	if lease <= 0 {
+		lease = 2 * time.Minute
+	}
+	beat := time.Duration(cfg.Worker.HeartbeatSeconds) * time.Second
+	if beat <= 0 || beat >= lease {
+		beat = lease / 4
+	}
+	return &ScanWorker{
+		cfg:   cfg,
+		db:    dbc,
+		st:    store.New(dbc),
+		sp:    spider.New(cfg, dbc, rp),
+		poll:  2 * time.Second,
+		id:    workerID(),
+		lease: lease,
+		beat:  beat,
+	}
+}
+
+// cancelPoll is how often a running job checks for a cancel request.
+const cancelPoll = 3 * time.Second
+
+// workerID identifies this process in scan_jobs.worker_id.
+func workerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano()%1e6)
 }
 
 func (w *ScanWorker) Run(ctx context.Context) {
+	log.Printf("scan_worker: started id=%s lease=%s", w.id, w.lease)
 	for {
 		select {
 		case <-ctx.Done():
@@ -32,7 +69,7 @@ func (w *ScanWorker) Run(ctx context.Context) {
 		default:
 		}
 
-		job, err := w.st.ClaimNextScanJob(ctx)
+		job, err := w.st.ClaimNextScanJob(ctx, w.id, w.lease)
 		if err != nil {
 			// no job or transient error
 			time.Sleep(w.poll)
@@ -44,19 +81,150 @@ func (w *ScanWorker) Run(ctx context.Context) {
 		}
 
 		since := time.Duration(job.SinceSec) * time.Second
-		log.Printf("scan_worker: running job id=%d host=%s since=%s depth=%d", job.ID, job.TargetHost, since, job.SpiderDepth)
+		log.Printf("scan_worker: running job id=%d kind=%s host=%s since=%s depth=%d attempt=%d/%d", job.ID, job.Kind, job.TargetHost, since, job.SpiderDepth, job.Attempts, job.MaxAttempts)
+
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		lost := make(chan struct{})
+		go w.heartbeat(jobCtx, job.ID, cancelJob, lost)
+
+		result, err := w.runOne(jobCtx, job, since)
+		cancelJob()
 
-		err = w.runOne(ctx, job, since)
-		if err2 := w.st.FinishScanJob(ctx, job.ID, err); err2 != nil {
+		select {
+		case <-lost:
+			// The reaper took the job back; another worker owns it now.
+			log.Printf("scan_worker: lost lease on job id=%d, abandoning", job.ID)
+			continue
+		default:
+		}
+		if ctx.Err() != nil {
+			// Shutting down: leave the checkpoint for the next worker.
+			if err2 := w.st.RequeueScanJob(context.WithoutCancel(ctx), job.ID, w.id); err2 != nil {
+				log.Printf("scan_worker: requeue job id=%d error=%v", job.ID, err2)
+			}
+			return
+		}
+		// Persist the job result (totals across every run of the job), even on error.
+		if result == nil {
+			if totals, err2 := w.st.ScanJobTotals(ctx, job.ID); err2 == nil {
+				result = totals
+			}
+		}
+		if result != nil {
+			_ = w.st.SetScanJobResult(ctx, job.ID, result)
+		}
+		if err2 := w.st.FinishScanJob(ctx, job.ID, w.id, err); err2 != nil {
 			log.Printf("scan_worker: finish job id=%d error=%v", job.ID, err2)
 		}
 	}
 }
 
-func (w *ScanWorker) runOne(ctx context.Context, job *store.ScanJob, since time.Duration) error {
-	if job.Kind != "scan" {
-		return errors.New("unknown job kind")
+// heartbeat renews the job lease until ctx ends. If the lease was lost it
+// closes lost and cancels the job; a cancel request (POST /scan/{id}/cancel)
+// also cancels the job.
+func (w *ScanWorker) heartbeat(ctx context.Context, jobID int64, cancelJob context.CancelFunc, lost chan<- struct{}) {
+	t := time.NewTicker(w.beat)
+	defer t.Stop()
+	cancelCheck := time.NewTicker(cancelPoll)
+	defer cancelCheck.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cancelCheck.C:
+			if req, err := w.st.ScanJobCancelRequested(ctx, jobID); err == nil && req {
+				log.Printf("scan_worker: cancel requested for job id=%d", jobID)
+				cancelJob()
+				return
+			}
+		case <-t.C:
+			err := w.st.HeartbeatScanJob(ctx, jobID, w.id, w.lease)
+			if errors.Is(err, store.ErrLeaseLost) {
+				close(lost)
+				cancelJob()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("scan_worker: heartbeat job id=%d error=%v", jobID, err)
+			}
+		}
+	}
+}
+
+// runOne runs job according to its kind. A nil result means the job's
+// scan_job_hosts totals are its result.
+func (w *ScanWorker) runOne(ctx context.Context, job *store.ScanJob, since time.Duration) (any, error) {
+	params, err := job.JobParams()
+	if err != nil {
+		return nil, err
+	}
+	ctx = sshclient.WithInitiator(ctx, fmt.Sprintf("scan_job:%d", job.ID))
+
+	switch job.Kind {
+	case store.JobKindScan:
+		_, err = w.sp.ScanJob(ctx, job.ID, job.TargetHost, since, job.SpiderDepth)
+	case store.JobKindAuthorizedKeys:
+		err = w.sp.AuthorizedKeysJob(ctx, job.ID, params.Hosts)
+	case store.JobKindKeyHunt:
+		err = w.sp.KeyHuntJob(ctx, job.ID, params.Hosts)
+	case store.JobKindConfigAudit:
+		err = w.sp.ConfigAuditJob(ctx, job.ID, params.Hosts)
+	case store.JobKindRescanAll:
+		return w.fanOut(ctx, job, params)
+	case store.JobKindKeyFindings:
+		kf := w.cfg.KeyFindings
+		f, err := w.st.RefreshKeyFindings(ctx, store.KeyFindingRules{UnusedDays: kf.UnusedDays, MaxPrivateHosts: kf.MaxPrivateHosts, MaxAuthorizedAccounts: kf.MaxAuthorizedAccounts})
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	default:
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	return nil, err
+}
+
+// fanOut runs a rescan_all job: it enqueues one child job per host last seen
+// reachable, at the parent's priority. Children already enqueued by an earlier
+// run of the same job are skipped.
+func (w *ScanWorker) fanOut(ctx context.Context, job *store.ScanJob, params store.JobParams) (any, error) {
+	kind := params.ChildKind
+	if kind == "" {
+		kind = store.JobKindScan
+	}
+	hosts, err := w.st.ListReachableHostnames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done, err := w.st.ChildJobHosts(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enqueued := 0
+	for _, h := range hosts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if done[h] {
+			continue
+		}
+		spec := store.JobSpec{
+			Kind:        kind,
+			TargetHost:  h,
+			Since:       time.Duration(job.SinceSec) * time.Second,
+			SpiderDepth: job.SpiderDepth,
+			Priority:    job.Priority,
+			ParentJobID: &job.ID,
+		}
+		if kind != store.JobKindScan {
+			spec.Params.Hosts = []string{h}
+		}
+		if _, err := w.st.EnqueueJob(ctx, spec); err != nil {
+			return nil, err
+		}
+		enqueued++
 	}
-	_, err := w.sp.ScanHost(ctx, job.TargetHost, since, job.SpiderDepth)
-	return err
+	log.Printf("scan_worker: job id=%d enqueued %d %s job(s) over %d reachable host(s)", job.ID, enqueued, kind, len(hosts))
+	return map[string]any{"child_kind": kind, "hosts": len(hosts), "children_enqueued": len(done) + enqueued}, nil
 }